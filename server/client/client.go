@@ -0,0 +1,293 @@
+// Package client is a Go client for the NESTQueue ticket API. Requests are
+// made with a context, failed requests are retried with backoff and errors
+// answered by the API are returned as *Error.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// _defaultRetries is how many times a failed request is retried by
+	// default
+	_defaultRetries = 3
+
+	// _defaultBackoff is the wait before the first retry by default. Each
+	// retry waits twice as long as the one before.
+	_defaultBackoff = 250 * time.Millisecond
+
+	// _maxBackoff caps the wait between retries
+	_maxBackoff = 10 * time.Second
+)
+
+// Client makes requests to the ticket API. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	retries    int
+	backoff    time.Duration
+	header     http.Header
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient makes requests with httpClient instead of
+// http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets how many times a failed request is retried. Zero turns
+// retries off.
+func WithRetries(retries int) Option {
+	return func(c *Client) {
+		c.retries = max(retries, 0)
+	}
+}
+
+// WithBackoff sets the wait before the first retry, which doubles with every
+// retry after it
+func WithBackoff(backoff time.Duration) Option {
+	return func(c *Client) {
+		c.backoff = backoff
+	}
+}
+
+// WithHeader sets a header sent with every request, e.g. Authorization
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.header.Set(key, value)
+	}
+}
+
+// New creates a client for the API served at baseURL, e.g.
+// "http://localhost:3000"
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		retries:    _defaultRetries,
+		backoff:    _defaultBackoff,
+		header:     make(http.Header),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// request describes a request to the API
+type request struct {
+	method string
+	path   string
+	query  url.Values
+
+	// body is encoded as JSON unless it is an io.Reader, which is sent as is
+	// with contentType
+	body        any
+	contentType string
+
+	// header holds headers sent with the request, overriding the client's
+	header http.Header
+
+	// notFound is returned, wrapped in *Error, when the API answers 404
+	notFound error
+}
+
+// do sends req and decodes the response into out, unless out is nil. Requests
+// answered with 429 Too Many Requests are retried, as are requests answered
+// with a server error or failing to reach the server, unless they are POST
+// requests, which may have been carried out already. Requests whose body is a
+// reader are never retried, as the body cannot be sent twice.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// send sends req, retrying as described by do, and returns the successful
+// response. The caller must close its body.
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	var body []byte
+
+	if req.body != nil {
+		if _, ok := req.body.(io.Reader); !ok {
+			data, err := json.Marshal(req.body)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode request: %w", err)
+			}
+
+			body = data
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		httpReq, err := c.newRequest(ctx, req, body)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.httpClient.Do(httpReq)
+
+		retry := attempt < c.retries && c.canRetry(req, resp, err)
+		if !retry {
+			if err != nil {
+				return nil, err
+			}
+
+			if resp.StatusCode >= http.StatusBadRequest {
+				defer resp.Body.Close()
+				return nil, newError(resp, req.notFound)
+			}
+
+			return resp, nil
+		}
+
+		wait := c.wait(attempt, resp)
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// newRequest builds the HTTP request for req, with an already encoded body
+func (c *Client) newRequest(ctx context.Context, req request, body []byte) (*http.Request, error) {
+	u := *c.baseURL
+	u.Path += req.path
+	u.RawQuery = req.query.Encode()
+
+	var (
+		reader      io.Reader
+		contentType = req.contentType
+	)
+
+	switch {
+	case body != nil:
+		reader = bytes.NewReader(body)
+
+		if contentType == "" {
+			contentType = "application/json"
+		}
+	case req.body != nil:
+		reader = req.body.(io.Reader)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range c.header {
+		httpReq.Header[key] = slices.Clone(values)
+	}
+
+	httpReq.Header.Set("Accept", "application/json, application/problem+json")
+
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+
+	for key, values := range req.header {
+		httpReq.Header[key] = slices.Clone(values)
+	}
+
+	return httpReq, nil
+}
+
+// canRetry reports whether req can be sent again after failing with resp or
+// err
+func (c *Client) canRetry(req request, resp *http.Response, err error) bool {
+	if _, streamed := req.body.(io.Reader); streamed {
+		return false
+	}
+
+	if err != nil {
+		// The request may have been canceled rather than failed
+		return req.method != http.MethodPost && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return req.method != http.MethodPost
+	default:
+		return false
+	}
+}
+
+// wait returns how long to wait before retrying after attempt, honoring a
+// Retry-After header in resp
+func (c *Client) wait(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, _maxBackoff)
+		}
+	}
+
+	var wait = c.backoff
+
+	for range attempt {
+		if wait >= _maxBackoff {
+			break
+		}
+
+		wait *= 2
+	}
+
+	wait = min(wait, _maxBackoff)
+	if wait <= 0 {
+		return 0
+	}
+
+	// Jitter spreads out clients retrying at the same time
+	return wait/2 + rand.N(wait/2+1)
+}
@@ -0,0 +1,1094 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/api"
+	"go.uber.org/zap"
+)
+
+const (
+	_ticketID = "0123456789abcdef01234567"
+	_otherID  = "89abcdef0123456789abcdef"
+	_linkID   = "fedcba9876543210fedcba98"
+)
+
+// newTestClient returns a client of a server routing requests like the API.
+// Requests are answered by fakes, by route pattern, and otherwise by the API
+// handlers themselves, which have no database and so only answer requests
+// rejected before reaching it.
+func newTestClient(t *testing.T, fakes map[string]http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+
+	var (
+		logger = zap.NewNop()
+		routes = http.NewServeMux()
+		faked  = http.NewServeMux()
+	)
+
+	for pattern, fake := range fakes {
+		faked.HandleFunc(pattern, fake)
+	}
+
+	api.NewTicketHandler(nil, nil, nil, nil, logger).RegisterRoutes(routes)
+	api.NewLinkHandler(nil, logger).RegisterRoutes(routes)
+	api.NewCommentHandler(nil, logger).RegisterRoutes(routes)
+	api.NewImportHandler(nil, logger).RegisterRoutes(routes)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := routes.Handler(r)
+		if pattern == "" {
+			t.Errorf("%s %s is not an API route", r.Method, r.URL.Path)
+		}
+
+		if _, ok := fakes[pattern]; ok {
+			faked.ServeHTTP(w, r)
+			return
+		}
+
+		routes.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	c, err := New(server.URL, append([]Option{WithBackoff(time.Millisecond)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// writeTestJSON answers with val encoded as JSON
+func writeTestJSON(t *testing.T, w http.ResponseWriter, status int, val any) {
+	t.Helper()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(val); err != nil {
+		t.Error(err)
+	}
+}
+
+// writeTestProblem answers with the problem details the API answers errors
+// with
+func writeTestProblem(t *testing.T, w http.ResponseWriter, status int, detail string) {
+	t.Helper()
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+
+	problem := map[string]any{"type": "about:blank", "title": http.StatusText(status), "status": status, "detail": detail}
+
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		t.Error(err)
+	}
+}
+
+// decodeTestBody decodes the JSON body of r into val
+func decodeTestBody(t *testing.T, r *http.Request, val any) {
+	t.Helper()
+
+	if err := json.NewDecoder(r.Body).Decode(val); err != nil {
+		t.Errorf("invalid request body: %v", err)
+	}
+}
+
+func TestCreateTicket(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"POST /api/v1/tickets": func(w http.ResponseWriter, r *http.Request) {
+			if got := r.Header.Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+
+			var input TicketInput
+			decodeTestBody(t, r, &input)
+
+			if input.Title != "Printer jam" || input.Priority != 2 {
+				t.Errorf("body = %+v", input)
+			}
+
+			writeTestJSON(t, w, http.StatusCreated, Created{ID: _ticketID, Duplicates: []Duplicate{{ID: _otherID, Score: 0.9}}})
+		},
+	})
+
+	created, err := c.CreateTicket(context.Background(), TicketInput{Title: "Printer jam", Priority: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created.ID != _ticketID || len(created.Duplicates) != 1 || created.Duplicates[0].ID != _otherID {
+		t.Errorf("created = %+v", created)
+	}
+}
+
+func TestGetTicket(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"GET /api/v1/tickets/{id}": func(w http.ResponseWriter, r *http.Request) {
+			if r.PathValue("id") != _ticketID {
+				writeTestProblem(t, w, http.StatusNotFound, "ticket not found")
+				return
+			}
+
+			writeTestJSON(t, w, http.StatusOK, Ticket{ID: _ticketID, Title: "Printer jam", Tags: []string{"hardware"}})
+		},
+	})
+
+	ticket, err := c.GetTicket(context.Background(), _ticketID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ticket.ID != _ticketID || ticket.Title != "Printer jam" || len(ticket.Tags) != 1 {
+		t.Errorf("ticket = %+v", ticket)
+	}
+}
+
+func TestListTickets(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"GET /api/v1/tickets": func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+
+			for key, want := range map[string]string{
+				"site":      "Watsonville",
+				"status":    "Open,Active",
+				"tags.any":  "a,b",
+				"cf.serial": "X1",
+				"sort":      "-priority",
+				"offset":    "10",
+				"limit":     "5",
+			} {
+				if got := query.Get(key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+
+			writeTestJSON(t, w, http.StatusOK, map[string]any{"tickets": []Ticket{{ID: _ticketID}}})
+		},
+	})
+
+	opts := ListOptions{
+		Site:         "Watsonville",
+		Statuses:     []string{StatusOpen, StatusActive},
+		AnyTags:      []string{"a", "b"},
+		CustomFields: map[string]string{"serial": "X1"},
+		Sort:         "-priority",
+	}
+
+	tickets, err := c.ListTickets(context.Background(), opts, 10, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tickets) != 1 || tickets[0].ID != _ticketID {
+		t.Errorf("tickets = %+v", tickets)
+	}
+}
+
+func TestListTicketsMatchingNothing(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"GET /api/v1/tickets": func(w http.ResponseWriter, r *http.Request) {
+			writeTestProblem(t, w, http.StatusNotFound, "no tickets found")
+		},
+	})
+
+	tickets, err := c.ListTickets(context.Background(), ListOptions{}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tickets == nil || len(tickets) != 0 {
+		t.Errorf("tickets = %#v, want an empty slice", tickets)
+	}
+}
+
+func TestListTicketsInvalidFilter(t *testing.T) {
+	// The API handler rejects the sort itself
+	c := newTestClient(t, nil)
+
+	_, err := c.ListTickets(context.Background(), ListOptions{Sort: "nonsense"}, 0, 0)
+
+	var apiErr *Error
+
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest || !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("err = %v, want a 400 *Error", err)
+	}
+
+	if apiErr.Type != "about:blank" || apiErr.Detail == "" {
+		t.Errorf("problem = %+v", apiErr)
+	}
+}
+
+// pagedTickets answers ticket listings from total tickets, like the API,
+// counting the pages read
+func pagedTickets(t *testing.T, total int, pages *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pages.Add(1)
+
+		// Both are left out when zero
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		var tickets []Ticket
+
+		for i := offset; i < min(offset+limit, total); i++ {
+			tickets = append(tickets, Ticket{ID: string(rune('a' + i))})
+		}
+
+		// The API answers a page past the last ticket with 404
+		if len(tickets) == 0 {
+			writeTestProblem(t, w, http.StatusNotFound, "no tickets found")
+			return
+		}
+
+		writeTestJSON(t, w, http.StatusOK, map[string]any{"tickets": tickets})
+	}
+}
+
+func TestTickets(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    int
+		pageSize int
+		pages    int32
+	}{
+		{"partial last page", 5, 2, 3},
+		{"full last page", 4, 2, 3},
+		{"no tickets", 0, 2, 1},
+		{"default page size", 3, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pages atomic.Int32
+
+			c := newTestClient(t, map[string]http.HandlerFunc{
+				"GET /api/v1/tickets": pagedTickets(t, tt.total, &pages),
+			})
+
+			var ids string
+
+			for ticket, err := range c.Tickets(context.Background(), ListOptions{PageSize: tt.pageSize}) {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				ids += ticket.ID
+			}
+
+			if want := "abcde"[:tt.total]; ids != want {
+				t.Errorf("tickets = %q, want %q", ids, want)
+			}
+
+			if got := pages.Load(); got != tt.pages {
+				t.Errorf("read %d pages, want %d", got, tt.pages)
+			}
+		})
+	}
+}
+
+func TestTicketsStopsEarly(t *testing.T) {
+	var pages atomic.Int32
+
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"GET /api/v1/tickets": pagedTickets(t, 5, &pages),
+	})
+
+	for ticket, err := range c.Tickets(context.Background(), ListOptions{PageSize: 2}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ticket.ID == "b" {
+			break
+		}
+	}
+
+	if got := pages.Load(); got != 1 {
+		t.Errorf("read %d pages, want 1", got)
+	}
+}
+
+func TestTicketsError(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"GET /api/v1/tickets": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("offset") == "" {
+				writeTestJSON(t, w, http.StatusOK, map[string]any{"tickets": []Ticket{{ID: "a"}, {ID: "b"}}})
+				return
+			}
+
+			writeTestProblem(t, w, http.StatusBadRequest, "invalid filter")
+		},
+	})
+
+	var (
+		ids  string
+		errs []error
+	)
+
+	for ticket, err := range c.Tickets(context.Background(), ListOptions{PageSize: 2}) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		ids += ticket.ID
+	}
+
+	if ids != "ab" || len(errs) != 1 || !errors.Is(errs[0], ErrBadRequest) {
+		t.Errorf("tickets = %q, errors = %v", ids, errs)
+	}
+}
+
+func TestReplaceTicket(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"PUT /api/v1/tickets/{id}": func(w http.ResponseWriter, r *http.Request) {
+			var input TicketInput
+			decodeTestBody(t, r, &input)
+
+			writeTestJSON(t, w, http.StatusOK, Ticket{ID: r.PathValue("id"), Title: input.Title, Status: input.Status})
+		},
+	})
+
+	ticket, err := c.ReplaceTicket(context.Background(), _ticketID, TicketInput{Title: "Replaced", Priority: 1, Status: StatusOpen})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ticket.ID != _ticketID || ticket.Title != "Replaced" || ticket.Status != StatusOpen {
+		t.Errorf("ticket = %+v", ticket)
+	}
+}
+
+func TestPatchTicket(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"PATCH /api/v1/tickets/{id}": func(w http.ResponseWriter, r *http.Request) {
+			if got := r.Header.Get("Content-Type"); got != "application/merge-patch+json" {
+				t.Errorf("Content-Type = %q, want application/merge-patch+json", got)
+			}
+
+			var patch map[string]any
+			decodeTestBody(t, r, &patch)
+
+			if value, ok := patch["assignedTo"]; !ok || value != nil {
+				t.Errorf("assignedTo = %v, want null", value)
+			}
+
+			writeTestJSON(t, w, http.StatusOK, Ticket{ID: r.PathValue("id"), Priority: 1})
+		},
+	})
+
+	ticket, err := c.PatchTicket(context.Background(), _ticketID, Patch{"assignedTo": nil, "priority": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ticket.ID != _ticketID || ticket.Priority != 1 {
+		t.Errorf("ticket = %+v", ticket)
+	}
+}
+
+func TestPatchTicketInvalidFields(t *testing.T) {
+	// The API handler rejects the fields itself
+	c := newTestClient(t, nil)
+
+	_, err := c.PatchTicket(context.Background(), _ticketID, Patch{"id": "x", "priority": "high"})
+
+	var apiErr *Error
+
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrInvalidFields) {
+		t.Fatalf("err = %v, want ErrInvalidFields", err)
+	}
+
+	fields := make(map[string]bool)
+
+	for _, f := range apiErr.Errors {
+		fields[f.Field] = true
+	}
+
+	if !fields["id"] || !fields["priority"] {
+		t.Errorf("field errors = %+v, want id and priority", apiErr.Errors)
+	}
+}
+
+func TestDeleteTicket(t *testing.T) {
+	var deleted atomic.Bool
+
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"DELETE /api/v1/tickets/{id}": func(w http.ResponseWriter, r *http.Request) {
+			deleted.Store(r.PathValue("id") == _ticketID)
+			w.WriteHeader(http.StatusNoContent)
+		},
+	})
+
+	if err := c.DeleteTicket(context.Background(), _ticketID); err != nil {
+		t.Fatal(err)
+	}
+
+	if !deleted.Load() {
+		t.Error("ticket was not deleted")
+	}
+}
+
+func TestMergeTicket(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"POST /api/v1/tickets/{id}/merge": func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Into string `json:"into"`
+			}
+			decodeTestBody(t, r, &body)
+
+			writeTestJSON(t, w, http.StatusOK, Ticket{ID: body.Into, MergedFrom: []string{r.PathValue("id")}})
+		},
+	})
+
+	ticket, err := c.MergeTicket(context.Background(), _otherID, _ticketID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ticket.ID != _ticketID || len(ticket.MergedFrom) != 1 || ticket.MergedFrom[0] != _otherID {
+		t.Errorf("ticket = %+v", ticket)
+	}
+}
+
+func TestSuggestDuplicates(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"POST /api/v1/tickets/duplicates": func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]string
+			decodeTestBody(t, r, &body)
+
+			if body["title"] != "Printer jam" || body["site"] != "Watsonville" {
+				t.Errorf("body = %v", body)
+			}
+
+			writeTestJSON(t, w, http.StatusOK, map[string]any{"duplicates": []Duplicate{{ID: _otherID, Score: 0.8}}})
+		},
+	})
+
+	duplicates, err := c.SuggestDuplicates(context.Background(), "Printer jam", "", "Watsonville")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(duplicates) != 1 || duplicates[0].ID != _otherID {
+		t.Errorf("duplicates = %+v", duplicates)
+	}
+}
+
+func TestBulkTickets(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"POST /api/v1/tickets/bulk": func(w http.ResponseWriter, r *http.Request) {
+			var bulk BulkRequest
+			decodeTestBody(t, r, &bulk)
+
+			if bulk.Confirm == "" {
+				writeTestJSON(t, w, http.StatusOK, BulkResponse{Action: bulk.Action, Count: 1, IDs: []string{_ticketID}, Confirm: "token"})
+				return
+			}
+
+			if bulk.Confirm != "token" {
+				writeTestProblem(t, w, http.StatusConflict, "the filter matches different tickets")
+				return
+			}
+
+			writeTestJSON(t, w, http.StatusOK, BulkResponse{
+				Action:  bulk.Action,
+				Count:   1,
+				Summary: map[string]int{"deleted": 1},
+				Results: []BulkResult{{ID: _ticketID, Result: "deleted"}},
+			})
+		},
+	})
+
+	bulk := BulkRequest{Action: BulkDelete, Filter: ListOptions{Site: "Watsonville"}.Filter()}
+
+	preview, err := c.BulkTickets(context.Background(), bulk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !preview.IsPreview() || len(preview.IDs) != 1 {
+		t.Fatalf("preview = %+v", preview)
+	}
+
+	bulk.Confirm = "stale"
+
+	if _, err := c.BulkTickets(context.Background(), bulk); !errors.Is(err, ErrConflict) {
+		t.Errorf("err = %v, want ErrConflict", err)
+	}
+
+	bulk.Confirm = preview.Confirm
+
+	result, err := c.BulkTickets(context.Background(), bulk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.IsPreview() || result.Summary["deleted"] != 1 || len(result.Results) != 1 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestExportTickets(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"GET /api/v1/tickets/export": func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+
+			if query.Get("format") != "ndjson" || query.Get("columns") != "id,title" || query.Get("tz") != "America/Los_Angeles" || query.Get("site") != "Watsonville" {
+				t.Errorf("query = %v", query)
+			}
+
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			if _, err := io.WriteString(w, `{"id":"a"}`+"\n"); err != nil {
+				t.Error(err)
+			}
+		},
+	})
+
+	opts := ExportOptions{
+		ListOptions: ListOptions{Site: "Watsonville"},
+		Format:      "ndjson",
+		Columns:     []string{"id", "title"},
+		TimeZone:    "America/Los_Angeles",
+	}
+
+	body, err := c.ExportTickets(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != `{"id":"a"}`+"\n" {
+		t.Errorf("export = %q", data)
+	}
+}
+
+func TestImportTickets(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"POST /api/v1/tickets/import": func(w http.ResponseWriter, r *http.Request) {
+			if got := r.Header.Get("Content-Type"); got != "application/x-ndjson" {
+				t.Errorf("Content-Type = %q, want application/x-ndjson", got)
+			}
+
+			query := r.URL.Query()
+
+			if query.Get("format") != "ndjson" || query.Get("map.Subject") != "title" || query.Get("dryRun") != "true" {
+				t.Errorf("query = %v", query)
+			}
+
+			data, _ := io.ReadAll(r.Body)
+			rows := strings.Count(string(data), "\n")
+
+			writeTestJSON(t, w, http.StatusOK, ImportReport{DryRun: true, Rows: rows, Valid: rows})
+		},
+	})
+
+	opts := ImportOptions{Format: "ndjson", Mapping: map[string]string{"Subject": "title"}, DryRun: true}
+
+	report, err := c.ImportTickets(context.Background(), strings.NewReader("{\"Subject\":\"a\"}\n{\"Subject\":\"b\"}\n"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.DryRun || report.Rows != 2 || report.Valid != 2 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"GET /api/v1/tickets/{id}/links": func(w http.ResponseWriter, r *http.Request) {
+			writeTestJSON(t, w, http.StatusOK, map[string]any{"links": []Link{{ID: _linkID, Type: LinkBlocks, Source: r.PathValue("id"), Target: _otherID}}})
+		},
+		"POST /api/v1/tickets/{id}/links": func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]string
+			decodeTestBody(t, r, &body)
+
+			writeTestJSON(t, w, http.StatusCreated, Link{ID: _linkID, Type: body["type"], Source: r.PathValue("id"), Target: body["target"]})
+		},
+		"DELETE /api/v1/tickets/{id}/links/{linkId}": func(w http.ResponseWriter, r *http.Request) {
+			if r.PathValue("linkId") != _linkID {
+				writeTestProblem(t, w, http.StatusNotFound, "link not found")
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		},
+	})
+
+	ctx := context.Background()
+
+	link, err := c.LinkTicket(ctx, _ticketID, LinkBlocks, _otherID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if link.ID != _linkID || link.Type != LinkBlocks || link.Source != _ticketID || link.Target != _otherID {
+		t.Errorf("link = %+v", link)
+	}
+
+	links, err := c.Links(ctx, _ticketID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(links) != 1 || links[0].ID != _linkID {
+		t.Errorf("links = %+v", links)
+	}
+
+	if err := c.UnlinkTicket(ctx, _ticketID, _linkID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestComments(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"GET /api/v1/tickets/{id}/comments": func(w http.ResponseWriter, r *http.Request) {
+			writeTestJSON(t, w, http.StatusOK, map[string]any{"count": 1, "comments": []Comment{{ID: _linkID, Ticket: r.PathValue("id"), Author: "ana@example.com", Body: "Replaced the toner"}}})
+		},
+		"POST /api/v1/tickets/{id}/comments": func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]string
+			decodeTestBody(t, r, &body)
+
+			writeTestJSON(t, w, http.StatusCreated, Comment{ID: _linkID, Ticket: r.PathValue("id"), Author: body["author"], Body: body["body"]})
+		},
+	})
+
+	ctx := context.Background()
+
+	comment, err := c.AddComment(ctx, _ticketID, "ana@example.com", "Replaced the toner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if comment.Ticket != _ticketID || comment.Author != "ana@example.com" || comment.Body != "Replaced the toner" {
+		t.Errorf("comment = %+v", comment)
+	}
+
+	comments, err := c.Comments(ctx, _ticketID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(comments) != 1 || comments[0].Body != "Replaced the toner" {
+		t.Errorf("comments = %+v", comments)
+	}
+}
+
+func TestCommentWithoutBody(t *testing.T) {
+	c := newTestClient(t, nil)
+
+	_, err := c.AddComment(context.Background(), _ticketID, "ana@example.com", " ")
+
+	if !errors.Is(err, ErrBadRequest) {
+		t.Errorf("err = %v, want %v", err, ErrBadRequest)
+	}
+}
+
+// writeTestEvents writes raw server-sent events and flushes them
+func writeTestEvents(t *testing.T, w http.ResponseWriter, events string) {
+	t.Helper()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+
+	if _, err := io.WriteString(w, events); err != nil {
+		t.Error(err)
+	}
+
+	w.(http.Flusher).Flush()
+}
+
+func TestEvents(t *testing.T) {
+	var streams atomic.Int32
+
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"GET /api/v1/tickets/events": func(w http.ResponseWriter, r *http.Request) {
+			if accept := r.Header.Get("Accept"); !strings.Contains(accept, "text/event-stream") {
+				t.Errorf("Accept = %q", accept)
+			}
+
+			lastID := r.Header.Get("Last-Event-ID")
+
+			switch streams.Add(1) {
+			case 1:
+				if lastID != "" {
+					t.Errorf("first stream resumes after %q", lastID)
+				}
+
+				// The stream ends, so the client reopens it
+				writeTestEvents(t, w, ": keep-alive\n\n"+
+					"id: 1\nevent: ticket.updated\ndata: {\"type\":\"ticket.updated\",\"ticketId\":\""+_ticketID+"\",\"ticket\":{\"id\":\""+_ticketID+"\",\"title\":\"Printer jammed\"}}\n\n"+
+					"event: stream.reset\ndata: {}\n\n")
+			default:
+				if lastID != "1" {
+					t.Errorf("stream resumes after %q, want 1", lastID)
+				}
+
+				writeTestEvents(t, w, "id: 2\r\nevent: comment.created\r\ndata: {\"ticketId\":\""+_ticketID+"\",\r\ndata: \"comment\":{\"body\":\"Replaced the toner\"}}\r\n\r\n")
+				<-r.Context().Done()
+			}
+		},
+	})
+
+	var events []Event
+
+	for event, err := range c.Events(context.Background(), "") {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if events = append(events, event); len(events) == 3 {
+			break
+		}
+	}
+
+	if events[0].ID != "1" || events[0].Type != EventTicketUpdated || events[0].Ticket == nil || events[0].Ticket.Title != "Printer jammed" {
+		t.Errorf("events[0] = %+v", events[0])
+	}
+
+	if events[1].ID != "" || events[1].Type != EventStreamReset {
+		t.Errorf("events[1] = %+v", events[1])
+	}
+
+	if events[2].ID != "2" || events[2].Type != EventCommentCreated || events[2].TicketID != _ticketID || events[2].Comment == nil || events[2].Comment.Body != "Replaced the toner" {
+		t.Errorf("events[2] = %+v", events[2])
+	}
+
+	if n := streams.Load(); n != 2 {
+		t.Errorf("streams = %d, want 2", n)
+	}
+}
+
+func TestEventsCanceled(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"GET /api/v1/tickets/events": func(w http.ResponseWriter, r *http.Request) {
+			writeTestEvents(t, w, "id: 1\nevent: ticket.deleted\ndata: {\"ticketId\":\""+_ticketID+"\"}\n\n")
+			<-r.Context().Done()
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var count int
+
+	// Iteration ends without an error once canceled
+	for event, err := range c.Events(ctx, "") {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if event.Type != EventTicketDeleted {
+			t.Errorf("event = %+v", event)
+		}
+
+		count++
+		cancel()
+	}
+
+	if count != 1 {
+		t.Errorf("read %d events, want 1", count)
+	}
+}
+
+func TestEventsError(t *testing.T) {
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"GET /api/v1/tickets/events": func(w http.ResponseWriter, r *http.Request) {
+			writeTestProblem(t, w, http.StatusServiceUnavailable, "change streams need a replica set")
+		},
+	}, WithRetries(0))
+
+	var errs []error
+
+	for _, err := range c.Events(context.Background(), "") {
+		errs = append(errs, err)
+	}
+
+	var apiErr *Error
+
+	if len(errs) != 1 || !errors.As(errs[0], &apiErr) || apiErr.Status != http.StatusServiceUnavailable {
+		t.Errorf("errors = %v, want one 503", errs)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	notFound := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", "req-1")
+		writeTestProblem(t, w, http.StatusNotFound, "not found")
+	}
+
+	c := newTestClient(t, map[string]http.HandlerFunc{
+		"GET /api/v1/tickets/{id}":                   notFound,
+		"PUT /api/v1/tickets/{id}":                   notFound,
+		"PATCH /api/v1/tickets/{id}":                 notFound,
+		"DELETE /api/v1/tickets/{id}":                notFound,
+		"POST /api/v1/tickets/{id}/merge":            notFound,
+		"GET /api/v1/tickets/{id}/links":             notFound,
+		"POST /api/v1/tickets/{id}/links":            notFound,
+		"DELETE /api/v1/tickets/{id}/links/{linkId}": notFound,
+		"GET /api/v1/tickets/{id}/comments":          notFound,
+		"POST /api/v1/tickets/{id}/comments":         notFound,
+	})
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{"GetTicket", func() error { _, err := c.GetTicket(ctx, _ticketID); return err }, ErrTicketNotFound},
+		{"ReplaceTicket", func() error { _, err := c.ReplaceTicket(ctx, _ticketID, TicketInput{Title: "a"}); return err }, ErrTicketNotFound},
+		{"PatchTicket", func() error { _, err := c.PatchTicket(ctx, _ticketID, Patch{"priority": 1}); return err }, ErrTicketNotFound},
+		{"DeleteTicket", func() error { return c.DeleteTicket(ctx, _ticketID) }, ErrTicketNotFound},
+		{"MergeTicket", func() error { _, err := c.MergeTicket(ctx, _ticketID, _otherID); return err }, ErrTicketNotFound},
+		{"Links", func() error { _, err := c.Links(ctx, _ticketID); return err }, ErrTicketNotFound},
+		{"LinkTicket", func() error { _, err := c.LinkTicket(ctx, _ticketID, LinkRelated, _otherID); return err }, ErrTicketNotFound},
+		{"UnlinkTicket", func() error { return c.UnlinkTicket(ctx, _ticketID, _linkID) }, ErrLinkNotFound},
+		{"Comments", func() error { _, err := c.Comments(ctx, _ticketID); return err }, ErrTicketNotFound},
+		{"AddComment", func() error { _, err := c.AddComment(ctx, _ticketID, "ana@example.com", "a"); return err }, ErrTicketNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+
+			if !errors.Is(err, tt.want) || !errors.Is(err, ErrNotFound) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+
+			var apiErr *Error
+
+			if !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound || apiErr.RequestID != "req-1" {
+				t.Errorf("err = %#v", err)
+			}
+
+			// A missing link is not a missing ticket, and the other way around
+			for _, other := range []error{ErrTicketNotFound, ErrLinkNotFound} {
+				if other != tt.want && errors.Is(err, other) {
+					t.Errorf("err matches %v", other)
+				}
+			}
+		})
+	}
+}
+
+// flakyTickets answers GET and POST ticket requests with status failures
+// times before succeeding, counting the attempts
+func flakyTickets(t *testing.T, status int, retryAfter string, failures int32, attempts *atomic.Int32) map[string]http.HandlerFunc {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+
+			writeTestProblem(t, w, status, "try again")
+
+			return
+		}
+
+		writeTestJSON(t, w, http.StatusOK, Ticket{ID: _ticketID})
+	}
+
+	return map[string]http.HandlerFunc{
+		"GET /api/v1/tickets/{id}":        handler,
+		"POST /api/v1/tickets/{id}/merge": handler,
+		"POST /api/v1/tickets/import":     handler,
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		failures   int32
+		post       bool
+		attempts   int32
+		wantErr    error
+	}{
+		{"GET recovers from server errors", http.StatusServiceUnavailable, "", 2, false, 3, nil},
+		{"GET honors Retry-After", http.StatusServiceUnavailable, "0", 2, false, 3, nil},
+		{"GET gives up after the retries", http.StatusInternalServerError, "", 10, false, 4, ErrServer},
+		{"GET recovers from rate limiting", http.StatusTooManyRequests, "0", 1, false, 2, nil},
+		{"GET does not retry client errors", http.StatusBadRequest, "", 1, false, 1, ErrBadRequest},
+		{"POST is not retried on server errors", http.StatusServiceUnavailable, "", 1, true, 1, ErrServer},
+		{"POST is retried when rate limited", http.StatusTooManyRequests, "0", 1, true, 2, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+
+			c := newTestClient(t, flakyTickets(t, tt.status, tt.retryAfter, tt.failures, &attempts))
+
+			var err error
+
+			if tt.post {
+				_, err = c.MergeTicket(context.Background(), _otherID, _ticketID)
+			} else {
+				_, err = c.GetTicket(context.Background(), _ticketID)
+			}
+
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("err = %v", err)
+			}
+
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			if got := attempts.Load(); got != tt.attempts {
+				t.Errorf("made %d attempts, want %d", got, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestRetriesOff(t *testing.T) {
+	var attempts atomic.Int32
+
+	c := newTestClient(t, flakyTickets(t, http.StatusServiceUnavailable, "", 1, &attempts), WithRetries(0))
+
+	if _, err := c.GetTicket(context.Background(), _ticketID); !errors.Is(err, ErrServer) {
+		t.Fatalf("err = %v, want ErrServer", err)
+	}
+
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("made %d attempts, want 1", got)
+	}
+}
+
+func TestStreamedRequestsAreNotRetried(t *testing.T) {
+	var attempts atomic.Int32
+
+	c := newTestClient(t, flakyTickets(t, http.StatusTooManyRequests, "0", 1, &attempts))
+
+	if _, err := c.ImportTickets(context.Background(), strings.NewReader("title\na\n"), ImportOptions{}); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("made %d attempts, want 1", got)
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	var attempts atomic.Int32
+
+	c := newTestClient(t, flakyTickets(t, http.StatusServiceUnavailable, "10", 5, &attempts))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	if _, err := c.GetTicket(ctx, _ticketID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("waited %v for Retry-After after the context was done", elapsed)
+	}
+
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("made %d attempts, want 1", got)
+	}
+}
+
+func TestCanRetry(t *testing.T) {
+	var (
+		c      = &Client{}
+		netErr = errors.New("connection reset")
+	)
+
+	response := func(status int) *http.Response {
+		return &http.Response{StatusCode: status}
+	}
+
+	tests := []struct {
+		name string
+		req  request
+		resp *http.Response
+		err  error
+		want bool
+	}{
+		{"GET server error", request{method: http.MethodGet}, response(http.StatusBadGateway), nil, true},
+		{"PUT server error", request{method: http.MethodPut}, response(http.StatusInternalServerError), nil, true},
+		{"DELETE network error", request{method: http.MethodDelete}, nil, netErr, true},
+		{"GET rate limited", request{method: http.MethodGet}, response(http.StatusTooManyRequests), nil, true},
+		{"GET client error", request{method: http.MethodGet}, response(http.StatusNotFound), nil, false},
+		{"GET canceled", request{method: http.MethodGet}, nil, context.Canceled, false},
+		{"GET timed out", request{method: http.MethodGet}, nil, context.DeadlineExceeded, false},
+		{"POST server error", request{method: http.MethodPost}, response(http.StatusServiceUnavailable), nil, false},
+		{"POST network error", request{method: http.MethodPost}, nil, netErr, false},
+		{"POST rate limited", request{method: http.MethodPost}, response(http.StatusTooManyRequests), nil, true},
+		{"streamed body", request{method: http.MethodPut, body: strings.NewReader("")}, response(http.StatusTooManyRequests), nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.canRetry(tt.req, tt.resp, tt.err); got != tt.want {
+				t.Errorf("canRetry = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWait(t *testing.T) {
+	var c = &Client{backoff: 100 * time.Millisecond}
+
+	retryAfter := func(value string) *http.Response {
+		return &http.Response{Header: http.Header{"Retry-After": []string{value}}}
+	}
+
+	for _, tt := range []struct {
+		name string
+		resp *http.Response
+		want time.Duration
+	}{
+		{"Retry-After", retryAfter("3"), 3 * time.Second},
+		{"Retry-After of zero", retryAfter("0"), 0},
+		{"Retry-After over the cap", retryAfter("3600"), _maxBackoff},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.wait(5, tt.resp); got != tt.want {
+				t.Errorf("wait = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Backoff doubles with every attempt, less up to half of it in jitter,
+	// unless Retry-After is not a number of seconds
+	for attempt, backoff := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+		for _, resp := range []*http.Response{nil, retryAfter("Wed, 21 Oct 2015 07:28:00 GMT"), retryAfter("-1")} {
+			if got := c.wait(attempt, resp); got < backoff/2 || got > backoff {
+				t.Errorf("wait(%d) = %v, want between %v and %v", attempt, got, backoff/2, backoff)
+			}
+		}
+	}
+
+	if got := c.wait(20, nil); got < _maxBackoff/2 || got > _maxBackoff {
+		t.Errorf("wait(20) = %v, want between %v and %v", got, _maxBackoff/2, _maxBackoff)
+	}
+
+	if got := (&Client{}).wait(3, nil); got != 0 {
+		t.Errorf("wait without backoff = %v, want 0", got)
+	}
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+var (
+	ErrBadRequest    = errors.New("bad request")
+	ErrNotFound      = errors.New("not found")
+	ErrConflict      = errors.New("conflict")
+	ErrInvalidFields = errors.New("invalid fields")
+	ErrRateLimited   = errors.New("rate limited")
+	ErrServer        = errors.New("server error")
+
+	// ErrTicketNotFound is returned when a ticket does not exist. It matches
+	// ErrNotFound too.
+	ErrTicketNotFound = fmt.Errorf("ticket %w", ErrNotFound)
+
+	// ErrLinkNotFound is returned when a link does not exist. It matches
+	// ErrNotFound too.
+	ErrLinkNotFound = fmt.Errorf("link %w", ErrNotFound)
+
+	// _maxErrorBody caps the bytes of an error response that are read
+	_maxErrorBody int64 = 1 << 20
+)
+
+// FieldError is a problem with a single field of a request body
+type FieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// Error is an error answered by the API, read from its problem details (RFC
+// 7807). It matches the sentinel error for its status with errors.Is, e.g.
+// ErrTicketNotFound or ErrConflict.
+type Error struct {
+	Status    int          `json:"status"`
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Detail    string       `json:"detail"`
+	Instance  string       `json:"instance"`
+	Errors    []FieldError `json:"errors"`
+	RequestID string       `json:"requestId"`
+
+	// kind is the sentinel error matching Status
+	kind error
+}
+
+func (e *Error) Error() string {
+	var msg = fmt.Sprintf("%d %s", e.Status, e.Title)
+
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+
+	for _, f := range e.Errors {
+		msg += fmt.Sprintf("; %s %s", f.Field, f.Detail)
+	}
+
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+// newError reads the error answered in resp. notFound is the error matched by
+// a 404, which defaults to ErrNotFound.
+func newError(resp *http.Response, notFound error) *Error {
+	var e Error
+
+	// Errors that are not problem details keep their body as the detail
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, _maxErrorBody))
+	if err := json.Unmarshal(body, &e); err != nil || e.Title == "" {
+		e = Error{Detail: string(body)}
+	}
+
+	e.Status = resp.StatusCode
+
+	if e.Title == "" {
+		e.Title = http.StatusText(resp.StatusCode)
+	}
+
+	if e.RequestID == "" {
+		e.RequestID = resp.Header.Get("X-Request-ID")
+	}
+
+	switch {
+	case e.Status == http.StatusNotFound && notFound != nil:
+		e.kind = notFound
+	case e.Status == http.StatusNotFound:
+		e.kind = ErrNotFound
+	case e.Status == http.StatusConflict:
+		e.kind = ErrConflict
+	case e.Status == http.StatusUnprocessableEntity:
+		e.kind = ErrInvalidFields
+	case e.Status == http.StatusTooManyRequests:
+		e.kind = ErrRateLimited
+	case e.Status >= http.StatusInternalServerError:
+		e.kind = ErrServer
+	default:
+		e.kind = ErrBadRequest
+	}
+
+	return &e
+}
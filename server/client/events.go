@@ -0,0 +1,141 @@
+package client
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Events iterates over the changes made to tickets and their comments as they
+// happen, starting after the event identified by after, or from now when it
+// is empty. Iteration goes on until ctx is done: when the server ends the
+// stream, it is reopened after the last event read. Iteration stops at the
+// first error reopening it, which is yielded with a zero event.
+func (c *Client) Events(ctx context.Context, after string) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		for attempt := 0; ; attempt++ {
+			header := http.Header{"Accept": {"text/event-stream, application/problem+json"}}
+
+			if after != "" {
+				header.Set("Last-Event-ID", after)
+			}
+
+			resp, err := c.send(ctx, request{method: http.MethodGet, path: "/api/v1/tickets/events", header: header})
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err != nil {
+				yield(Event{}, err)
+				return
+			}
+
+			received, stopped := readEvents(resp.Body, func(event Event) bool {
+				// Events without an ID, e.g. EventStreamReset, cannot be
+				// resumed after
+				if event.ID != "" {
+					after = event.ID
+				}
+
+				return yield(event, nil)
+			})
+
+			resp.Body.Close()
+
+			if stopped || ctx.Err() != nil {
+				return
+			}
+
+			// A stream failing before sending anything is reopened after a
+			// growing wait, as a failed request would be retried
+			if received {
+				attempt = 0
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.wait(attempt, nil)):
+			}
+		}
+	}
+}
+
+// readEvents reads server-sent events from r, passing each to fn until it
+// returns false or r ends. It reports whether anything was received, even
+// just a comment keeping the stream alive, and whether fn stopped reading.
+// Events whose data cannot be decoded are skipped.
+func readEvents(r io.Reader, fn func(Event) bool) (received, stopped bool) {
+	var (
+		reader = bufio.NewReader(r)
+
+		// The fields of the event being read
+		id, name string
+		data     strings.Builder
+	)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// An event is only complete once followed by an empty line
+			return received, false
+		}
+
+		received = true
+
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if line != "" {
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+
+			switch field {
+			case "id":
+				id = value
+			case "event":
+				name = value
+			case "data":
+				if data.Len() > 0 {
+					data.WriteByte('\n')
+				}
+
+				data.WriteString(value)
+			}
+
+			continue
+		}
+
+		// An empty line ends the event; comments keeping the stream alive
+		// have no data
+		if data.Len() > 0 {
+			event, err := decodeEvent(id, name, data.String())
+
+			if err == nil && !fn(event) {
+				return received, true
+			}
+		}
+
+		id, name = "", ""
+		data.Reset()
+	}
+}
+
+// decodeEvent decodes the event named name with data, identified by id
+func decodeEvent(id, name, data string) (Event, error) {
+	var event Event
+
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return Event{}, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	event.ID, event.Type = id, cmp.Or(name, event.Type)
+
+	return event, nil
+}
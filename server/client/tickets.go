@@ -0,0 +1,324 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// _defaultPageSize is how many tickets Tickets reads at once by default
+var _defaultPageSize = 100
+
+// ticketPath returns the path of the ticket identified by id, followed by
+// elems
+func ticketPath(id string, elems ...string) string {
+	var path = "/api/v1/tickets/" + url.PathEscape(id)
+
+	for _, elem := range elems {
+		path += "/" + url.PathEscape(elem)
+	}
+
+	return path
+}
+
+// CreateTicket creates a ticket and returns its ID, along with open tickets
+// that look like it
+func (c *Client) CreateTicket(ctx context.Context, ticket TicketInput) (*Created, error) {
+	var created Created
+
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/tickets", body: ticket}, &created)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// GetTicket returns the ticket identified by id. A ticket merged into another
+// is returned as the ticket it was merged into.
+func (c *Client) GetTicket(ctx context.Context, id string) (*Ticket, error) {
+	var ticket Ticket
+
+	err := c.do(ctx, request{method: http.MethodGet, path: ticketPath(id), notFound: ErrTicketNotFound}, &ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ticket, nil
+}
+
+// ListTickets returns a page of the tickets matching opts, skipping the first
+// offset. A limit of zero returns every match.
+func (c *Client) ListTickets(ctx context.Context, opts ListOptions, offset, limit int) ([]Ticket, error) {
+	var (
+		query    = opts.values()
+		response struct {
+			Tickets []Ticket `json:"tickets"`
+		}
+	)
+
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/tickets", query: query}, &response)
+
+	// The API answers a filter matching nothing with 404
+	if errors.Is(err, ErrNotFound) {
+		return []Ticket{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Tickets, nil
+}
+
+// Tickets iterates over the tickets matching opts, reading them a page at a
+// time. Iteration stops at the first error, which is yielded with a zero
+// ticket.
+func (c *Client) Tickets(ctx context.Context, opts ListOptions) iter.Seq2[Ticket, error] {
+	var pageSize = opts.PageSize
+
+	if pageSize <= 0 {
+		pageSize = _defaultPageSize
+	}
+
+	return func(yield func(Ticket, error) bool) {
+		for offset := 0; ; offset += pageSize {
+			page, err := c.ListTickets(ctx, opts, offset, pageSize)
+			if err != nil {
+				yield(Ticket{}, err)
+				return
+			}
+
+			for _, ticket := range page {
+				if !yield(ticket, nil) {
+					return
+				}
+			}
+
+			if len(page) < pageSize {
+				return
+			}
+		}
+	}
+}
+
+// ReplaceTicket replaces the editable fields of the ticket identified by id
+// and returns the updated ticket. Title, Priority and Status are required;
+// fields left empty are cleared.
+func (c *Client) ReplaceTicket(ctx context.Context, id string, ticket TicketInput) (*Ticket, error) {
+	var updated Ticket
+
+	err := c.do(ctx, request{method: http.MethodPut, path: ticketPath(id), body: ticket, notFound: ErrTicketNotFound}, &updated)
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// PatchTicket applies patch to the ticket identified by id and returns the
+// updated ticket
+func (c *Client) PatchTicket(ctx context.Context, id string, patch Patch) (*Ticket, error) {
+	var (
+		updated Ticket
+		req     = request{
+			method:      http.MethodPatch,
+			path:        ticketPath(id),
+			body:        patch,
+			contentType: "application/merge-patch+json",
+			notFound:    ErrTicketNotFound,
+		}
+	)
+
+	if err := c.do(ctx, req, &updated); err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// DeleteTicket deletes the ticket identified by id
+func (c *Client) DeleteTicket(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: ticketPath(id), notFound: ErrTicketNotFound}, nil)
+}
+
+// MergeTicket merges the duplicate ticket identified by id into the ticket
+// identified by into and returns the merged ticket
+func (c *Client) MergeTicket(ctx context.Context, id, into string) (*Ticket, error) {
+	var (
+		merged Ticket
+		body   = map[string]string{"into": into}
+	)
+
+	err := c.do(ctx, request{method: http.MethodPost, path: ticketPath(id, "merge"), body: body, notFound: ErrTicketNotFound}, &merged)
+	if err != nil {
+		return nil, err
+	}
+
+	return &merged, nil
+}
+
+// SuggestDuplicates returns open tickets at site that look like a ticket with
+// title and description
+func (c *Client) SuggestDuplicates(ctx context.Context, title, description, site string) ([]Duplicate, error) {
+	var (
+		body = map[string]string{
+			"title":       title,
+			"description": description,
+			"site":        site,
+		}
+		response struct {
+			Duplicates []Duplicate `json:"duplicates"`
+		}
+	)
+
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/tickets/duplicates", body: body}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Duplicates, nil
+}
+
+// BulkTickets runs an operation on many tickets. An operation on a filter is
+// only previewed until it is repeated with the confirmation token of its
+// preview; ErrConflict is returned when the filter matches different tickets
+// by then.
+func (c *Client) BulkTickets(ctx context.Context, bulk BulkRequest) (*BulkResponse, error) {
+	var response BulkResponse
+
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/tickets/bulk", body: bulk}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// ExportTickets streams the tickets matching opts as CSV or NDJSON. The
+// caller must close the returned reader.
+func (c *Client) ExportTickets(ctx context.Context, opts ExportOptions) (io.ReadCloser, error) {
+	var query = opts.values()
+
+	if opts.Format != "" {
+		query.Set("format", opts.Format)
+	}
+
+	if len(opts.Columns) > 0 {
+		query.Set("columns", strings.Join(opts.Columns, ","))
+	}
+
+	if opts.TimeZone != "" {
+		query.Set("tz", opts.TimeZone)
+	}
+
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/api/v1/tickets/export", query: query})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// ImportTickets imports the tickets read from r, which is never retried as
+// it cannot be read twice
+func (c *Client) ImportTickets(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	var (
+		report ImportReport
+		req    = request{
+			method:      http.MethodPost,
+			path:        "/api/v1/tickets/import",
+			query:       opts.values(),
+			body:        r,
+			contentType: "text/csv",
+		}
+	)
+
+	if opts.Format == "ndjson" {
+		req.contentType = "application/x-ndjson"
+	}
+
+	if err := c.do(ctx, req, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// Links returns the links to and from the ticket identified by id
+func (c *Client) Links(ctx context.Context, id string) ([]Link, error) {
+	var response struct {
+		Links []Link `json:"links"`
+	}
+
+	err := c.do(ctx, request{method: http.MethodGet, path: ticketPath(id, "links"), notFound: ErrTicketNotFound}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Links, nil
+}
+
+// LinkTicket links the ticket identified by id to target with a link of
+// linkType, e.g. LinkBlocks
+func (c *Client) LinkTicket(ctx context.Context, id, linkType, target string) (*Link, error) {
+	var (
+		link Link
+		body = map[string]string{"type": linkType, "target": target}
+	)
+
+	err := c.do(ctx, request{method: http.MethodPost, path: ticketPath(id, "links"), body: body, notFound: ErrTicketNotFound}, &link)
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+// UnlinkTicket deletes the link identified by linkId from the ticket
+// identified by id
+func (c *Client) UnlinkTicket(ctx context.Context, id, linkId string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: ticketPath(id, "links", linkId), notFound: ErrLinkNotFound}, nil)
+}
+
+// Comments returns the comments on the ticket identified by id, oldest first
+func (c *Client) Comments(ctx context.Context, id string) ([]Comment, error) {
+	var response struct {
+		Comments []Comment `json:"comments"`
+	}
+
+	err := c.do(ctx, request{method: http.MethodGet, path: ticketPath(id, "comments"), notFound: ErrTicketNotFound}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Comments, nil
+}
+
+// AddComment comments on the ticket identified by id as author
+func (c *Client) AddComment(ctx context.Context, id, author, body string) (*Comment, error) {
+	var (
+		comment Comment
+		input   = map[string]string{"author": author, "body": body}
+	)
+
+	err := c.do(ctx, request{method: http.MethodPost, path: ticketPath(id, "comments"), body: input, notFound: ErrTicketNotFound}, &comment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
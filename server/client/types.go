@@ -0,0 +1,312 @@
+package client
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ticket statuses
+const (
+	StatusActive   = "Active"
+	StatusOpen     = "Open"
+	StatusClosed   = "Closed"
+	StatusRejected = "Rejected"
+)
+
+// Link types
+const (
+	LinkParent    = "parent"
+	LinkDuplicate = "duplicate-of"
+	LinkBlocks    = "blocks"
+	LinkRelated   = "related"
+)
+
+// Bulk actions
+const (
+	BulkUpdate = "update"
+	BulkAssign = "assign"
+	BulkDelete = "delete"
+)
+
+// Event types
+const (
+	EventTicketCreated  = "ticket.created"
+	EventTicketUpdated  = "ticket.updated"
+	EventTicketDeleted  = "ticket.deleted"
+	EventCommentCreated = "comment.created"
+
+	// EventStreamReset starts a stream that could not resume after the last
+	// event read, so events may have been missed
+	EventStreamReset = "stream.reset"
+)
+
+// Ticket is a ticket as returned by the API
+type Ticket struct {
+	ID           string         `json:"id"`
+	Title        string         `json:"title"`
+	Description  string         `json:"description"`
+	Site         string         `json:"site"`
+	Category     string         `json:"category"`
+	AssignedTo   string         `json:"assignedTo"`
+	CreatedBy    string         `json:"createdBy"`
+	Priority     int            `json:"priority"`
+	Status       string         `json:"status"`
+	Tags         []string       `json:"tags"`
+	CustomFields map[string]any `json:"customFields,omitempty"`
+	MergedInto   string         `json:"mergedInto,omitempty"`
+	MergedFrom   []string       `json:"mergedFrom,omitempty"`
+	CreatedOn    time.Time      `json:"createdOn"`
+	UpdatedAt    time.Time      `json:"updatedAt"`
+	ClosedOn     time.Time      `json:"closedOn,omitzero"`
+}
+
+// TicketInput holds the fields of a ticket to create, or to replace a ticket
+// with. Fields left empty are cleared on replacement.
+type TicketInput struct {
+	Title        string         `json:"title"`
+	Description  string         `json:"description,omitempty"`
+	Site         string         `json:"site,omitempty"`
+	Category     string         `json:"category,omitempty"`
+	AssignedTo   string         `json:"assignedTo,omitempty"`
+	CreatedBy    string         `json:"createdBy,omitempty"`
+	Priority     int            `json:"priority,omitempty"`
+	Status       string         `json:"status,omitempty"`
+	Tags         []string       `json:"tags,omitempty"`
+	CustomFields map[string]any `json:"customFields,omitempty"`
+}
+
+// Patch is a JSON Merge Patch (RFC 7396) of a ticket. Fields left out are
+// kept and nil clears a field, e.g. Patch{"assignedTo": nil}.
+type Patch map[string]any
+
+// Created is the result of creating a ticket
+type Created struct {
+	ID string `json:"id"`
+
+	// Duplicates lists open tickets at the same site that look like the new
+	// ticket
+	Duplicates []Duplicate `json:"duplicates"`
+}
+
+// Duplicate is an open ticket that looks like another ticket
+type Duplicate struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Site      string    `json:"site"`
+	Status    string    `json:"status"`
+	CreatedOn time.Time `json:"createdOn"`
+	Score     float64   `json:"score"`
+}
+
+// Link relates two tickets
+type Link struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Source    string    `json:"source"`
+	Target    string    `json:"target"`
+	CreatedOn time.Time `json:"createdOn"`
+}
+
+// Comment is a note left on a ticket
+type Comment struct {
+	ID        string    `json:"id"`
+	Ticket    string    `json:"ticket"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedOn time.Time `json:"createdOn"`
+}
+
+// Event is a change to a ticket, or a comment left on one
+type Event struct {
+	ID       string `json:"-"`
+	Type     string `json:"type"`
+	TicketID string `json:"ticketId"`
+
+	// Ticket is the ticket just after the change, missing once deleted
+	Ticket  *Ticket  `json:"ticket,omitempty"`
+	Comment *Comment `json:"comment,omitempty"`
+}
+
+// ListOptions filter and order the tickets listed. Zero-valued fields do not
+// filter anything.
+type ListOptions struct {
+	// Query matches a ticket's title and description
+	Query string
+
+	Site     string
+	Statuses []string
+
+	// AnyTags, AllTags and NoTags match tickets carrying any, all or none of
+	// the tags
+	AnyTags []string
+	AllTags []string
+	NoTags  []string
+
+	// CustomFields matches tickets whose custom fields equal the values
+	CustomFields map[string]string
+
+	// Sort orders tickets by a comma-separated list of fields, each
+	// optionally prefixed with "-" for descending order, e.g. "-priority"
+	Sort string
+
+	// PageSize is how many tickets are read at once by Tickets. Defaults to
+	// 100.
+	PageSize int
+}
+
+// values returns the query parameters of the listing endpoint matching o
+func (o ListOptions) values() url.Values {
+	var values = make(url.Values)
+
+	set := func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+
+	set("q", o.Query)
+	set("site", o.Site)
+	set("status", strings.Join(o.Statuses, ","))
+	set("tags.any", strings.Join(o.AnyTags, ","))
+	set("tags.all", strings.Join(o.AllTags, ","))
+	set("tags.none", strings.Join(o.NoTags, ","))
+	set("sort", o.Sort)
+
+	for key, value := range o.CustomFields {
+		values.Set("cf."+key, value)
+	}
+
+	return values
+}
+
+// Filter returns o as the filter of a bulk operation
+func (o ListOptions) Filter() string {
+	return o.values().Encode()
+}
+
+// ExportOptions control a ticket export
+type ExportOptions struct {
+	ListOptions
+
+	// Format is csv or ndjson. Defaults to csv.
+	Format string
+
+	// Columns lists the columns exported, including custom fields as
+	// "cf.<key>". Defaults to every built-in field.
+	Columns []string
+
+	// TimeZone is the IANA time zone of exported timestamps. Defaults to UTC.
+	TimeZone string
+}
+
+// ImportOptions control a ticket import
+type ImportOptions struct {
+	// Format is csv or ndjson
+	Format string
+
+	// Mapping maps source columns to ticket fields, e.g. "Subject" to
+	// "title". Custom fields are prefixed with "cf." and "-" skips a column.
+	Mapping map[string]string
+
+	// TimeZone is the IANA time zone of timestamps without one. Defaults to
+	// UTC.
+	TimeZone string
+
+	// DryRun checks every row without storing anything
+	DryRun bool
+
+	// BatchSize is how many tickets are inserted at once
+	BatchSize int
+}
+
+// values returns the query parameters of the import endpoint matching o
+func (o ImportOptions) values() url.Values {
+	var values = make(url.Values)
+
+	if o.Format != "" {
+		values.Set("format", o.Format)
+	}
+
+	for column, field := range o.Mapping {
+		values.Set("map."+column, field)
+	}
+
+	if o.TimeZone != "" {
+		values.Set("tz", o.TimeZone)
+	}
+
+	if o.DryRun {
+		values.Set("dryRun", "true")
+	}
+
+	if o.BatchSize > 0 {
+		values.Set("batchSize", strconv.Itoa(o.BatchSize))
+	}
+
+	return values
+}
+
+// ImportReport summarizes an import
+type ImportReport struct {
+	DryRun   bool       `json:"dryRun"`
+	Rows     int        `json:"rows"`
+	Valid    int        `json:"valid"`
+	Imported int        `json:"imported"`
+	Failed   int        `json:"failed"`
+	Ignored  []string   `json:"ignored"`
+	Errors   []RowError `json:"errors"`
+}
+
+// RowError explains why a row was not imported. Row 0 stands for the input
+// as a whole.
+type RowError struct {
+	Row    int    `json:"row"`
+	Column string `json:"column"`
+	Error  string `json:"error"`
+}
+
+// BulkRequest is an operation on many tickets, identified by IDs or matching
+// Filter. Use ListOptions.Filter to build a filter.
+type BulkRequest struct {
+	Action     string         `json:"action"`
+	IDs        []string       `json:"ids,omitempty"`
+	Filter     string         `json:"filter,omitempty"`
+	Updates    map[string]any `json:"updates,omitempty"`
+	AssignedTo *string        `json:"assignedTo,omitempty"`
+
+	// Confirm is the confirmation token of a preview, required to run an
+	// operation on a filter
+	Confirm string `json:"confirm,omitempty"`
+}
+
+// BulkResponse is the outcome of a bulk operation, or its preview when the
+// operation is on a filter and has no confirmation token
+type BulkResponse struct {
+	Action string `json:"action"`
+	Count  int    `json:"count"`
+
+	// Summary counts the results of the operation by outcome
+	Summary map[string]int `json:"summary,omitempty"`
+	Results []BulkResult   `json:"results,omitempty"`
+
+	// IDs, Confirm and ExpiresAt are set on a preview
+	IDs       []string  `json:"ids,omitempty"`
+	Confirm   string    `json:"confirm,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt,omitzero"`
+}
+
+// IsPreview reports whether r previews an operation rather than reporting
+// its outcome
+func (r *BulkResponse) IsPreview() bool {
+	return r.Confirm != ""
+}
+
+// BulkResult is the outcome of a bulk operation on a single ticket: updated,
+// deleted, notFound or failed
+type BulkResult struct {
+	ID     string `json:"id"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
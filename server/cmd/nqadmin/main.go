@@ -0,0 +1,365 @@
+// Command nqadmin maintains the ticket database in the Mongo DB cluster named
+// by MONGO_URI.
+//
+// Usage:
+//
+//	nqadmin <command> [flags] [arguments]
+//
+// The commands are:
+//
+//	migrate   apply pending schema migrations, or list them with -status
+//	reindex   create the indexes of every collection, rebuilding them with -drop
+//	backup    dump tickets and related collections to an archive
+//	restore   restore the collections dumped to an archive
+//	verify    report documents missing required fields or holding invalid values
+//
+// Run "nqadmin <command> -h" for the flags of a command.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/backup"
+	"github.com/digitalnest-wit/nestqueue/internal/migrate"
+	"github.com/digitalnest-wit/nestqueue/internal/storage"
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+// errUsage is returned when a command is given the wrong arguments. The
+// command's usage has already been printed.
+var errUsage = errors.New("usage")
+
+// errIssues is returned by verify when it finds problems, which it has
+// already reported
+var errIssues = errors.New("issues found")
+
+// _commands maps the name of each command to the function running it
+var _commands = map[string]func(ctx context.Context, args []string) error{
+	"migrate": runMigrate,
+	"reindex": runReindex,
+	"backup":  runBackup,
+	"restore": runRestore,
+	"verify":  runVerify,
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	run, ok := _commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	err := run(ctx, os.Args[2:])
+
+	switch {
+	case err == nil:
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		os.Exit(2)
+	case errors.Is(err, errIssues):
+		os.Exit(1)
+	default:
+		fmt.Fprintf(os.Stderr, "nqadmin: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// usage prints the commands of nqadmin
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage: nqadmin <command> [flags] [arguments]
+
+commands:
+  migrate   apply pending schema migrations, or list them with -status
+  reindex   create the indexes of every collection, rebuilding them with -drop
+  backup    dump tickets and related collections to an archive
+  restore   restore the collections dumped to an archive
+  verify    report documents missing required fields or holding invalid values
+`)
+}
+
+// newFlagSet returns the flag set of a command, with the -timeout flag every
+// command takes
+func newFlagSet(name, args string, timeout time.Duration) (*flag.FlagSet, *time.Duration) {
+	fs := flag.NewFlagSet("nqadmin "+name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: nqadmin %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+
+	return fs, fs.Duration("timeout", timeout, "how long the command may take")
+}
+
+// connect connects to the Mongo DB cluster and calls fn with the client,
+// giving it timeout to run
+func connect(ctx context.Context, timeout time.Duration, fn func(ctx context.Context, client *mongo.Client, logger *zap.Logger) error) error {
+	logger, err := zap.NewDevelopment(zap.IncreaseLevel(zap.InfoLevel))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = logger.Sync() }()
+
+	if err := godotenv.Load(); err != nil {
+		logger.Sugar().Debugw("failed to load environment file", "error", err)
+	}
+
+	uri, ok := os.LookupEnv("MONGO_URI")
+	if !ok {
+		return errors.New("expected MONGO_URI variable in environment")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri).SetServerAPIOptions(options.ServerAPI(options.ServerAPIVersion1)))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := client.Disconnect(context.Background()); err != nil {
+			logger.Sugar().Errorw("failed to disconnect Mongo DB client", "error", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := client.Ping(ctx, nil); err != nil {
+		return fmt.Errorf("failed to connect to Mongo DB cluster: %w", err)
+	}
+
+	return fn(ctx, client, logger)
+}
+
+// runMigrate applies pending migrations or lists every migration
+func runMigrate(ctx context.Context, args []string) error {
+	var (
+		fs, timeout = newFlagSet("migrate", "", time.Hour)
+		status      = fs.Bool("status", false, "list every migration and whether it was applied instead")
+		target      = fs.Int("to", 0, "the version to migrate to (default the latest)")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	return connect(ctx, *timeout, func(ctx context.Context, client *mongo.Client, logger *zap.Logger) error {
+		migrator := migrate.NewMigrator(client, logger)
+
+		if *status {
+			statuses, err := migrator.Status(ctx)
+			if err != nil {
+				return err
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+
+			for _, s := range statuses {
+				applied := "pending"
+				if s.Applied {
+					applied = s.AppliedOn.Local().Format(time.DateTime)
+				}
+
+				fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+			}
+
+			return tw.Flush()
+		}
+
+		applied, err := migrator.Migrate(ctx, *target)
+
+		for _, migration := range applied {
+			fmt.Printf("applied %d: %s\n", migration.Version, migration.Name)
+		}
+
+		if err == nil && len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+
+		return err
+	})
+}
+
+// runReindex creates the indexes of every collection
+func runReindex(ctx context.Context, args []string) error {
+	var (
+		fs, timeout = newFlagSet("reindex", "", time.Hour)
+		drop        = fs.Bool("drop", false, "drop every index first, rebuilding them from scratch")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	return connect(ctx, *timeout, func(ctx context.Context, client *mongo.Client, logger *zap.Logger) error {
+		return storage.EnsureIndexes(ctx, client, *drop)
+	})
+}
+
+// runBackup dumps every collection to an archive
+func runBackup(ctx context.Context, args []string) error {
+	fs, timeout := newFlagSet("backup", "<file>", time.Hour)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	return connect(ctx, *timeout, func(ctx context.Context, client *mongo.Client, logger *zap.Logger) error {
+		var (
+			path   = fs.Arg(0)
+			output io.Writer
+		)
+
+		if path == "-" {
+			output = os.Stdout
+		} else {
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			output = file
+		}
+
+		manifest, err := backup.Dump(ctx, storage.Database(client), backupCollections(), output)
+		if err != nil {
+			if path != "-" {
+				os.Remove(path)
+			}
+
+			return err
+		}
+
+		for _, name := range backupCollections() {
+			fmt.Fprintf(os.Stderr, "dumped %d documents from %s\n", manifest.Collections[name], name)
+		}
+
+		return nil
+	})
+}
+
+// runRestore restores the collections of an archive
+func runRestore(ctx context.Context, args []string) error {
+	var (
+		fs, timeout = newFlagSet("restore", "<file>", time.Hour)
+		drop        = fs.Bool("drop", false, "replace the documents of collections that are not empty")
+		batchSize   = fs.Int("batch-size", 0, "the number of documents inserted at once (default 500)")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	var input io.Reader = os.Stdin
+
+	if path := fs.Arg(0); path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		input = file
+	}
+
+	return connect(ctx, *timeout, func(ctx context.Context, client *mongo.Client, logger *zap.Logger) error {
+		opts := backup.RestoreOptions{Drop: *drop, BatchSize: *batchSize}
+
+		manifest, err := backup.Restore(ctx, storage.Database(client), input, opts)
+		if err != nil {
+			return err
+		}
+
+		for name, count := range manifest.Collections {
+			fmt.Printf("restored %d documents to %s\n", count, name)
+		}
+
+		// Collections emptied by -drop keep their indexes, but collections
+		// restored from nothing have none yet
+		return storage.EnsureIndexes(ctx, client, false)
+	})
+}
+
+// runVerify reports the problems found in stored documents
+func runVerify(ctx context.Context, args []string) error {
+	fs, timeout := newFlagSet("verify", "", time.Hour)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	return connect(ctx, *timeout, func(ctx context.Context, client *mongo.Client, logger *zap.Logger) error {
+		issues, err := storage.Verify(ctx, client)
+		if err != nil {
+			return err
+		}
+
+		if len(issues) == 0 {
+			fmt.Println("no issues found")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(tw, "COLLECTION\tID\tPROBLEM")
+
+		for _, issue := range issues {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", issue.Collection, issue.ID, issue.Problem)
+		}
+
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "%d issues found\n", len(issues))
+
+		return errIssues
+	})
+}
+
+// backupCollections lists the collections dumped by backup, which include
+// the record of applied migrations so a restored database is not migrated
+// again
+func backupCollections() []string {
+	return append(append([]string{}, storage.Collections...), migrate.Collection)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// defineCompletion defines the completion command, which prints a script
+// completing the commands and flags of nqctl
+func defineCompletion(fs *flag.FlagSet, app *app) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		if err := needArgs(fs, args, 1, 1); err != nil {
+			return err
+		}
+
+		switch args[0] {
+		case "bash":
+			writeBashCompletion(app.out)
+		case "zsh":
+			writeZshCompletion(app.out)
+		case "fish":
+			writeFishCompletion(app.out)
+		default:
+			return fmt.Errorf("unsupported shell %q, expected bash, zsh or fish", args[0])
+		}
+
+		return nil
+	}
+}
+
+// commandFlags returns the flags of cmd
+func commandFlags(cmd command) []*flag.Flag {
+	var flags []*flag.Flag
+
+	fs, _ := cmd.flagSet(&app{out: io.Discard})
+	fs.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, f)
+	})
+
+	return flags
+}
+
+// commandNames returns the names of every command
+func commandNames() []string {
+	var names []string
+
+	for _, cmd := range _commands {
+		names = append(names, cmd.name)
+	}
+
+	return names
+}
+
+// writeBashCompletion writes the completion script of bash
+func writeBashCompletion(w io.Writer) {
+	fmt.Fprintf(w, `# bash completion for nqctl, loaded with: source <(nqctl completion bash)
+_nqctl() {
+	local cur="${COMP_WORDS[COMP_CWORD]}"
+
+	if [[ $COMP_CWORD -eq 1 ]]; then
+		COMPREPLY=($(compgen -W "help %s" -- "$cur"))
+		return
+	fi
+
+	local flags=""
+	case "${COMP_WORDS[1]}" in
+`, strings.Join(commandNames(), " "))
+
+	for _, cmd := range _commands {
+		var flags []string
+
+		for _, f := range commandFlags(cmd) {
+			flags = append(flags, "-"+f.Name)
+		}
+
+		fmt.Fprintf(w, "\t%s) flags=%q ;;\n", cmd.name, strings.Join(flags, " "))
+	}
+
+	fmt.Fprint(w, `	help) COMPREPLY=($(compgen -W "`+strings.Join(commandNames(), " ")+`" -- "$cur")); return ;;
+	esac
+
+	case "$cur" in
+	-*) COMPREPLY=($(compgen -W "$flags" -- "$cur")) ;;
+	*) COMPREPLY=($(compgen -f -- "$cur")) ;;
+	esac
+}
+complete -F _nqctl nqctl
+`)
+}
+
+// writeZshCompletion writes the completion script of zsh
+func writeZshCompletion(w io.Writer) {
+	fmt.Fprint(w, `#compdef nqctl
+# zsh completion for nqctl, loaded with: source <(nqctl completion zsh)
+_nqctl() {
+	local -a commands
+	commands=(
+`)
+
+	for _, cmd := range _commands {
+		fmt.Fprintf(w, "\t\t%s\n", shellQuote(cmd.name+":"+cmd.summary))
+	}
+
+	fmt.Fprint(w, `	)
+
+	if (( CURRENT == 2 )); then
+		_describe command commands
+		return
+	fi
+
+	case $words[2] in
+`)
+
+	for _, cmd := range _commands {
+		var specs []string
+
+		for _, f := range commandFlags(cmd) {
+			specs = append(specs, shellQuote(fmt.Sprintf("-%s[%s]", f.Name, zshEscape(f.Usage))))
+		}
+
+		fmt.Fprintf(w, "\t%s) _arguments %s '*:file:_files' ;;\n", cmd.name, strings.Join(specs, " "))
+	}
+
+	fmt.Fprint(w, `	help) _describe command commands ;;
+	esac
+}
+compdef _nqctl nqctl
+`)
+}
+
+// writeFishCompletion writes the completion script of fish
+func writeFishCompletion(w io.Writer) {
+	fmt.Fprintln(w, "# fish completion for nqctl, loaded with: nqctl completion fish | source")
+	fmt.Fprintln(w, "complete -c nqctl -f")
+
+	for _, cmd := range _commands {
+		fmt.Fprintf(w, "complete -c nqctl -n __fish_use_subcommand -a %s -d %s\n", cmd.name, shellQuote(cmd.summary))
+	}
+
+	for _, cmd := range _commands {
+		for _, f := range commandFlags(cmd) {
+			fmt.Fprintf(w, "complete -c nqctl -n '__fish_seen_subcommand_from %s' -o %s -d %s\n", cmd.name, f.Name, shellQuote(f.Usage))
+		}
+	}
+}
+
+// shellQuote quotes s in single quotes for a shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// zshEscape escapes the characters of s that end a description in an
+// _arguments spec
+func zshEscape(s string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`, ":", `\:`).Replace(s)
+}
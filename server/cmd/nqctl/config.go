@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	_defaultProfile = "default"
+	_defaultServer  = "http://localhost:3000"
+)
+
+// Config holds the profiles nqctl can talk to
+type Config struct {
+	// Current names the profile used unless another is chosen
+	Current  string             `json:"current,omitempty"`
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// Profile is a server and the credentials to use with it
+type Profile struct {
+	Server string `json:"server,omitempty"`
+	Token  string `json:"token,omitempty"`
+
+	// User is who tickets are assigned to by "assign" and created by
+	User string `json:"user,omitempty"`
+}
+
+// configPath returns the path of the config file, which is
+// $NQCTL_CONFIG or nqctl/config.json in the user's config directory
+func configPath() (string, error) {
+	if path := os.Getenv("NQCTL_CONFIG"); path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "nqctl", "config.json"), nil
+}
+
+// loadConfig reads the config file, which need not exist
+func loadConfig() (*Config, error) {
+	var config = &Config{Profiles: make(map[string]Profile)}
+
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	if config.Profiles == nil {
+		config.Profiles = make(map[string]Profile)
+	}
+
+	return config, nil
+}
+
+// save writes c to the config file. Only the user can read it, as it holds
+// credentials.
+func (c *Config) save() error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// defineConfig defines the config command, which sets, switches between and
+// shows profiles
+func defineConfig(fs *flag.FlagSet, app *app) func(ctx context.Context, args []string) error {
+	var (
+		profile = fs.String("profile", "", "the profile to set (default the current profile)")
+		server  = fs.String("server", "", "the server URL of the profile")
+		token   = fs.String("token", "", "the API token of the profile")
+		user    = fs.String("user", "", "the user of the profile, who tickets are assigned to by assign")
+	)
+
+	return func(ctx context.Context, args []string) error {
+		if err := needArgs(fs, args, 1, 2); err != nil {
+			return err
+		}
+
+		config, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		switch args[0] {
+		case "set":
+			name := firstOf(*profile, config.Current, _defaultProfile)
+			if len(args) == 2 {
+				name = args[1]
+			}
+
+			p := config.Profiles[name]
+
+			fs.Visit(func(f *flag.Flag) {
+				switch f.Name {
+				case "server":
+					p.Server = *server
+				case "token":
+					p.Token = *token
+				case "user":
+					p.User = *user
+				}
+			})
+
+			config.Profiles[name] = p
+
+			if config.Current == "" {
+				config.Current = name
+			}
+
+			return config.save()
+		case "use":
+			if len(args) != 2 {
+				fs.Usage()
+				return errUsage
+			}
+
+			if _, ok := config.Profiles[args[1]]; !ok {
+				return fmt.Errorf("unknown profile %q", args[1])
+			}
+
+			config.Current = args[1]
+
+			return config.save()
+		case "show":
+			return showConfig(app, config)
+		default:
+			fs.Usage()
+			return errUsage
+		}
+	}
+}
+
+// showConfig prints every profile, hiding their tokens
+func showConfig(app *app, config *Config) error {
+	tw := tabwriter.NewWriter(app.out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "CURRENT\tNAME\tSERVER\tUSER\tTOKEN")
+
+	for _, name := range slices.Sorted(maps.Keys(config.Profiles)) {
+		var (
+			p       = config.Profiles[name]
+			current = ""
+			token   = ""
+		)
+
+		if name == config.Current {
+			current = "*"
+		}
+
+		if p.Token != "" {
+			token = strings.Repeat("*", 8)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", current, name, firstOf(p.Server, _defaultServer), p.User, token)
+	}
+
+	return tw.Flush()
+}
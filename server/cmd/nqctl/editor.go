@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/digitalnest-wit/nestqueue/client"
+)
+
+// _descriptionSeparator separates the fields of a ticket from its
+// description in the editor
+const _descriptionSeparator = "---"
+
+// editTicket opens ticket in the user's editor and returns it as edited
+func editTicket(ticket client.TicketInput) (client.TicketInput, error) {
+	text, err := editText(ticketTemplate(ticket))
+	if err != nil {
+		return ticket, err
+	}
+
+	return parseTicket(text, ticket)
+}
+
+// editComment opens an empty comment in the user's editor and returns it as
+// written, without the lines starting with #
+func editComment(id string) (string, error) {
+	text, err := editText(fmt.Sprintf("\n# Write the comment on ticket %s above. Lines starting with # are ignored.\n", id))
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+
+	for line := range strings.Lines(text) {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "")), nil
+}
+
+// editText opens text in the user's editor and returns it as edited
+func editText(text string) (string, error) {
+	file, err := os.CreateTemp("", "nqctl-*.txt")
+	if err != nil {
+		return "", err
+	}
+
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(text); err != nil {
+		file.Close()
+		return "", err
+	}
+
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	editor := firstOf(os.Getenv("VISUAL"), os.Getenv("EDITOR"), "vi")
+
+	// The editor may carry arguments, e.g. "code --wait", so it is run by the
+	// shell with the file passed separately
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", file.Name())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor failed: %w", err)
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// ticketTemplate returns ticket as the text edited by the user
+func ticketTemplate(ticket client.TicketInput) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Title: %s\n", ticket.Title)
+	fmt.Fprintf(&b, "Site: %s\n", ticket.Site)
+	fmt.Fprintf(&b, "Category: %s\n", ticket.Category)
+	fmt.Fprintf(&b, "Priority: %d\n", ticket.Priority)
+	fmt.Fprintf(&b, "Status: %s\n", ticket.Status)
+	fmt.Fprintf(&b, "Tags: %s\n", strings.Join(ticket.Tags, ", "))
+	fmt.Fprintf(&b, "Assigned to: %s\n", ticket.AssignedTo)
+	fmt.Fprintf(&b, "# Write the description below the line. Lines starting with # are ignored.\n")
+	fmt.Fprintf(&b, "%s\n%s", _descriptionSeparator, ticket.Description)
+
+	return b.String()
+}
+
+// parseTicket reads the fields of ticket back from text edited by the user
+func parseTicket(text string, ticket client.TicketInput) (client.TicketInput, error) {
+	var (
+		scanner     = bufio.NewScanner(strings.NewReader(text))
+		description []string
+		inHeader    = true
+	)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !inHeader {
+			description = append(description, line)
+			continue
+		}
+
+		if strings.TrimSpace(line) == _descriptionSeparator {
+			inHeader = false
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return ticket, fmt.Errorf("invalid line %q, expected \"Field: value\"", line)
+		}
+
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "title":
+			ticket.Title = value
+		case "site":
+			ticket.Site = value
+		case "category":
+			ticket.Category = value
+		case "priority":
+			priority, err := strconv.Atoi(value)
+			if err != nil {
+				return ticket, fmt.Errorf("invalid priority %q", value)
+			}
+
+			ticket.Priority = priority
+		case "status":
+			ticket.Status = value
+		case "tags":
+			var tags listFlag
+
+			tags.Set(value)
+			ticket.Tags = tags
+		case "assigned to":
+			ticket.AssignedTo = value
+		default:
+			return ticket, fmt.Errorf("unknown field %q", key)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return ticket, err
+	}
+
+	if inHeader {
+		return ticket, errors.New("the description separator \"" + _descriptionSeparator + "\" was removed")
+	}
+
+	ticket.Description = strings.TrimSpace(strings.Join(description, "\n"))
+
+	return ticket, nil
+}
@@ -0,0 +1,271 @@
+// Command nqctl works the ticket queue from the terminal through the ticket
+// API.
+//
+// Usage:
+//
+//	nqctl <command> [flags] [arguments]
+//
+// The server and credentials are read from the current profile in the config
+// file, which is managed with "nqctl config". Every ticket command takes -o to
+// print a table, JSON or YAML, and -profile or -server to talk to another
+// server. Run "nqctl help <command>" for the flags of a command.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/digitalnest-wit/nestqueue/client"
+)
+
+// errUsage is returned when a command is given the wrong arguments. The
+// command's usage has already been printed.
+var errUsage = errors.New("usage")
+
+// command is a subcommand of nqctl
+type command struct {
+	name    string
+	args    string
+	summary string
+
+	// define defines the flags of the command on fs and returns the function
+	// running it with the remaining arguments
+	define func(fs *flag.FlagSet, app *app) func(ctx context.Context, args []string) error
+}
+
+// _commands lists every subcommand, in the order they are listed in the usage
+var _commands []command
+
+func init() {
+	_commands = []command{
+		{name: "list", summary: "list tickets", define: defineList},
+		{name: "show", args: "<id>", summary: "show a ticket", define: defineShow},
+		{name: "create", summary: "create a ticket from flags or in $EDITOR", define: defineCreate},
+		{name: "update", args: "<id>", summary: "update the fields of a ticket", define: defineUpdate},
+		{name: "assign", args: "<id> [user]", summary: "assign a ticket, to yourself by default", define: defineAssign},
+		{name: "close", args: "<id>", summary: "close or reject a ticket", define: defineClose},
+		{name: "comment", args: "<id> [text]", summary: "comment on a ticket, in $EDITOR when no text is given", define: defineComment},
+		{name: "tui", summary: "work the queue in an interactive terminal UI", define: defineTUI},
+		{name: "config", args: "set|use|show [name]", summary: "manage server profiles", define: defineConfig},
+		{name: "completion", args: "bash|zsh|fish", summary: "print a shell completion script", define: defineCompletion},
+	}
+}
+
+// app holds the state shared by every command
+type app struct {
+	out    io.Writer
+	output string
+
+	// profile and server override the current profile and its server
+	profile string
+	server  string
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := run(ctx, os.Args[1:])
+
+	switch {
+	case err == nil:
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "nqctl: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// run runs the command named by the first of args
+func run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return errUsage
+	}
+
+	name, args := args[0], args[1:]
+
+	switch name {
+	case "help", "-h", "-help", "--help":
+		if len(args) == 0 {
+			usage(os.Stdout)
+			return nil
+		}
+
+		cmd, ok := findCommand(args[0])
+		if !ok {
+			return fmt.Errorf("unknown command %q", args[0])
+		}
+
+		fs, _ := cmd.flagSet(&app{out: os.Stdout})
+		fs.SetOutput(os.Stdout)
+		fs.Usage()
+
+		return nil
+	}
+
+	cmd, ok := findCommand(name)
+	if !ok {
+		usage(os.Stderr)
+		return fmt.Errorf("unknown command %q", name)
+	}
+
+	fs, runCmd := cmd.flagSet(&app{out: os.Stdout})
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	return runCmd(ctx, positional)
+}
+
+// flagSet defines the flags of cmd and returns them with the function running
+// it
+func (cmd command) flagSet(app *app) (*flag.FlagSet, func(ctx context.Context, args []string) error) {
+	fs := flag.NewFlagSet("nqctl "+cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s\n\n%s\n\nflags:\n", strings.TrimSpace("nqctl "+cmd.name+" [flags] "+cmd.args), capitalize(cmd.summary))
+		fs.PrintDefaults()
+	}
+
+	runCmd := cmd.define(fs, app)
+
+	return fs, runCmd
+}
+
+// findCommand returns the command called name
+func findCommand(name string) (command, bool) {
+	i := slices.IndexFunc(_commands, func(cmd command) bool { return cmd.name == name })
+	if i < 0 {
+		return command{}, false
+	}
+
+	return _commands[i], true
+}
+
+// usage prints the commands of nqctl to w
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "nqctl works the ticket queue from the terminal.\n\nusage: nqctl <command> [flags] [arguments]\n\ncommands:\n")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+
+	for _, cmd := range _commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.summary)
+	}
+
+	tw.Flush()
+
+	fmt.Fprintf(w, "\nRun \"nqctl help <command>\" for the flags of a command.\n")
+}
+
+// parseArgs parses the flags in args with fs, allowing flags to follow the
+// positional arguments, which are returned
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// defineOutput defines the flags choosing the output format and server of a
+// command
+func defineOutput(fs *flag.FlagSet, app *app) {
+	fs.StringVar(&app.output, "o", _formatTable, "the output format: table, json or yaml")
+	defineServer(fs, app)
+}
+
+// defineServer defines the flags choosing the server of a command
+func defineServer(fs *flag.FlagSet, app *app) {
+	fs.StringVar(&app.profile, "profile", "", "the profile to use instead of the current one")
+	fs.StringVar(&app.server, "server", "", "the server URL to use instead of the profile's")
+}
+
+// client returns a client for the server of the chosen profile
+func (a *app) client() (*client.Client, error) {
+	profile, err := a.currentProfile()
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []client.Option
+
+	if profile.Token != "" {
+		opts = append(opts, client.WithHeader("Authorization", "Bearer "+profile.Token))
+	}
+
+	return client.New(profile.Server, opts...)
+}
+
+// currentProfile returns the chosen profile, with the overrides of the
+// command line and environment applied
+func (a *app) currentProfile() (Profile, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return Profile{}, err
+	}
+
+	name := firstOf(a.profile, os.Getenv("NQCTL_PROFILE"), config.Current, _defaultProfile)
+
+	profile, ok := config.Profiles[name]
+	if !ok && name != _defaultProfile {
+		return Profile{}, fmt.Errorf("unknown profile %q", name)
+	}
+
+	profile.Server = firstOf(a.server, os.Getenv("NQCTL_SERVER"), profile.Server, _defaultServer)
+	profile.Token = firstOf(os.Getenv("NQCTL_TOKEN"), profile.Token)
+	profile.User = firstOf(os.Getenv("NQCTL_USER"), profile.User)
+
+	return profile, nil
+}
+
+// firstOf returns the first of values that is not empty
+func firstOf(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// capitalize returns s with its first letter in upper case
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// needArgs checks that a command was given between minArgs and maxArgs
+// arguments, printing its usage when it was not
+func needArgs(fs *flag.FlagSet, args []string, minArgs, maxArgs int) error {
+	if len(args) < minArgs || len(args) > maxArgs {
+		fs.Usage()
+		return errUsage
+	}
+
+	return nil
+}
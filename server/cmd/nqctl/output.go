@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/digitalnest-wit/nestqueue/client"
+)
+
+// Output formats
+const (
+	_formatTable = "table"
+	_formatJSON  = "json"
+	_formatYAML  = "yaml"
+)
+
+// _titleWidth caps the width of titles in tables
+var _titleWidth = 60
+
+// print writes v to the output in the chosen format, using table to write
+// it as a table
+func (a *app) print(v any, table func(w io.Writer) error) error {
+	switch a.output {
+	case _formatJSON:
+		encoder := json.NewEncoder(a.out)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(v)
+	case _formatYAML:
+		return writeYAML(a.out, v)
+	case _formatTable, "":
+		return table(a.out)
+	default:
+		return fmt.Errorf("unknown output format %q", a.output)
+	}
+}
+
+// checkOutput checks that the chosen output format is known before anything
+// is sent to the server
+func (a *app) checkOutput() error {
+	if !slices.Contains([]string{_formatTable, _formatJSON, _formatYAML, ""}, a.output) {
+		return fmt.Errorf("unknown output format %q", a.output)
+	}
+
+	return nil
+}
+
+// writeTickets writes tickets as a table
+func writeTickets(w io.Writer, tickets []client.Ticket) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tPRI\tSTATUS\tSITE\tASSIGNEE\tAGE\tTITLE")
+
+	for _, t := range tickets {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Priority, t.Status, t.Site, t.AssignedTo, age(t.CreatedOn), truncate(t.Title, _titleWidth))
+	}
+
+	return tw.Flush()
+}
+
+// writeTicket writes every field of ticket, followed by its description
+func writeTicket(w io.Writer, t client.Ticket) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	row := func(label, value string) {
+		if value != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", label, value)
+		}
+	}
+
+	row("ID", t.ID)
+	row("Title", t.Title)
+	row("Status", t.Status)
+	row("Priority", strconv.Itoa(t.Priority))
+	row("Site", t.Site)
+	row("Category", t.Category)
+	row("Assigned to", t.AssignedTo)
+	row("Created by", t.CreatedBy)
+	row("Tags", strings.Join(t.Tags, ", "))
+	row("Created", timestamp(t.CreatedOn))
+	row("Updated", timestamp(t.UpdatedAt))
+	row("Closed", timestamp(t.ClosedOn))
+	row("Merged into", t.MergedInto)
+	row("Merged from", strings.Join(t.MergedFrom, ", "))
+
+	for _, key := range slices.Sorted(maps.Keys(t.CustomFields)) {
+		row(key, fmt.Sprint(t.CustomFields[key]))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if t.Description != "" {
+		fmt.Fprintf(w, "\n%s\n", t.Description)
+	}
+
+	return nil
+}
+
+// writeComments writes comments one after the other, each under a line
+// saying who wrote it and when
+func writeComments(w io.Writer, comments []client.Comment) error {
+	for i, c := range comments {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		if _, err := fmt.Fprintf(w, "%s, %s:\n%s\n", c.Author, timestamp(c.CreatedOn), c.Body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// age returns how long ago t was, in its largest unit, e.g. 3d
+func age(t time.Time) string {
+	d := time.Since(t)
+
+	switch {
+	case t.IsZero():
+		return ""
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+// timestamp returns t in local time with its age, or nothing if t is zero
+func timestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return fmt.Sprintf("%s (%s ago)", t.Local().Format("2006-01-02 15:04"), age(t))
+}
+
+// truncate shortens s to width runes, marking that it was cut
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+
+	if width <= 0 {
+		return ""
+	}
+
+	return string([]rune(s)[:width-1]) + "…"
+}
+
+// writeYAML writes v as YAML. v is first encoded as JSON, so it is written
+// with its JSON field names and in the same order.
+func writeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	node, err := readNode(decoder)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+
+	switch node.(type) {
+	case *object, []any:
+		writeNode(&b, node, 0)
+	default:
+		b.WriteString(scalar(node))
+		b.WriteByte('\n')
+	}
+
+	_, err = io.WriteString(w, b.String())
+
+	return err
+}
+
+// object is a JSON object that keeps the order of its keys
+type object struct {
+	keys   []string
+	values map[string]any
+}
+
+// readNode reads the next JSON value from decoder, keeping the order of
+// object keys
+func readNode(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		obj := &object{values: make(map[string]any)}
+
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := readNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			obj.keys = append(obj.keys, key.(string))
+			obj.values[key.(string)] = value
+		}
+
+		_, err := decoder.Token()
+
+		return obj, err
+	case json.Delim('['):
+		list := []any{}
+
+		for decoder.More() {
+			value, err := readNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			list = append(list, value)
+		}
+
+		_, err := decoder.Token()
+
+		return list, err
+	default:
+		return token, nil
+	}
+}
+
+// writeNode writes an object or list as YAML block collections at indent
+func writeNode(b *strings.Builder, node any, indent int) {
+	pad := strings.Repeat("  ", indent)
+
+	switch n := node.(type) {
+	case *object:
+		for _, key := range n.keys {
+			b.WriteString(pad + scalar(key) + ":")
+			writeValue(b, n.values[key], indent+1)
+		}
+	case []any:
+		for _, item := range n {
+			b.WriteString(pad + "-")
+
+			// Collections in a list start on the line of their dash
+			if obj, ok := item.(*object); ok && len(obj.keys) > 0 {
+				var nested strings.Builder
+
+				writeNode(&nested, obj, indent+1)
+				b.WriteString(" " + strings.TrimPrefix(nested.String(), pad+"  "))
+
+				continue
+			}
+
+			writeValue(b, item, indent+1)
+		}
+	}
+}
+
+// writeValue writes the value of a key or list item, which starts on the
+// same line when it is a scalar or an empty collection
+func writeValue(b *strings.Builder, value any, indent int) {
+	switch v := value.(type) {
+	case *object:
+		if len(v.keys) == 0 {
+			b.WriteString(" {}\n")
+			return
+		}
+
+		b.WriteByte('\n')
+		writeNode(b, v, indent)
+	case []any:
+		if len(v) == 0 {
+			b.WriteString(" []\n")
+			return
+		}
+
+		b.WriteByte('\n')
+		writeNode(b, v, indent)
+	default:
+		b.WriteString(" " + scalar(v) + "\n")
+	}
+}
+
+// scalar returns a JSON scalar as a YAML scalar. Strings are quoted whenever
+// they could be read as anything else.
+func scalar(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		if plain(v) {
+			return v
+		}
+
+		return strconv.Quote(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// plain reports whether s can be written as a plain YAML scalar
+func plain(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s, "\n\t\"'\\#") || strings.Contains(s, ": ") {
+		return false
+	}
+
+	if strings.ContainsAny(s[:1], "-?:,[]{}&*!|>%@`") {
+		return false
+	}
+
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return false
+	}
+
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return false
+	}
+
+	return !strings.HasSuffix(s, ":")
+}
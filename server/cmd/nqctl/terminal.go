@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// errNoTerminal is returned when the terminal UI is run without a terminal
+var errNoTerminal = errors.New("the terminal UI needs a terminal")
+
+// Escape sequences drawing the terminal UI
+const (
+	_enterScreen  = "\x1b[?1049h\x1b[?25l"
+	_leaveScreen  = "\x1b[?25h\x1b[?1049l"
+	_cursorHome   = "\x1b[H"
+	_clearLine    = "\x1b[K"
+	_clearBelow   = "\x1b[J"
+	_styleReverse = "\x1b[7m"
+	_styleBold    = "\x1b[1m"
+	_styleDim     = "\x1b[2m"
+	_styleReset   = "\x1b[0m"
+)
+
+// terminal is a terminal in raw mode, switched to its alternate screen so
+// the user's scrollback is left untouched
+type terminal struct {
+	out *bufio.Writer
+
+	// state is the mode of the terminal before it was switched to raw mode
+	state string
+}
+
+// openTerminal switches the terminal to raw mode. The terminal is set up by
+// stty, which every Unix system has.
+func openTerminal() (*terminal, error) {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return nil, errNoTerminal
+	}
+
+	state, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+
+	t := &terminal{out: bufio.NewWriter(os.Stdout), state: state}
+	t.out.WriteString(_enterScreen)
+
+	return t, t.out.Flush()
+}
+
+// Close restores the terminal as it was before it was opened
+func (t *terminal) Close() error {
+	t.out.WriteString(_leaveScreen)
+	t.out.Flush()
+
+	_, err := stty(t.state)
+
+	return err
+}
+
+// size returns the number of rows and columns of the terminal
+func (t *terminal) size() (rows, cols int) {
+	size, err := stty("size")
+	if err != nil {
+		return 24, 80
+	}
+
+	fields := strings.Fields(size)
+	if len(fields) != 2 {
+		return 24, 80
+	}
+
+	rows, _ = strconv.Atoi(fields[0])
+	cols, _ = strconv.Atoi(fields[1])
+
+	if rows <= 0 || cols <= 0 {
+		return 24, 80
+	}
+
+	return rows, cols
+}
+
+// readKeys sends the keys pressed to keys until reading the terminal fails
+func (t *terminal) readKeys(keys chan<- string) {
+	defer close(keys)
+
+	var buf = make([]byte, 64)
+
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for _, key := range parseKeys(buf[:n]) {
+			keys <- key
+		}
+	}
+}
+
+// _escapeKeys names the escape sequences sent by special keys
+var _escapeKeys = map[string]string{
+	"\x1b[A":  "up",
+	"\x1b[B":  "down",
+	"\x1b[C":  "right",
+	"\x1b[D":  "left",
+	"\x1b[H":  "home",
+	"\x1b[F":  "end",
+	"\x1b[1~": "home",
+	"\x1b[4~": "end",
+	"\x1b[5~": "pgup",
+	"\x1b[6~": "pgdn",
+	"\x1bOA":  "up",
+	"\x1bOB":  "down",
+	"\x1bOH":  "home",
+	"\x1bOF":  "end",
+}
+
+// parseKeys splits the bytes read from the terminal into keys. Special keys
+// are named, e.g. "up" or "enter", and others are their character.
+func parseKeys(b []byte) []string {
+	var keys []string
+
+	for len(b) > 0 {
+		if b[0] == 0x1b {
+			matched := false
+
+			for seq, name := range _escapeKeys {
+				if strings.HasPrefix(string(b), seq) {
+					keys, b, matched = append(keys, name), b[len(seq):], true
+					break
+				}
+			}
+
+			if !matched {
+				keys, b = append(keys, "esc"), b[1:]
+			}
+
+			continue
+		}
+
+		switch b[0] {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case 0x03:
+			keys = append(keys, "ctrl-c")
+		case '\t':
+			keys = append(keys, "tab")
+		case 0x7f, 0x08:
+			keys = append(keys, "backspace")
+		default:
+			r := []rune(string(b))[0]
+			keys = append(keys, string(r))
+			b = b[len(string(r)):]
+
+			continue
+		}
+
+		b = b[1:]
+	}
+
+	return keys
+}
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// stty runs stty on the terminal with args and returns its output
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+
+	out, err := cmd.Output()
+
+	return strings.TrimSpace(string(out)), err
+}
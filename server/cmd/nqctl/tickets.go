@@ -0,0 +1,446 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/digitalnest-wit/nestqueue/client"
+)
+
+// listFlag is a flag holding a comma-separated list, which may also be given
+// several times
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+
+	return nil
+}
+
+// fieldsFlag is a flag holding custom field values as key=value, which may
+// be given several times
+type fieldsFlag map[string]string
+
+func (f fieldsFlag) String() string {
+	var pairs []string
+
+	for key, value := range f {
+		pairs = append(pairs, key+"="+value)
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func (f fieldsFlag) Set(value string) error {
+	key, value, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return errors.New("must be key=value")
+	}
+
+	f[key] = value
+
+	return nil
+}
+
+// values returns the custom fields as the values of a ticket. An empty value
+// clears a field when clear is set and is left out otherwise.
+func (f fieldsFlag) values(clear bool) map[string]any {
+	var values = make(map[string]any, len(f))
+
+	for key, value := range f {
+		switch {
+		case value != "":
+			values[key] = value
+		case clear:
+			values[key] = nil
+		}
+	}
+
+	return values
+}
+
+// defineList defines the list command
+func defineList(fs *flag.FlagSet, app *app) func(ctx context.Context, args []string) error {
+	var (
+		opts   client.ListOptions
+		status listFlag
+		tags   listFlag
+		fields = make(fieldsFlag)
+		limit  = fs.Int("limit", 50, "the most tickets to list, or 0 for every match")
+	)
+
+	defineOutput(fs, app)
+	fs.StringVar(&opts.Query, "q", "", "list tickets whose title or description match")
+	fs.StringVar(&opts.Site, "site", "", "list tickets at a site")
+	fs.Var(&status, "status", "list tickets with any of the comma-separated statuses")
+	fs.Var(&tags, "tag", "list tickets with any of the comma-separated tags")
+	fs.Var(fields, "field", "list tickets whose custom field has a value, as key=value (repeatable)")
+	fs.StringVar(&opts.Sort, "sort", "-priority,createdOn", "the comma-separated fields to sort by, \"-\" sorting in descending order")
+
+	return func(ctx context.Context, args []string) error {
+		if err := needArgs(fs, args, 0, 0); err != nil {
+			return err
+		}
+
+		if err := app.checkOutput(); err != nil {
+			return err
+		}
+
+		c, err := app.client()
+		if err != nil {
+			return err
+		}
+
+		opts.Statuses, opts.AnyTags, opts.CustomFields = status, tags, fields
+
+		if *limit > 0 && *limit < 100 {
+			opts.PageSize = *limit
+		}
+
+		tickets := []client.Ticket{}
+
+		for ticket, err := range c.Tickets(ctx, opts) {
+			if err != nil {
+				return err
+			}
+
+			tickets = append(tickets, ticket)
+
+			if len(tickets) == *limit {
+				break
+			}
+		}
+
+		return app.print(tickets, func(w io.Writer) error {
+			return writeTickets(w, tickets)
+		})
+	}
+}
+
+// defineShow defines the show command
+func defineShow(fs *flag.FlagSet, app *app) func(ctx context.Context, args []string) error {
+	defineOutput(fs, app)
+
+	return func(ctx context.Context, args []string) error {
+		if err := needArgs(fs, args, 1, 1); err != nil {
+			return err
+		}
+
+		if err := app.checkOutput(); err != nil {
+			return err
+		}
+
+		c, err := app.client()
+		if err != nil {
+			return err
+		}
+
+		ticket, err := c.GetTicket(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		return app.print(ticket, func(w io.Writer) error {
+			// Tables end with the comments on the ticket
+			comments, err := c.Comments(ctx, ticket.ID)
+			if err != nil {
+				return err
+			}
+
+			if err := writeTicket(w, *ticket); err != nil {
+				return err
+			}
+
+			if len(comments) == 0 {
+				return nil
+			}
+
+			fmt.Fprintln(w)
+
+			return writeComments(w, comments)
+		})
+	}
+}
+
+// defineCreate defines the create command
+func defineCreate(fs *flag.FlagSet, app *app) func(ctx context.Context, args []string) error {
+	var (
+		ticket = client.TicketInput{Priority: 3, Status: client.StatusOpen}
+		tags   listFlag
+		fields = make(fieldsFlag)
+		edit   = fs.Bool("editor", false, "write the ticket in $EDITOR, starting from the other flags")
+	)
+
+	defineOutput(fs, app)
+	fs.StringVar(&ticket.Title, "title", "", "the title of the ticket")
+	fs.StringVar(&ticket.Description, "description", "", "the description of the ticket")
+	fs.StringVar(&ticket.Site, "site", "", "the site of the ticket")
+	fs.StringVar(&ticket.Category, "category", "", "the category of the ticket")
+	fs.IntVar(&ticket.Priority, "priority", ticket.Priority, "the priority of the ticket, from 1 to 5")
+	fs.StringVar(&ticket.Status, "status", ticket.Status, "the status of the ticket")
+	fs.StringVar(&ticket.AssignedTo, "assign", "", "the user to assign the ticket to")
+	fs.StringVar(&ticket.CreatedBy, "created-by", "", "who the ticket is created by (default the profile's user)")
+	fs.Var(&tags, "tag", "the comma-separated tags of the ticket")
+	fs.Var(fields, "field", "a custom field of the ticket, as key=value (repeatable)")
+
+	return func(ctx context.Context, args []string) error {
+		if err := needArgs(fs, args, 0, 0); err != nil {
+			return err
+		}
+
+		if err := app.checkOutput(); err != nil {
+			return err
+		}
+
+		profile, err := app.currentProfile()
+		if err != nil {
+			return err
+		}
+
+		ticket.Tags = tags
+		ticket.CreatedBy = firstOf(ticket.CreatedBy, profile.User)
+
+		if len(fields) > 0 {
+			ticket.CustomFields = fields.values(false)
+		}
+
+		if *edit {
+			if ticket, err = editTicket(ticket); err != nil {
+				return err
+			}
+		}
+
+		if strings.TrimSpace(ticket.Title) == "" {
+			return errors.New("a ticket needs a title, set with -title or -editor")
+		}
+
+		c, err := app.client()
+		if err != nil {
+			return err
+		}
+
+		created, err := c.CreateTicket(ctx, ticket)
+		if err != nil {
+			return err
+		}
+
+		return app.print(created, func(w io.Writer) error {
+			fmt.Fprintln(w, created.ID)
+
+			if len(created.Duplicates) > 0 {
+				fmt.Fprintln(os.Stderr, "\nThese open tickets look like the new ticket:")
+
+				for _, d := range created.Duplicates {
+					fmt.Fprintf(os.Stderr, "  %s  %s\n", d.ID, d.Title)
+				}
+			}
+
+			return nil
+		})
+	}
+}
+
+// defineUpdate defines the update command, which only changes the fields
+// whose flags are set
+func defineUpdate(fs *flag.FlagSet, app *app) func(ctx context.Context, args []string) error {
+	var (
+		title       = fs.String("title", "", "the title of the ticket")
+		description = fs.String("description", "", "the description of the ticket")
+		site        = fs.String("site", "", "the site of the ticket")
+		category    = fs.String("category", "", "the category of the ticket")
+		priority    = fs.Int("priority", 0, "the priority of the ticket, from 1 to 5")
+		status      = fs.String("status", "", "the status of the ticket")
+		assign      = fs.String("assign", "", "the user to assign the ticket to")
+		unassign    = fs.Bool("unassign", false, "leave the ticket unassigned")
+		tags        listFlag
+		fields      = make(fieldsFlag)
+	)
+
+	defineOutput(fs, app)
+	fs.Var(&tags, "tags", "the comma-separated tags of the ticket, replacing its tags")
+	fs.Var(fields, "field", "a custom field of the ticket, as key=value, clearing it when the value is empty (repeatable)")
+
+	return func(ctx context.Context, args []string) error {
+		if err := needArgs(fs, args, 1, 1); err != nil {
+			return err
+		}
+
+		if err := app.checkOutput(); err != nil {
+			return err
+		}
+
+		var patch = make(client.Patch)
+
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "title":
+				patch["title"] = *title
+			case "description":
+				patch["description"] = *description
+			case "site":
+				patch["site"] = *site
+			case "category":
+				patch["category"] = *category
+			case "priority":
+				patch["priority"] = *priority
+			case "status":
+				patch["status"] = *status
+			case "assign":
+				patch["assignedTo"] = *assign
+			case "unassign":
+				if *unassign {
+					patch["assignedTo"] = nil
+				}
+			case "tags":
+				patch["tags"] = []string(tags)
+			case "field":
+				patch["customFields"] = fields.values(true)
+			}
+		})
+
+		if len(patch) == 0 {
+			return errors.New("nothing to update, set the flags of the fields to change")
+		}
+
+		return app.patchTicket(ctx, args[0], patch)
+	}
+}
+
+// defineAssign defines the assign command
+func defineAssign(fs *flag.FlagSet, app *app) func(ctx context.Context, args []string) error {
+	defineOutput(fs, app)
+
+	return func(ctx context.Context, args []string) error {
+		if err := needArgs(fs, args, 1, 2); err != nil {
+			return err
+		}
+
+		if err := app.checkOutput(); err != nil {
+			return err
+		}
+
+		profile, err := app.currentProfile()
+		if err != nil {
+			return err
+		}
+
+		user := profile.User
+		if len(args) == 2 {
+			user = args[1]
+		}
+
+		if user == "" {
+			return errors.New("no user to assign to, name one or set the profile's with \"nqctl config set -user\"")
+		}
+
+		return app.patchTicket(ctx, args[0], client.Patch{"assignedTo": user})
+	}
+}
+
+// defineClose defines the close command
+func defineClose(fs *flag.FlagSet, app *app) func(ctx context.Context, args []string) error {
+	var reject = fs.Bool("reject", false, "reject the ticket instead of closing it")
+
+	defineOutput(fs, app)
+
+	return func(ctx context.Context, args []string) error {
+		if err := needArgs(fs, args, 1, 1); err != nil {
+			return err
+		}
+
+		if err := app.checkOutput(); err != nil {
+			return err
+		}
+
+		var status = client.StatusClosed
+
+		if *reject {
+			status = client.StatusRejected
+		}
+
+		return app.patchTicket(ctx, args[0], client.Patch{"status": status})
+	}
+}
+
+// defineComment defines the comment command
+func defineComment(fs *flag.FlagSet, app *app) func(ctx context.Context, args []string) error {
+	var author = fs.String("author", "", "who the comment is by (default the profile's user)")
+
+	defineOutput(fs, app)
+
+	return func(ctx context.Context, args []string) error {
+		if err := needArgs(fs, args, 1, 2); err != nil {
+			return err
+		}
+
+		if err := app.checkOutput(); err != nil {
+			return err
+		}
+
+		profile, err := app.currentProfile()
+		if err != nil {
+			return err
+		}
+
+		if *author = firstOf(*author, profile.User); *author == "" {
+			return errors.New("no author for the comment, set -author or the profile's user with \"nqctl config set -user\"")
+		}
+
+		var text string
+
+		if len(args) == 2 {
+			text = args[1]
+		} else if text, err = editComment(args[0]); err != nil {
+			return err
+		}
+
+		if strings.TrimSpace(text) == "" {
+			return errors.New("the comment is empty")
+		}
+
+		c, err := app.client()
+		if err != nil {
+			return err
+		}
+
+		comment, err := c.AddComment(ctx, args[0], *author, text)
+		if err != nil {
+			return err
+		}
+
+		return app.print(comment, func(w io.Writer) error {
+			return writeComments(w, []client.Comment{*comment})
+		})
+	}
+}
+
+// patchTicket applies patch to the ticket identified by id and prints the
+// updated ticket
+func (a *app) patchTicket(ctx context.Context, id string, patch client.Patch) error {
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	ticket, err := c.PatchTicket(ctx, id, patch)
+	if err != nil {
+		return err
+	}
+
+	return a.print(ticket, func(w io.Writer) error {
+		return writeTicket(w, *ticket)
+	})
+}
@@ -0,0 +1,744 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/digitalnest-wit/nestqueue/client"
+)
+
+// Orders of the tickets in the terminal UI
+const (
+	_sortPriority = "priority"
+	_sortAge      = "age"
+)
+
+// _statusKeys maps the keys choosing a status to the status
+var _statusKeys = map[string]string{
+	"o": client.StatusOpen,
+	"a": client.StatusActive,
+	"c": client.StatusClosed,
+	"r": client.StatusRejected,
+}
+
+// _tuiHelp lists the keys of the terminal UI
+var _tuiHelp = "↑↓ move  s sort  S reverse  a assign to me  c status  n add note  r refresh  q quit"
+
+// defineTUI defines the tui command, which shows the queue in an interactive
+// terminal UI refreshed as tickets change on the server, or by polling it
+// while it cannot stream the changes
+func defineTUI(fs *flag.FlagSet, app *app) func(ctx context.Context, args []string) error {
+	var (
+		opts     = client.ListOptions{Sort: "-priority,createdOn"}
+		status   listFlag
+		tags     listFlag
+		interval = fs.Duration("refresh", 15*time.Second, "how often tickets are refreshed from the server while it cannot stream their changes")
+		limit    = fs.Int("limit", 500, "the most tickets to show")
+	)
+
+	defineServer(fs, app)
+	fs.StringVar(&opts.Query, "q", "", "show tickets whose title or description match")
+	fs.StringVar(&opts.Site, "site", "", "show tickets at a site")
+	fs.Var(&status, "status", "show tickets with any of the comma-separated statuses (default Open,Active)")
+	fs.Var(&tags, "tag", "show tickets with any of the comma-separated tags")
+
+	return func(ctx context.Context, args []string) error {
+		if err := needArgs(fs, args, 0, 0); err != nil {
+			return err
+		}
+
+		if *interval <= 0 || *limit <= 0 {
+			return fmt.Errorf("-refresh and -limit must be positive")
+		}
+
+		profile, err := app.currentProfile()
+		if err != nil {
+			return err
+		}
+
+		c, err := app.client()
+		if err != nil {
+			return err
+		}
+
+		opts.Statuses, opts.AnyTags = status, tags
+
+		if len(status) == 0 {
+			opts.Statuses = []string{client.StatusOpen, client.StatusActive}
+		}
+
+		t := &tui{
+			client:   c,
+			server:   profile.Server,
+			user:     profile.User,
+			opts:     opts,
+			limit:    *limit,
+			sortBy:   _sortPriority,
+			updates:  make(chan func(), 16),
+			interval: *interval,
+		}
+
+		return t.run(ctx)
+	}
+}
+
+// tui is the state of the terminal UI. It is only changed by the goroutine
+// running it; requests to the server send their results back as updates.
+type tui struct {
+	client *client.Client
+	server string
+	user   string
+	opts   client.ListOptions
+	limit  int
+
+	tickets []client.Ticket
+
+	// comments are the comments on the ticket identified by commentsFor
+	comments    []client.Comment
+	commentsFor string
+
+	// selected is the index of the selected ticket and top the index of the
+	// first ticket shown
+	selected int
+	top      int
+
+	sortBy  string
+	reverse bool
+
+	// choosingStatus is set while waiting for the key choosing the status
+	// of the selected ticket
+	choosingStatus bool
+
+	// writingNote is set while the note in note is written, to be added as
+	// a comment on the selected ticket
+	writingNote bool
+	note        []rune
+
+	message   string
+	loading   bool
+	refreshed time.Time
+
+	// stale is set when tickets changed while they were being read, so they
+	// are read again
+	stale bool
+
+	// live is set while changes are streamed from the server, which turns
+	// polling off
+	live bool
+
+	updates  chan func()
+	interval time.Duration
+}
+
+// run runs the terminal UI until the user quits or ctx is done
+func (t *tui) run(ctx context.Context) error {
+	term, err := openTerminal()
+	if err != nil {
+		return err
+	}
+
+	defer term.Close()
+
+	// Requests still running when the user quits are canceled
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		keys   = make(chan string)
+		ticker = time.NewTicker(t.interval)
+	)
+
+	defer ticker.Stop()
+
+	go term.readKeys(keys)
+	go t.follow(ctx)
+
+	t.refresh(ctx)
+
+	for {
+		t.loadComments(ctx)
+		t.draw(term)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case key, ok := <-keys:
+			if !ok || t.handleKey(ctx, key) {
+				return nil
+			}
+		case update := <-t.updates:
+			update()
+		case <-ticker.C:
+			if !t.live {
+				t.refresh(ctx)
+			}
+		}
+	}
+}
+
+// follow streams the changes made to tickets on the server, refreshing the
+// tickets as they change. When the stream fails, tickets are polled for
+// until it is opened again.
+func (t *tui) follow(ctx context.Context) {
+	// failing is set once a failure is shown, so it is not shown again on
+	// every retry
+	var failing bool
+
+	for {
+		t.update(ctx, func() {
+			t.live = true
+		})
+
+		for event, err := range t.client.Events(ctx, "") {
+			if err != nil {
+				shown := failing
+				failing = true
+
+				t.update(ctx, func() {
+					t.live = false
+
+					if !shown {
+						t.message = "Live updates failed, polling instead: " + err.Error()
+					}
+				})
+
+				break
+			}
+
+			failing = false
+
+			t.update(ctx, func() {
+				t.handleEvent(ctx, event)
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(t.interval):
+		}
+
+		// Changes may have been missed while the stream was down
+		t.update(ctx, func() {
+			t.refresh(ctx)
+		})
+	}
+}
+
+// update sends fn to be run by the goroutine running the UI, unless ctx is
+// done
+func (t *tui) update(ctx context.Context, fn func()) {
+	select {
+	case t.updates <- fn:
+	case <-ctx.Done():
+	}
+}
+
+// handleEvent acts on a change made on the server
+func (t *tui) handleEvent(ctx context.Context, event client.Event) {
+	if event.Type != client.EventCommentCreated {
+		// The server decides which tickets match the filters, so they are
+		// read again rather than patched in place
+		t.refresh(ctx)
+		return
+	}
+
+	if event.TicketID == t.commentsFor && event.Comment != nil && !slices.ContainsFunc(t.comments, func(c client.Comment) bool { return c.ID == event.Comment.ID }) {
+		t.comments = append(t.comments, *event.Comment)
+	}
+}
+
+// handleKey acts on a key pressed by the user and reports whether to quit
+func (t *tui) handleKey(ctx context.Context, key string) bool {
+	t.message = ""
+
+	if t.writingNote {
+		t.writeNote(ctx, key)
+		return false
+	}
+
+	if t.choosingStatus {
+		t.choosingStatus = false
+
+		if status, ok := _statusKeys[key]; ok {
+			t.updateSelected(ctx, client.Patch{"status": status}, "Set the status to "+status)
+		}
+
+		return false
+	}
+
+	switch key {
+	case "q", "ctrl-c":
+		return true
+	case "up", "k":
+		t.move(-1)
+	case "down", "j":
+		t.move(1)
+	case "pgup":
+		t.move(-10)
+	case "pgdn":
+		t.move(10)
+	case "home", "g":
+		t.move(-len(t.tickets))
+	case "end", "G":
+		t.move(len(t.tickets))
+	case "s":
+		t.sortBy = map[string]string{_sortPriority: _sortAge, _sortAge: _sortPriority}[t.sortBy]
+		t.setTickets(t.tickets)
+	case "S":
+		t.reverse = !t.reverse
+		t.setTickets(t.tickets)
+	case "r":
+		t.refresh(ctx)
+	case "a":
+		if t.user == "" {
+			t.message = "No user to assign to, set one with \"nqctl config set -user\""
+			break
+		}
+
+		t.updateSelected(ctx, client.Patch{"assignedTo": t.user}, "Assigned to "+t.user)
+	case "c":
+		if len(t.tickets) > 0 {
+			t.choosingStatus = true
+		}
+	case "n":
+		if t.user == "" {
+			t.message = "No author for notes, set one with \"nqctl config set -user\""
+			break
+		}
+
+		if len(t.tickets) > 0 {
+			t.writingNote, t.note = true, nil
+		}
+	}
+
+	return false
+}
+
+// writeNote acts on a key pressed while writing a note: enter adds the note
+// to the selected ticket and esc drops it
+func (t *tui) writeNote(ctx context.Context, key string) {
+	switch key {
+	case "enter":
+		t.writingNote = false
+		t.addNote(ctx, strings.TrimSpace(string(t.note)))
+	case "esc", "ctrl-c":
+		t.writingNote = false
+	case "backspace":
+		if len(t.note) > 0 {
+			t.note = t.note[:len(t.note)-1]
+		}
+	case "tab":
+		t.note = append(t.note, ' ')
+	default:
+		// Other special keys are named, while characters are single runes
+		if utf8.RuneCountInString(key) == 1 {
+			t.note = append(t.note, []rune(key)...)
+		}
+	}
+}
+
+// addNote adds note as a comment on the selected ticket
+func (t *tui) addNote(ctx context.Context, note string) {
+	if note == "" || len(t.tickets) == 0 {
+		return
+	}
+
+	id := t.tickets[t.selected].ID
+	t.message = "Adding note…"
+
+	go func() {
+		comment, err := t.client.AddComment(ctx, id, t.user, note)
+
+		t.update(ctx, func() {
+			if err != nil {
+				t.message = "Adding note failed: " + err.Error()
+				return
+			}
+
+			// The note may have been streamed back already
+			if id == t.commentsFor && !slices.ContainsFunc(t.comments, func(c client.Comment) bool { return c.ID == comment.ID }) {
+				t.comments = append(t.comments, *comment)
+			}
+
+			t.message = "Added the note"
+		})
+	}()
+}
+
+// loadComments reads the comments on the selected ticket unless they were
+// read already
+func (t *tui) loadComments(ctx context.Context) {
+	if len(t.tickets) == 0 || t.tickets[t.selected].ID == t.commentsFor {
+		return
+	}
+
+	id := t.tickets[t.selected].ID
+	t.comments, t.commentsFor = nil, id
+
+	go func() {
+		comments, err := t.client.Comments(ctx, id)
+
+		t.update(ctx, func() {
+			// Another ticket may have been selected since
+			if id != t.commentsFor {
+				return
+			}
+
+			if err != nil {
+				t.message = "Reading notes failed: " + err.Error()
+				return
+			}
+
+			// Notes streamed while they were read are kept
+			for _, c := range t.comments {
+				if !slices.ContainsFunc(comments, func(read client.Comment) bool { return read.ID == c.ID }) {
+					comments = append(comments, c)
+				}
+			}
+
+			t.comments = comments
+		})
+	}()
+}
+
+// move moves the selection by n tickets
+func (t *tui) move(n int) {
+	t.selected = max(0, min(t.selected+n, len(t.tickets)-1))
+}
+
+// refresh reads the tickets from the server. When they are being read
+// already, they are read again once read, as they may have changed since.
+func (t *tui) refresh(ctx context.Context) {
+	if t.loading {
+		t.stale = true
+		return
+	}
+
+	t.loading, t.stale = true, false
+
+	go func() {
+		var tickets []client.Ticket
+
+		err := func() error {
+			for ticket, err := range t.client.Tickets(ctx, t.opts) {
+				if err != nil {
+					return err
+				}
+
+				if tickets = append(tickets, ticket); len(tickets) == t.limit {
+					break
+				}
+			}
+
+			return nil
+		}()
+
+		t.update(ctx, func() {
+			t.loading = false
+
+			if t.stale {
+				defer t.refresh(ctx)
+			}
+
+			if err != nil {
+				t.message = "Refresh failed: " + err.Error()
+				return
+			}
+
+			t.setTickets(tickets)
+			t.refreshed = time.Now()
+		})
+	}()
+}
+
+// updateSelected applies patch to the selected ticket, showing done once it
+// is applied
+func (t *tui) updateSelected(ctx context.Context, patch client.Patch, done string) {
+	if len(t.tickets) == 0 {
+		return
+	}
+
+	id := t.tickets[t.selected].ID
+	t.message = "Updating…"
+
+	go func() {
+		ticket, err := t.client.PatchTicket(ctx, id, patch)
+
+		t.update(ctx, func() {
+			if err != nil {
+				t.message = "Update failed: " + err.Error()
+				return
+			}
+
+			if i := slices.IndexFunc(t.tickets, func(t client.Ticket) bool { return t.ID == id }); i >= 0 {
+				t.tickets[i] = *ticket
+				t.setTickets(t.tickets)
+			}
+
+			t.message = done
+		})
+	}()
+}
+
+// setTickets shows tickets in the chosen order, keeping the same ticket
+// selected
+func (t *tui) setTickets(tickets []client.Ticket) {
+	var selectedID string
+
+	if t.selected < len(t.tickets) {
+		selectedID = t.tickets[t.selected].ID
+	}
+
+	slices.SortStableFunc(tickets, func(a, b client.Ticket) int {
+		var (
+			byPriority = cmp.Compare(b.Priority, a.Priority)
+			byAge      = a.CreatedOn.Compare(b.CreatedOn)
+			order      = cmp.Or(byPriority, byAge)
+		)
+
+		if t.sortBy == _sortAge {
+			order = cmp.Or(byAge, byPriority)
+		}
+
+		if t.reverse {
+			return -order
+		}
+
+		return order
+	})
+
+	t.tickets = tickets
+
+	if i := slices.IndexFunc(tickets, func(t client.Ticket) bool { return t.ID == selectedID }); i >= 0 {
+		t.selected = i
+	}
+
+	t.move(0)
+}
+
+// draw draws the whole UI: a header, the table of tickets, the detail pane
+// of the selected ticket and a footer
+func (t *tui) draw(term *terminal) {
+	var (
+		rows, cols  = term.size()
+		tableHeight = max(3, (rows-4)/2)
+		lines       []string
+	)
+
+	// Header
+	order := map[bool]string{false: "most urgent first", true: "least urgent first"}[t.reverse]
+	if t.sortBy == _sortAge {
+		order = map[bool]string{false: "oldest first", true: "newest first"}[t.reverse]
+	}
+
+	header := fmt.Sprintf(" nqctl  %s  %d tickets  sorted by %s (%s)", t.server, len(t.tickets), t.sortBy, order)
+
+	switch {
+	case t.loading && t.refreshed.IsZero():
+		header += "  loading…"
+	case !t.refreshed.IsZero():
+		header += "  refreshed " + t.refreshed.Format("15:04:05")
+	}
+
+	header += map[bool]string{false: "  polling", true: "  live"}[t.live]
+
+	lines = append(lines, styled(_styleReverse, fit(header, cols, true)))
+
+	// Table
+	lines = append(lines, styled(_styleBold, fit(ticketRow("PRI", "STATUS", "SITE", "ASSIGNEE", "AGE", "TITLE"), cols, false)))
+
+	if t.selected < t.top {
+		t.top = t.selected
+	}
+
+	if t.selected >= t.top+tableHeight {
+		t.top = t.selected - tableHeight + 1
+	}
+
+	for i := t.top; i < t.top+tableHeight; i++ {
+		if i >= len(t.tickets) {
+			lines = append(lines, "")
+			continue
+		}
+
+		ticket := t.tickets[i]
+		row := fit(ticketRow(strconv.Itoa(ticket.Priority), ticket.Status, ticket.Site, ticket.AssignedTo, age(ticket.CreatedOn), ticket.Title), cols, true)
+
+		if i == t.selected {
+			row = styled(_styleReverse, row)
+		}
+
+		lines = append(lines, row)
+	}
+
+	lines = append(lines, styled(_styleDim, strings.Repeat("─", cols)))
+
+	// Detail pane, filling the rows left above the footer
+	if len(t.tickets) > 0 {
+		for _, line := range ticketDetail(t.tickets[t.selected], t.comments, cols) {
+			lines = append(lines, fit(line, cols, false))
+		}
+	}
+
+	for len(lines) < rows-1 {
+		lines = append(lines, "")
+	}
+
+	lines = lines[:rows-1]
+
+	// Footer
+	footer := _tuiHelp
+
+	switch {
+	case t.writingNote:
+		footer = "Note: " + string(t.note) + "▏  enter adds  esc cancels"
+	case t.choosingStatus:
+		footer = "Status: [o]pen  [a]ctive  [c]losed  [r]ejected  any other key cancels"
+	case t.message != "":
+		footer = t.message
+	}
+
+	lines = append(lines, styled(_styleReverse, fit(" "+footer, cols, true)))
+
+	var b strings.Builder
+
+	b.WriteString(_cursorHome)
+
+	for i, line := range lines {
+		b.WriteString(line + _clearLine)
+
+		if i < len(lines)-1 {
+			b.WriteString("\r\n")
+		}
+	}
+
+	b.WriteString(_clearBelow)
+
+	term.out.WriteString(b.String())
+	term.out.Flush()
+}
+
+// ticketRow returns a row of the table of tickets
+func ticketRow(priority, status, site, assignee, age, title string) string {
+	return fmt.Sprintf(" %s %s %s %s %s %s", pad(priority, 3), pad(status, 8), pad(site, 12), pad(assignee, 14), pad(age, 4), title)
+}
+
+// ticketDetail returns the lines of the detail pane of ticket: its fields,
+// what happened to it, and its description and comments wrapped to width
+func ticketDetail(ticket client.Ticket, comments []client.Comment, width int) []string {
+	var lines = []string{
+		styled(_styleBold, truncate(ticket.Title, width)),
+		fmt.Sprintf("ID %s  ·  %s  ·  priority %d  ·  %s  ·  %s", ticket.ID, ticket.Status, ticket.Priority, firstOf(ticket.Site, "no site"), firstOf(ticket.Category, "no category")),
+		fmt.Sprintf("Assigned to %s  ·  tags %s", firstOf(ticket.AssignedTo, "nobody"), firstOf(strings.Join(ticket.Tags, ", "), "none")),
+	}
+
+	var custom []string
+
+	for _, key := range slices.Sorted(maps.Keys(ticket.CustomFields)) {
+		custom = append(custom, fmt.Sprintf("%s: %v", key, ticket.CustomFields[key]))
+	}
+
+	if len(custom) > 0 {
+		lines = append(lines, strings.Join(custom, "  ·  "))
+	}
+
+	history := []string{fmt.Sprintf("Created %s by %s", timestamp(ticket.CreatedOn), firstOf(ticket.CreatedBy, "unknown"))}
+
+	if !ticket.UpdatedAt.Equal(ticket.CreatedOn) {
+		history = append(history, "updated "+timestamp(ticket.UpdatedAt))
+	}
+
+	if !ticket.ClosedOn.IsZero() {
+		history = append(history, "closed "+timestamp(ticket.ClosedOn))
+	}
+
+	if len(ticket.MergedFrom) > 0 {
+		history = append(history, "merged from "+strings.Join(ticket.MergedFrom, ", "))
+	}
+
+	lines = append(lines, styled(_styleDim, truncate(strings.Join(history, "  ·  "), width)), "")
+
+	for _, paragraph := range strings.Split(firstOf(ticket.Description, "No description."), "\n") {
+		lines = append(lines, wrap(paragraph, width-1)...)
+	}
+
+	for _, c := range comments {
+		lines = append(lines, "", styled(_styleDim, truncate(fmt.Sprintf("%s, %s:", c.Author, timestamp(c.CreatedOn)), width)))
+
+		for _, paragraph := range strings.Split(c.Body, "\n") {
+			lines = append(lines, wrap(paragraph, width-1)...)
+		}
+	}
+
+	return lines
+}
+
+// styled returns s drawn in style
+func styled(style, s string) string {
+	return style + s + _styleReset
+}
+
+// pad truncates or pads s to width runes
+func pad(s string, width int) string {
+	s = truncate(s, width)
+
+	return s + strings.Repeat(" ", width-utf8.RuneCountInString(s))
+}
+
+// fit truncates s to width columns, padding it to the full width when fill
+// is set. Escape sequences in s do not count towards its width.
+func fit(s string, width int, fill bool) string {
+	if strings.Contains(s, "\x1b") {
+		return s
+	}
+
+	if fill {
+		return pad(s, width)
+	}
+
+	return truncate(s, width)
+}
+
+// wrap breaks s into lines of at most width runes, between words where it
+// can
+func wrap(s string, width int) []string {
+	var (
+		lines []string
+		line  string
+	)
+
+	if width <= 0 {
+		return []string{s}
+	}
+
+	for _, word := range strings.Fields(s) {
+		for utf8.RuneCountInString(word) > width {
+			if line != "" {
+				lines, line = append(lines, line), ""
+			}
+
+			runes := []rune(word)
+			lines, word = append(lines, string(runes[:width])), string(runes[width:])
+		}
+
+		switch {
+		case line == "":
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+			line += " " + word
+		default:
+			lines, line = append(lines, line), word
+		}
+	}
+
+	return append(lines, line)
+}
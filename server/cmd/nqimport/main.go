@@ -0,0 +1,179 @@
+// Command nqimport imports tickets from a CSV or NDJSON file straight into the
+// Mongo DB cluster named by MONGO_URI, printing a report of the rows that
+// could not be imported.
+//
+// Usage:
+//
+//	nqimport [flags] <file>
+//
+// The file is read from standard input when it is "-". Columns are mapped to
+// ticket fields with -map, e.g. -map Subject=title -map Opened=createdOn.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/importer"
+	"github.com/digitalnest-wit/nestqueue/internal/storage"
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+// mapping collects repeated -map column=field flags
+type mapping map[string]string
+
+func (m mapping) String() string {
+	pairs := make([]string, 0, len(m))
+
+	for column, field := range m {
+		pairs = append(pairs, column+"="+field)
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func (m mapping) Set(value string) error {
+	column, field, ok := strings.Cut(value, "=")
+	if !ok || column == "" {
+		return errors.New("expected column=field")
+	}
+
+	m[column] = field
+
+	return nil
+}
+
+var (
+	_mapping   = make(mapping)
+	_format    = flag.String("format", "", "the format of the file, csv or ndjson (default from the file extension)")
+	_timezone  = flag.String("tz", "UTC", "the time zone of timestamps without one")
+	_dryRun    = flag.Bool("dry-run", false, "check every row without storing anything")
+	_batchSize = flag.Int("batch-size", 0, "the number of tickets inserted at once (default 500)")
+	_timeout   = flag.Duration("timeout", time.Hour, "how long the import may take")
+)
+
+func main() {
+	flag.Var(_mapping, "map", "map a column to a ticket field as column=field; may be repeated")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <file>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	report, err := run(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nqimport: %s\n", err)
+		os.Exit(1)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "nqimport: %s\n", err)
+		os.Exit(1)
+	}
+
+	if len(report.Errors) > 0 {
+		os.Exit(1)
+	}
+}
+
+// run imports the tickets in the file at path
+func run(path string) (*importer.Report, error) {
+	var input io.Reader = os.Stdin
+
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		input = file
+	}
+
+	opts := importer.Options{
+		Format:    *_format,
+		Mapping:   _mapping,
+		DryRun:    *_dryRun,
+		BatchSize: *_batchSize,
+	}
+
+	if opts.Format == "" {
+		opts.Format = formatOf(path)
+	}
+
+	location, err := time.LoadLocation(*_timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Location = location
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	logger, err := zap.NewDevelopment(zap.IncreaseLevel(zap.WarnLevel))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = logger.Sync() }()
+
+	if err := godotenv.Load(); err != nil {
+		logger.Sugar().Debugw("failed to load environment file", "error", err)
+	}
+
+	uri, ok := os.LookupEnv("MONGO_URI")
+	if !ok {
+		return nil, errors.New("expected MONGO_URI variable in environment")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri).SetServerAPIOptions(options.ServerAPI(options.ServerAPIVersion1)))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := client.Disconnect(context.Background()); err != nil {
+			logger.Sugar().Errorw("failed to disconnect Mongo DB client", "error", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *_timeout)
+	defer cancel()
+
+	tickets, err := storage.NewTicketStore(ctx, client, nil, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return importer.NewImporter(tickets, storage.NewFieldStore(client, logger), logger).Import(ctx, input, opts)
+}
+
+// formatOf returns the import format of a file by its extension, defaulting
+// to CSV
+func formatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return importer.FormatNDJSON
+	default:
+		return importer.FormatCSV
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/tracing"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// _principalHeader names the user authenticated by a trusted proxy in front
+// of the server, as set by e.g. oauth2-proxy. The server does not
+// authenticate requests itself.
+const _principalHeader = "X-Forwarded-User"
+
+// accessLogConfig configures the access log
+type accessLogConfig struct {
+	// level is the level requests are logged at, except server errors,
+	// which are always logged as errors
+	level zapcore.Level
+
+	// first and thereafter sample the requests logged each second: the
+	// first are logged, then every thereafter-th. Zero logs every request.
+	first, thereafter int
+
+	// proxies are trusted to report the client address and principal
+	proxies []netip.Prefix
+}
+
+// configureAccessLog reads the access log config from the environment:
+//
+//   - ACCESS_LOG_LEVEL: the level requests are logged at. Defaults to info.
+//   - ACCESS_LOG_SAMPLING: first,thereafter, e.g. 100,10 logs the first 100
+//     requests each second, then every tenth. Server errors are not sampled.
+//   - TRUSTED_PROXIES: comma-separated addresses or CIDR ranges of the proxies
+//     whose X-Forwarded-For and X-Forwarded-User headers are believed.
+func configureAccessLog() (accessLogConfig, error) {
+	config := accessLogConfig{level: zapcore.InfoLevel}
+
+	if value, ok := os.LookupEnv("ACCESS_LOG_LEVEL"); ok {
+		level, err := zapcore.ParseLevel(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid ACCESS_LOG_LEVEL: %w", err)
+		}
+
+		config.level = level
+	}
+
+	if value, ok := os.LookupEnv("ACCESS_LOG_SAMPLING"); ok {
+		first, thereafter, _ := strings.Cut(value, ",")
+
+		var errFirst, errThereafter error
+
+		config.first, errFirst = strconv.Atoi(strings.TrimSpace(first))
+		config.thereafter, errThereafter = strconv.Atoi(strings.TrimSpace(thereafter))
+
+		if errFirst != nil || errThereafter != nil || config.first < 1 || config.thereafter < 1 {
+			return config, fmt.Errorf("invalid ACCESS_LOG_SAMPLING %q; expected first,thereafter, e.g. 100,10", value)
+		}
+	}
+
+	if value := os.Getenv("TRUSTED_PROXIES"); value != "" {
+		for item := range strings.SplitSeq(value, ",") {
+			prefix, err := parsePrefix(strings.TrimSpace(item))
+			if err != nil {
+				return config, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: %w", item, err)
+			}
+
+			config.proxies = append(config.proxies, prefix)
+		}
+	}
+
+	return config, nil
+}
+
+// parsePrefix parses a CIDR range, or an address as the range holding only it
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// accessLogMiddleware logs every request served by mux once it is answered,
+// with its status, response size, latency, client address, user agent,
+// principal and request ID
+func accessLogMiddleware(next http.Handler, mux *http.ServeMux, logger *zap.Logger, config accessLogConfig) http.Handler {
+	var (
+		access  = logger.Named("access")
+		sampled = access
+	)
+
+	// Samplers count the lines logged per level and message, so the sampled
+	// logger is shared by every request
+	if config.first > 0 {
+		sampled = access.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewSamplerWithOptions(core, time.Second, config.first, config.thereafter)
+		}))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			start         = time.Now()
+			recorder      = &statusRecorder{ResponseWriter: w}
+			_, pattern    = mux.Handler(r)
+			_, route      = routeOf(r, pattern)
+			peer, trusted = peerOf(r, config.proxies)
+		)
+
+		next.ServeHTTP(recorder, r)
+
+		var (
+			status = cmp.Or(recorder.status, http.StatusOK)
+			level  = config.level
+			log    = sampled
+		)
+
+		if status >= http.StatusInternalServerError {
+			level, log = zapcore.ErrorLevel, access
+		}
+
+		entry := log.Check(level, "request")
+		if entry == nil {
+			return
+		}
+
+		fields := []zap.Field{
+			zap.String("request_id", w.Header().Get("X-Request-ID")),
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.String("route", route),
+			zap.Int("status", status),
+			zap.Int64("size", recorder.size),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("remote_ip", clientIP(r, peer, trusted, config.proxies)),
+			zap.String("user_agent", r.UserAgent()),
+		}
+
+		if principal := r.Header.Get(_principalHeader); trusted && principal != "" {
+			fields = append(fields, zap.String("principal", principal))
+		}
+
+		entry.Write(append(fields, tracing.LogFields(r.Context())...)...)
+	})
+}
+
+// peerOf returns the address of the peer that sent r and whether it is a
+// trusted proxy
+func peerOf(r *http.Request, proxies []netip.Prefix) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	addr = addr.Unmap()
+
+	return addr, isTrusted(addr, proxies)
+}
+
+// clientIP returns the address of the client that sent r. Behind trusted
+// proxies it is the last address of X-Forwarded-For that is not a trusted
+// proxy, since addresses before it may be forged by the client.
+func clientIP(r *http.Request, peer netip.Addr, trusted bool, proxies []netip.Prefix) string {
+	client := peer
+
+	if trusted {
+		forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+
+		for i := len(forwarded) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+			if err != nil {
+				break
+			}
+
+			client = addr.Unmap()
+
+			if !isTrusted(client, proxies) {
+				break
+			}
+		}
+	}
+
+	if !client.IsValid() {
+		return r.RemoteAddr
+	}
+
+	return client.String()
+}
+
+// isTrusted tells whether addr is the address of a trusted proxy
+func isTrusted(addr netip.Addr, proxies []netip.Prefix) bool {
+	for _, prefix := range proxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
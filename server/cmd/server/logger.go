@@ -8,14 +8,47 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-// configureLogger creates a new ready-to-use logger
+const (
+	_formatConsole = "console"
+	_formatJSON    = "json"
+)
+
+// configureLogger creates a new ready-to-use logger. LOG_FORMAT chooses
+// between console output for development, the default, and JSON for
+// production. LOG_LEVEL sets the lowest level logged, which defaults to debug
+// for console output and info for JSON.
 func configureLogger() (*zap.Logger, error) {
+	var (
+		format = os.Getenv("LOG_FORMAT")
+		level  = zapcore.DebugLevel
+		cfg    zapcore.EncoderConfig
+	)
+
+	switch format {
+	case "", _formatConsole:
+		// Optimize the console output for human operators
+		cfg = zap.NewDevelopmentEncoderConfig()
+	case _formatJSON:
+		cfg = zap.NewProductionEncoderConfig()
+		level = zapcore.InfoLevel
+	default:
+		return nil, fmt.Errorf("unknown LOG_FORMAT %q; expected console or json", format)
+	}
+
+	if value, ok := os.LookupEnv("LOG_LEVEL"); ok {
+		var err error
+
+		if level, err = zapcore.ParseLevel(value); err != nil {
+			return nil, fmt.Errorf("invalid LOG_LEVEL: %w", err)
+		}
+	}
+
 	// Define level-handling logic
 	highPriority := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-		return lvl >= zapcore.ErrorLevel
+		return lvl >= zapcore.ErrorLevel && lvl >= level
 	})
 	lowPriority := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-		return lvl < zapcore.ErrorLevel
+		return lvl < zapcore.ErrorLevel && lvl >= level
 	})
 
 	// High-priority output should go to standard error, and low-priority
@@ -23,13 +56,14 @@ func configureLogger() (*zap.Logger, error) {
 	consoleDebugging := zapcore.Lock(os.Stdout)
 	consoleErrors := zapcore.Lock(os.Stderr)
 
-	// Optimize the console output for human operators
-	cfg := zap.NewDevelopmentEncoderConfig()
-	consoleEncoder := zapcore.NewConsoleEncoder(cfg)
+	encoder := zapcore.NewConsoleEncoder(cfg)
+	if format == _formatJSON {
+		encoder = zapcore.NewJSONEncoder(cfg)
+	}
 
 	core := zapcore.NewTee(
-		zapcore.NewCore(consoleEncoder, consoleErrors, highPriority),
-		zapcore.NewCore(consoleEncoder, consoleDebugging, lowPriority),
+		zapcore.NewCore(encoder, consoleErrors, highPriority),
+		zapcore.NewCore(encoder, consoleDebugging, lowPriority),
 	)
 
 	return zap.New(core).Named("nestqueue"), nil
@@ -2,27 +2,43 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/digitalnest-wit/nestqueue/internal/api"
+	"github.com/digitalnest-wit/nestqueue/internal/importer"
+	"github.com/digitalnest-wit/nestqueue/internal/metrics"
+	"github.com/digitalnest-wit/nestqueue/internal/migrate"
+	"github.com/digitalnest-wit/nestqueue/internal/reports"
+	"github.com/digitalnest-wit/nestqueue/internal/rules"
 	"github.com/digitalnest-wit/nestqueue/internal/storage"
+	"github.com/digitalnest-wit/nestqueue/internal/tracing"
 	"github.com/joho/godotenv"
+	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 )
 
 var (
-	_serverPort = flag.Int("port", 3000, "the port to listen to")
+	_serverPort      = flag.Int("port", 3000, "the port to listen to")
+	_migrateTimeout  = flag.Duration("migrate-timeout", 10*time.Minute, "how long pending migrations may take to apply at startup")
+	_drainDelay      = flag.Duration("drain-delay", 5*time.Second, "how long readiness fails before the server stops, so traffic drains")
+	_shutdownTimeout = flag.Duration("shutdown-timeout", 30*time.Second, "how long in-flight requests may take to finish on shutdown")
 )
 
 func main() {
 	flag.Parse()
 
+	// The environment file may configure the logger, so it is loaded first
+	envErr := godotenv.Load()
+
 	// Configure the logger
 	logger, err := configureLogger()
 	if err != nil {
@@ -32,36 +48,158 @@ func main() {
 
 	defer syncLogger(logger)
 
-	if err := godotenv.Load(); err != nil {
-		logger.Sugar().Warn("failed to load environment file", "error", err)
+	if envErr != nil {
+		logger.Sugar().Warnw("failed to load environment file", "error", envErr)
+	}
+
+	// Trace requests, the Mongo commands they run and the webhooks they call
+	tracer, err := configureTracer(context.Background(), logger)
+	if err != nil {
+		logger.Sugar().Fatalw("failed to configure tracing", "error", err)
 	}
 
+	otel.SetTracerProvider(tracer)
+	otel.SetTextMapPropagator(tracing.Propagator)
+
 	// Configure the Mongo DB client connection
-	client, err := configureMongoClient()
+	client, err := configureMongoClient(tracing.NewCommandMonitor())
 	if err != nil {
 		logger.Sugar().Fatalw("failed to connect to mongo cluster", "error", err)
 	}
 
-	defer disconnectClient(context.Background(), client, logger)
-
 	var ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Create a ticket storage solution
-	store, err := storage.NewTicketStore(ctx, client, logger)
+	// Create a ticket storage solution, routing new tickets through queues
+	queues := storage.NewQueueStore(client, logger)
+
+	store, err := storage.NewTicketStore(ctx, client, queues, logger)
 	if err != nil {
 		logger.Sugar().Fatal(err)
 	}
 
+	// Bring stored documents up to date before serving them
+	migrateCtx, cancelMigrate := context.WithTimeout(context.Background(), *_migrateTimeout)
+	defer cancelMigrate()
+
+	migrator := migrate.NewMigrator(client, logger)
+
+	if _, err := migrator.Migrate(migrateCtx, 0); err != nil {
+		logger.Sugar().Fatalw("failed to apply migrations", "error", err)
+	}
+
+	// Measure tickets against the configured SLA targets in reports
+	slaTargets := reports.DefaultSLATargets
+
+	if value, ok := os.LookupEnv("SLA_TARGETS"); ok {
+		if slaTargets, err = reports.ParseSLATargets(value); err != nil {
+			logger.Sugar().Fatalw("failed to parse SLA targets", "error", err)
+		}
+	}
+
+	// Sign bulk confirmation tokens with a key shared by every replica, so a
+	// preview served by one can be confirmed on another
+	confirmKey := []byte(os.Getenv("BULK_CONFIRM_KEY"))
+
+	switch {
+	case len(confirmKey) == 0:
+		logger.Sugar().Warn("BULK_CONFIRM_KEY is not set; bulk confirmation tokens are only valid on this server until it restarts")
+	case len(confirmKey) < 32:
+		logger.Sugar().Fatal("BULK_CONFIRM_KEY must be at least 32 bytes long")
+	}
+
 	var (
-		ticketHandler = api.NewTicketHandler(store, logger)
-		mux           = http.NewServeMux()
+		ruleStore      = storage.NewRuleStore(client, logger)
+		fieldStore     = storage.NewFieldStore(client, logger)
+		engine         = rules.NewEngine(ruleStore, rules.NewLogNotifier(logger), logger)
+		ticketHandler  = api.NewTicketHandler(store, fieldStore, engine, confirmKey, logger)
+		queueHandler   = api.NewQueueHandler(queues, logger)
+		ruleHandler    = api.NewRuleHandler(ruleStore, store, logger)
+		tagHandler     = api.NewTagHandler(storage.NewTagStore(client, logger), logger)
+		fieldHandler   = api.NewFieldHandler(fieldStore, logger)
+		linkHandler    = api.NewLinkHandler(storage.NewLinkStore(client, logger), logger)
+		commentHandler = api.NewCommentHandler(storage.NewCommentStore(client, logger), logger)
+		importHandler  = api.NewImportHandler(importer.NewImporter(store, fieldStore, logger), logger)
+		reportHandler  = api.NewReportHandler(reports.NewReporter(store, slaTargets, logger), logger)
+		docsHandler    = api.NewDocsHandler(logger)
+		healthHandler  = api.NewHealthHandler(logger)
+		mux            = http.NewServeMux()
 	)
 
 	ticketHandler.RegisterRoutes(mux)
+	queueHandler.RegisterRoutes(mux)
+	ruleHandler.RegisterRoutes(mux)
+	tagHandler.RegisterRoutes(mux)
+	fieldHandler.RegisterRoutes(mux)
+	linkHandler.RegisterRoutes(mux)
+	commentHandler.RegisterRoutes(mux)
+	importHandler.RegisterRoutes(mux)
+	reportHandler.RegisterRoutes(mux)
+	docsHandler.RegisterRoutes(mux)
+	healthHandler.RegisterRoutes(mux)
+	mux.Handle("GET /metrics", metrics.Handler())
+
+	// Report the server ready while its dependencies are, and while the
+	// background workers performing rule actions and exporting spans run
+	healthHandler.AddCheck("mongo", func(ctx context.Context) error {
+		return client.Ping(ctx, nil)
+	})
+	healthHandler.AddCheck("migrations", func(ctx context.Context) error {
+		pending, err := migrator.Pending(ctx)
+		if err == nil && len(pending) > 0 {
+			err = fmt.Errorf("%d migrations pending", len(pending))
+		}
+
+		return err
+	})
+	healthHandler.AddCheck("rules", engine.Check)
+	healthHandler.AddCheck("tracing", tracer.Check)
+
+	// Let routes be given more or less time than their timeout policy
+	var routeTimeouts api.RouteTimeouts
+
+	if value, ok := os.LookupEnv("ROUTE_TIMEOUTS"); ok {
+		if routeTimeouts, err = api.ParseRouteTimeouts(value); err != nil {
+			logger.Sugar().Fatalw("failed to parse route timeouts", "error", err)
+		}
 
-	// Wrap mux with global-level middleware
-	handler := corsMiddleware(logRequestsMiddleware(mux, logger))
+		if err := routeTimeouts.Check(mux); err != nil {
+			logger.Sugar().Fatalw("invalid route timeouts", "error", err)
+		}
+	}
+
+	// Count open tickets whenever metrics are scraped
+	metrics.NewGaugeFunc("nestqueue_open_tickets", "Open tickets by site, status and priority.", []string{"site", "status", "priority"}, func(ctx context.Context) ([]metrics.Sample, error) {
+		counts, err := store.CountOpenTickets(ctx)
+		if err != nil {
+			logger.Sugar().Errorw("failed to count open tickets", "error", err)
+			return nil, err
+		}
+
+		samples := make([]metrics.Sample, 0, len(counts))
+
+		for _, c := range counts {
+			samples = append(samples, metrics.Sample{
+				Labels: []string{c.Site, c.Status, strconv.Itoa(c.Priority)},
+				Value:  float64(c.Count),
+			})
+		}
+
+		return samples, nil
+	})
+
+	// Log every request once answered
+	accessLog, err := configureAccessLog()
+	if err != nil {
+		logger.Sugar().Fatalw("failed to configure access log", "error", err)
+	}
+
+	// Wrap mux with global-level middleware, innermost first. Requests are
+	// traced before their context is prepared, so logs hold their trace IDs.
+	handler := accessLogMiddleware(corsMiddleware(mux), mux, logger, accessLog)
+	handler = requestContextMiddleware(handler, mux, logger, routeTimeouts)
+	handler = tracingMiddleware(handler, mux)
+	handler = metricsMiddleware(handler, mux)
 
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", *_serverPort),
@@ -71,25 +209,52 @@ func main() {
 		IdleTimeout:  120 * time.Second,
 	}
 
-	// Start the server with graceful error handling
-	runServer(server, logger)
+	// Event streams would otherwise hold up shutdown until they time out
+	server.RegisterOnShutdown(ticketHandler.CloseStreams)
+
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		logger.Sugar().Fatalw("failed to listen", "address", server.Addr, "error", err)
+	}
+
+	// Relay interrupt signals to interrupts
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+
+	// Serve until interrupted, then stop the rest of the server in order once
+	// no request can use it
+	err = serve(server, listener, interrupts, healthHandler, *_drainDelay, *_shutdownTimeout, []stopStep{
+		{name: "rule actions", timeout: 15 * time.Second, stop: engine.Wait},
+		{name: "span exporter", timeout: 5 * time.Second, stop: tracer.Shutdown},
+		{name: "Mongo DB client", timeout: 5 * time.Second, stop: client.Disconnect},
+	}, logger)
+
+	signal.Stop(interrupts)
+
+	if err != nil {
+		syncLogger(logger)
+		os.Exit(1)
+	}
+
+	logger.Sugar().Info("server stopped")
 }
 
-func runServer(server *http.Server, logger *zap.Logger) {
+// runServer serves requests on listener until the server fails or a signal
+// is received on interrupts. Once interrupted, readiness fails for drainDelay
+// so traffic drains before the server stops listening, then in-flight
+// requests are given shutdownTimeout to finish. Connections still open after
+// that, e.g. of long exports, are closed.
+func runServer(server *http.Server, listener net.Listener, interrupts <-chan os.Signal, health *api.HealthHandler, drainDelay, shutdownTimeout time.Duration, logger *zap.Logger) error {
 	var (
 		sugar         = logger.Sugar()
-		interruptChan = make(chan os.Signal, 1)
 		serverErrChan = make(chan error, 1)
 	)
 
-	sugar.Infof("server started on port %d", *_serverPort)
-
-	// Relay interrupt signals to interruptChan
-	signal.Notify(interruptChan, os.Interrupt, syscall.SIGTERM)
+	sugar.Infof("server started on %s", listener.Addr())
 
 	run := func() {
-		// ListenAndServe will always return a non-nil error
-		err := server.ListenAndServe()
+		// Serve will always return a non-nil error
+		err := server.Serve(listener)
 
 		serverErrChan <- err
 		close(serverErrChan)
@@ -99,11 +264,35 @@ func runServer(server *http.Server, logger *zap.Logger) {
 
 	// Block until either the server is interrupted or receives an error
 	select {
-	case <-interruptChan:
-		sugar.Info("server stopped")
-		os.Exit(0)
+	case <-interrupts:
 	case err := <-serverErrChan:
 		sugar.Errorf("server received an error: %s", err)
-		os.Exit(1)
+		return err
 	}
+
+	// Fail readiness first so no new requests are routed here. Interrupting
+	// again skips the wait.
+	health.Drain()
+	sugar.Infof("draining for %s", drainDelay)
+
+	select {
+	case <-time.After(drainDelay):
+	case <-interrupts:
+	}
+
+	sugar.Infof("shutting down; waiting up to %s for requests to finish", shutdownTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		sugar.Warnw("requests did not finish in time; closing their connections", "error", err)
+		server.Close()
+	}
+
+	if err := <-serverErrChan; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
 }
@@ -1,8 +1,21 @@
 package main
 
 import (
+	"cmp"
+	"crypto/rand"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/digitalnest-wit/nestqueue/internal/api"
+	"github.com/digitalnest-wit/nestqueue/internal/logging"
+	"github.com/digitalnest-wit/nestqueue/internal/metrics"
+	"github.com/digitalnest-wit/nestqueue/internal/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -11,7 +24,7 @@ func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Allow requests from any origin
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 		// Handle preflight requests
@@ -24,11 +37,160 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// logRequestsMiddleware logs each request's method and path to logger
-func logRequestsMiddleware(next http.Handler, logger *zap.Logger) http.Handler {
+var (
+	_httpRequests = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "nestqueue_http_requests_total",
+		Help: "HTTP requests served, by route pattern and status.",
+	}, []string{"method", "route", "status"})
+	_httpDuration = metrics.Factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "nestqueue_http_request_duration_seconds",
+		Help:    "How long HTTP requests take to serve, by route pattern and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// statusRecorder records the status code and size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+
+	n, err := r.ResponseWriter.Write(b)
+	r.size += int64(n)
+
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush streamed exports
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// metricsMiddleware counts the requests served by mux and measures how long
+// they take, labeled by the pattern of the route they matched so IDs in paths
+// do not split the series
+func metricsMiddleware(next http.Handler, mux *http.ServeMux) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		sugar := logger.Sugar()
-		sugar.Infow("received request", "method", r.Method, "path", r.URL.Path)
+		var (
+			start         = time.Now()
+			recorder      = &statusRecorder{ResponseWriter: w}
+			_, pattern    = mux.Handler(r)
+			method, route = routeOf(r, pattern)
+		)
+
+		next.ServeHTTP(recorder, r)
+
+		status := strconv.Itoa(cmp.Or(recorder.status, http.StatusOK))
+
+		_httpRequests.WithLabelValues(method, route, status).Inc()
+		_httpDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routeOf returns the method and path of the route pattern matched by r.
+// Requests matching no route share a single route so unknown paths cannot
+// create series without bound.
+func routeOf(r *http.Request, pattern string) (method, route string) {
+	if pattern == "" {
+		return r.Method, "unmatched"
+	}
+
+	if method, route, ok := strings.Cut(pattern, " "); ok {
+		return method, route
+	}
+
+	return r.Method, pattern
+}
+
+// tracingMiddleware records a server span for each request served by mux,
+// named by the route it matched and continuing the trace of the caller when
+// it sent a traceparent header. The span is carried by the request context,
+// so the spans of the Mongo commands run for the request are its children.
+// Only server errors are failures of the span; client errors are the
+// caller's.
+func tracingMiddleware(next http.Handler, mux *http.ServeMux) http.Handler {
+	routed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		_, route := routeOf(r, pattern)
+
+		trace.SpanFromContext(r.Context()).SetAttributes(semconv.HTTPRoute(route))
+
 		next.ServeHTTP(w, r)
 	})
+
+	return otelhttp.NewHandler(routed, "", otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		_, pattern := mux.Handler(r)
+		method, route := routeOf(r, pattern)
+
+		return method + " " + route
+	}))
+}
+
+// _maxRequestIDLength caps the length of request IDs sent by clients
+const _maxRequestIDLength = 128
+
+// requestContextMiddleware prepares the context of each request served by
+// mux: it carries a logger holding the request and trace IDs, and the timeout
+// configured for the route matched, if any. The request ID is taken from the
+// X-Request-ID header, or generated when missing, and sent back on the
+// response.
+func requestContextMiddleware(next http.Handler, mux *http.ServeMux, logger *zap.Logger, timeouts api.RouteTimeouts) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			ctx        = r.Context()
+			id         = r.Header.Get("X-Request-ID")
+			_, pattern = mux.Handler(r)
+		)
+
+		if !validRequestID(id) {
+			id = rand.Text()
+		}
+
+		w.Header().Set("X-Request-ID", id)
+
+		requestLogger := logger.With(zap.String("request_id", id)).With(tracing.LogFields(ctx)...)
+		ctx = logging.NewContext(ctx, requestLogger)
+
+		if timeout, ok := timeouts[pattern]; ok {
+			ctx = api.WithTimeout(ctx, timeout)
+
+			// Let the route outlast the server's write timeout too
+			if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+				requestLogger.Sugar().Debugw("failed to set write deadline", "error", err)
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID tells whether a request ID sent by a client can be used,
+// i.e. it is short and made of characters safe to log and echo back
+func validRequestID(id string) bool {
+	if id == "" || len(id) > _maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:/+=", c)) {
+			return false
+		}
+	}
+
+	return true
 }
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/api"
+	"go.uber.org/zap"
+)
+
+// stopStep is a part of the server stopped on shutdown
+type stopStep struct {
+	name    string
+	timeout time.Duration
+	stop    func(ctx context.Context) error
+}
+
+// serve serves requests on listener as runServer does, then stops steps in
+// order once no request can use them. Steps are stopped even when the server
+// failed.
+func serve(server *http.Server, listener net.Listener, interrupts <-chan os.Signal, health *api.HealthHandler, drainDelay, shutdownTimeout time.Duration, steps []stopStep, logger *zap.Logger) error {
+	serveErr := runServer(server, listener, interrupts, health, drainDelay, shutdownTimeout, logger)
+	stopErr := stopAll(steps, logger)
+
+	return errors.Join(serveErr, stopErr)
+}
+
+// stopAll runs steps in order, each bounded by its own timeout. A failing step
+// does not prevent the steps after it from releasing their resources; errors
+// are logged and returned joined.
+func stopAll(steps []stopStep, logger *zap.Logger) error {
+	var (
+		sugar = logger.Sugar()
+		errs  []error
+	)
+
+	for _, step := range steps {
+		ctx, cancel := context.WithTimeout(context.Background(), step.timeout)
+		start := time.Now()
+
+		err := step.stop(ctx)
+
+		cancel()
+
+		if err != nil {
+			sugar.Errorw("failed to stop "+step.name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", step.name, err))
+
+			continue
+		}
+
+		sugar.Infow("stopped "+step.name, "duration", time.Since(start))
+	}
+
+	return errors.Join(errs...)
+}
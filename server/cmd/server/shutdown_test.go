@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/api"
+	"go.uber.org/zap"
+)
+
+// shutdownEvent is something happening on shutdown
+type shutdownEvent struct {
+	name string
+	at   time.Time
+}
+
+// events records what happens on shutdown, in order
+type events struct {
+	mu   sync.Mutex
+	list []shutdownEvent
+}
+
+func (e *events) add(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.list = append(e.list, shutdownEvent{name: name, at: time.Now()})
+}
+
+// names returns the names of the events recorded so far
+func (e *events) names() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	names := make([]string, 0, len(e.list))
+
+	for _, ev := range e.list {
+		names = append(names, ev.name)
+	}
+
+	return names
+}
+
+// at returns when the event named name happened
+func (e *events) at(name string) time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, ev := range e.list {
+		if ev.name == name {
+			return ev.at
+		}
+	}
+
+	return time.Time{}
+}
+
+// recordedStep is a stop step recording when it runs and failing with err
+func recordedStep(e *events, name string, err error) stopStep {
+	return stopStep{name: name, timeout: time.Second, stop: func(ctx context.Context) error {
+		e.add(name)
+		return err
+	}}
+}
+
+// listen returns a listener on a free local port
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return listener
+}
+
+// readiness returns the status of the readiness probe served at base
+func readiness(t *testing.T, base string) int {
+	t.Helper()
+
+	resp, err := http.Get(base + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return resp.StatusCode
+}
+
+func TestServeStopsInOrder(t *testing.T) {
+	t.Parallel()
+
+	const drainDelay = 200 * time.Millisecond
+
+	var (
+		recorded   events
+		health     = api.NewHealthHandler(zap.NewNop())
+		mux        = http.NewServeMux()
+		listener   = listen(t)
+		server     = &http.Server{Handler: mux}
+		interrupts = make(chan os.Signal, 1)
+		started    = make(chan struct{})
+		release    = make(chan struct{})
+		served     = make(chan error, 1)
+		base       = "http://" + listener.Addr().String()
+	)
+
+	health.RegisterRoutes(mux)
+
+	// The request is in flight until the server shuts down, which must wait
+	// for it
+	mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+
+		recorded.add("request finished")
+
+		_, _ = io.WriteString(w, "done")
+	})
+
+	server.RegisterOnShutdown(func() {
+		recorded.add("server shutdown")
+		close(release)
+	})
+
+	steps := []stopStep{
+		recordedStep(&recorded, "rule actions", nil),
+		recordedStep(&recorded, "span exporter", nil),
+		recordedStep(&recorded, "Mongo DB client", nil),
+	}
+
+	go func() {
+		served <- serve(server, listener, interrupts, health, drainDelay, 5*time.Second, steps, zap.NewNop())
+	}()
+
+	if status := readiness(t, base); status != http.StatusOK {
+		t.Fatalf("readiness = %d before shutdown, want 200", status)
+	}
+
+	type result struct {
+		body string
+		err  error
+	}
+
+	slow := make(chan result, 1)
+
+	go func() {
+		resp, err := http.Get(base + "/slow")
+		if err != nil {
+			slow <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		slow <- result{body: string(body), err: err}
+	}()
+
+	<-started
+
+	interruptedAt := time.Now()
+	interrupts <- syscall.SIGTERM
+
+	// Readiness fails while the server still serves, during the drain delay
+	for readiness(t, base) != http.StatusServiceUnavailable {
+		if time.Since(interruptedAt) > drainDelay {
+			t.Fatal("readiness did not fail during the drain delay")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	recorded.add("readiness failing")
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("serve = %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("serve did not return")
+	}
+
+	if r := <-slow; r.err != nil || r.body != "done" {
+		t.Errorf("in-flight request = %q, %v; want it to finish", r.body, r.err)
+	}
+
+	want := []string{"readiness failing", "server shutdown", "request finished", "rule actions", "span exporter", "Mongo DB client"}
+
+	if got := recorded.names(); !slices.Equal(got, want) {
+		t.Errorf("shutdown order = %q, want %q", got, want)
+	}
+
+	if waited := recorded.at("server shutdown").Sub(interruptedAt); waited < drainDelay {
+		t.Errorf("server shut down %v after the interrupt, before the drain delay of %v", waited, drainDelay)
+	}
+}
+
+func TestServeReportsStepErrors(t *testing.T) {
+	t.Parallel()
+
+	var (
+		recorded   events
+		errWait    = errors.New("rule actions still running")
+		errDisconn = errors.New("connection reset")
+		interrupts = make(chan os.Signal, 1)
+		steps      = []stopStep{
+			recordedStep(&recorded, "rule actions", errWait),
+			recordedStep(&recorded, "span exporter", nil),
+			recordedStep(&recorded, "Mongo DB client", errDisconn),
+		}
+	)
+
+	interrupts <- syscall.SIGTERM
+
+	err := serve(&http.Server{Handler: http.NewServeMux()}, listen(t), interrupts, api.NewHealthHandler(zap.NewNop()), 0, time.Second, steps, zap.NewNop())
+
+	if !errors.Is(err, errWait) || !errors.Is(err, errDisconn) {
+		t.Errorf("serve = %v, want both step errors", err)
+	}
+
+	want := []string{"rule actions", "span exporter", "Mongo DB client"}
+
+	if got := recorded.names(); !slices.Equal(got, want) {
+		t.Errorf("steps run = %q, want %q", got, want)
+	}
+}
+
+func TestServeStopsStepsWhenServerFails(t *testing.T) {
+	t.Parallel()
+
+	var (
+		recorded events
+		listener = listen(t)
+		steps    = []stopStep{
+			recordedStep(&recorded, "rule actions", nil),
+			recordedStep(&recorded, "Mongo DB client", nil),
+		}
+	)
+
+	// Serving on a closed listener fails at once
+	listener.Close()
+
+	err := serve(&http.Server{Handler: http.NewServeMux()}, listener, make(chan os.Signal), api.NewHealthHandler(zap.NewNop()), 0, time.Second, steps, zap.NewNop())
+
+	if err == nil || errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("serve = %v, want the error serving", err)
+	}
+
+	if got := recorded.names(); !slices.Equal(got, []string{"rule actions", "Mongo DB client"}) {
+		t.Errorf("steps run = %q, want every step", got)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/digitalnest-wit/nestqueue/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.uber.org/zap"
+)
+
+// configureTracer creates a tracer provider exporting spans as configured by
+// the standard OpenTelemetry environment variables:
+//
+//   - OTEL_TRACES_EXPORTER: otlp, console or none. Defaults to otlp when an
+//     OTLP endpoint is set and none otherwise.
+//   - OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT: where
+//     the collector listens. Defaults to http://localhost:4318. The exporter
+//     reads the other OTEL_EXPORTER_OTLP_* variables too, e.g. headers.
+//   - OTEL_EXPORTER_OTLP_PROTOCOL: only http/protobuf is supported.
+//   - OTEL_SERVICE_NAME: the name of the service. Defaults to nestqueue.
+//   - OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG: how new traces are
+//     sampled, e.g. parentbased_traceidratio and 0.1. Defaults to every trace.
+//
+// Errors exporting spans are logged to logger.
+func configureTracer(ctx context.Context, logger *zap.Logger) (*tracing.Provider, error) {
+	var (
+		config = tracing.Config{
+			ServiceName: "nestqueue",
+		}
+		endpoint         = cmp.Or(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"), os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"))
+		exporter, chosen = os.LookupEnv("OTEL_TRACES_EXPORTER")
+	)
+
+	if !chosen {
+		exporter = "none"
+
+		if endpoint != "" {
+			exporter = "otlp"
+		}
+	}
+
+	switch exporter {
+	case "otlp":
+		protocol := cmp.Or(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"), os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"))
+		if protocol != "" && protocol != "http/protobuf" {
+			return nil, fmt.Errorf("unsupported OTLP protocol %q; only http/protobuf is supported", protocol)
+		}
+
+		otlp, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Exporter = otlp
+	case "console", "stdout":
+		stdout, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, err
+		}
+
+		config.Exporter = stdout
+	case "none", "":
+	default:
+		return nil, fmt.Errorf("unknown traces exporter %q", exporter)
+	}
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Sugar().Warnw("failed to export spans", "error", err)
+	}))
+
+	return tracing.NewProvider(ctx, config)
+}
@@ -0,0 +1,376 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/logging"
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"github.com/digitalnest-wit/nestqueue/internal/storage"
+)
+
+// Bulk actions
+const (
+	_bulkUpdate = "update"
+	_bulkDelete = "delete"
+	_bulkAssign = "assign"
+)
+
+var (
+	// _bulkLimit caps the tickets a bulk operation affects
+	_bulkLimit = 500
+
+	// _bulkTimeoutPolicy bounds a bulk operation, which writes and runs the
+	// update rules for every ticket it affects
+	_bulkTimeoutPolicy = 60 * time.Second
+
+	// _confirmExpiry is how long a bulk confirmation token can be used for
+	_confirmExpiry = 5 * time.Minute
+
+	errConfirmMismatch = errors.New("confirmation token is invalid or has expired, or the filter now matches different tickets")
+)
+
+// bulkRequest is a bulk operation on the tickets in IDs or the tickets
+// matching Filter, which takes the query parameters of the listing endpoint,
+// e.g. "status=Open&site=HQ"
+type bulkRequest struct {
+	Action     string         `json:"action"`
+	IDs        []string       `json:"ids"`
+	Filter     string         `json:"filter"`
+	Updates    map[string]any `json:"updates"`
+	AssignedTo *string        `json:"assignedTo"`
+	Confirm    string         `json:"confirm"`
+}
+
+// handleBulkTickets handles updating, assigning or deleting many tickets at
+// once. Operations on a filter are previewed first: the response lists the
+// matching tickets and a confirmation token, and the operation only runs once
+// the same request is repeated with the token while the filter still matches
+// the same tickets.
+func (h *TicketHandler) handleBulkTickets(w http.ResponseWriter, r *http.Request) {
+	var (
+		body  bulkRequest
+		sugar = logging.FromContext(r.Context(), h.logger).Sugar()
+	)
+
+	if err := decodeInto(r.Body, &body); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	filter, err := validateBulkRequest(&body)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	ctx, cancel := requestContext(r, _bulkTimeoutPolicy)
+	defer cancel()
+
+	ids := body.IDs
+
+	if body.Filter != "" {
+		// One more than the limit is read to tell when it is exceeded
+		filter.Limit = _bulkLimit + 1
+
+		ids, err = h.store.FindTicketIDs(ctx, filter)
+		if err != nil {
+			writeError(h.logger, w, r, err)
+
+			return
+		}
+
+		if len(ids) > _bulkLimit {
+			e := fmt.Errorf("filter matches more than %d tickets", _bulkLimit)
+			writeError(h.logger, w, r, badRequest(e))
+
+			return
+		}
+
+		if body.Confirm == "" {
+			h.writeBulkPreview(w, body, ids)
+			return
+		}
+
+		if !h.checkConfirmation(body, ids) {
+			writeError(h.logger, w, r, errConfirmMismatch)
+
+			return
+		}
+	}
+
+	var results []storage.BulkResult
+
+	switch body.Action {
+	case _bulkDelete:
+		results, err = h.store.BulkDeleteTickets(ctx, ids)
+	case _bulkAssign:
+		results, err = h.bulkUpdate(ctx, ids, map[string]any{"assignedTo": *body.AssignedTo})
+	default:
+		results, err = h.bulkUpdate(ctx, ids, body.Updates)
+	}
+
+	if err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	summary := make(map[string]int)
+
+	for _, result := range results {
+		summary[result.Result]++
+	}
+
+	sugar.Infow("bulk operation", "action", body.Action, "count", len(results), "summary", summary)
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := map[string]any{
+		"action":  body.Action,
+		"count":   len(results),
+		"summary": summary,
+		"results": results,
+	}
+
+	encodeJSON(h, w, response)
+}
+
+// bulkUpdate applies updates to the tickets identified by ids. Custom fields
+// are checked against the schema of each ticket's category, so a ticket they
+// do not fit fails on its own, and the update rules run for every ticket
+// updated. Tickets, schemas and rules are each read once for all tickets.
+func (h *TicketHandler) bulkUpdate(ctx context.Context, ids []string, updates map[string]any) ([]storage.BulkResult, error) {
+	var (
+		results = make([]storage.BulkResult, len(ids))
+		pending []storage.BulkUpdate
+		indexes []int
+		current map[string]*models.Ticket
+		schemas = make(map[string]*models.FieldSchema)
+		sugar   = logging.FromContext(ctx, h.logger).Sugar()
+		err     error
+	)
+
+	// Tickets are only read up front when their custom fields are checked
+	if changesCustomFields(updates) {
+		if current, err = h.store.FindTicketsByID(ctx, ids); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, id := range ids {
+		ticketUpdates := maps.Clone(updates)
+
+		if current != nil {
+			ticket, ok := current[id]
+			if !ok {
+				results[i] = storage.BulkResult{ID: id, Result: storage.BulkNotFound}
+				continue
+			}
+
+			if fields, ok := ticketUpdates["customFields"]; ok && fields == nil {
+				clearCustomFields(ticket, ticketUpdates)
+			}
+
+			err = h.checkCustomFieldUpdates(ctx, ticket, ticketUpdates, schemas)
+		}
+
+		switch {
+		case err == nil:
+			pending = append(pending, storage.BulkUpdate{ID: id, Updates: ticketUpdates})
+			indexes = append(indexes, i)
+		case errors.Is(err, errInvalidCustomFields):
+			results[i] = storage.BulkResult{ID: id, Result: storage.BulkFailed, Error: err.Error()}
+		default:
+			return nil, err
+		}
+	}
+
+	updated, err := h.store.BulkUpdateTickets(ctx, pending)
+	if err != nil {
+		return nil, err
+	}
+
+	var updatedIds []string
+
+	for j, result := range updated {
+		results[indexes[j]] = result
+
+		if result.Result == storage.BulkUpdated {
+			updatedIds = append(updatedIds, result.ID)
+		}
+	}
+
+	if len(updatedIds) == 0 {
+		return results, nil
+	}
+
+	tickets, err := h.store.FindTicketsByID(ctx, updatedIds)
+	if err != nil {
+		sugar.Errorw("failed to apply update rules", "count", len(updatedIds), "error", err)
+		return results, nil
+	}
+
+	enabled, err := h.rules.EnabledRules(ctx)
+	if err != nil {
+		sugar.Errorw("failed to apply update rules", "count", len(updatedIds), "error", err)
+		return results, nil
+	}
+
+	for _, id := range updatedIds {
+		ticket, ok := tickets[id]
+		if !ok {
+			continue
+		}
+
+		result, err := h.rules.EvaluateRules(enabled, models.EventUpdate, *ticket)
+		if err != nil {
+			sugar.Errorw("failed to evaluate rules", "ticket.id", id, "error", err)
+			continue
+		}
+
+		h.applyRuleResult(ctx, result, ticket)
+	}
+
+	return results, nil
+}
+
+// writeBulkPreview responds with the tickets a filter-based operation would
+// affect and the token confirming it
+func (h *TicketHandler) writeBulkPreview(w http.ResponseWriter, body bulkRequest, ids []string) {
+	var expires = time.Now().Add(_confirmExpiry)
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := map[string]any{
+		"action":    body.Action,
+		"count":     len(ids),
+		"ids":       ids,
+		"confirm":   h.confirmationToken(body, ids, expires),
+		"expiresAt": expires.UTC().Truncate(time.Second),
+	}
+
+	encodeJSON(h, w, response)
+}
+
+// confirmationToken signs the operation in body on the tickets in ids until
+// expires. The token is the expiry time followed by the signature.
+func (h *TicketHandler) confirmationToken(body bulkRequest, ids []string, expires time.Time) string {
+	payload, _ := json.Marshal(map[string]any{
+		"action":     body.Action,
+		"filter":     body.Filter,
+		"updates":    body.Updates,
+		"assignedTo": body.AssignedTo,
+		"ids":        ids,
+		"expires":    expires.Unix(),
+	})
+
+	mac := hmac.New(sha256.New, h.confirmKey)
+	mac.Write(payload)
+
+	return strconv.FormatInt(expires.Unix(), 10) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// checkConfirmation reports whether the token in body confirms the operation
+// in body on the tickets in ids and has not expired
+func (h *TicketHandler) checkConfirmation(body bulkRequest, ids []string) bool {
+	unix, _, ok := strings.Cut(body.Confirm, ".")
+	if !ok {
+		return false
+	}
+
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	expires := time.Unix(seconds, 0)
+	if time.Now().After(expires) {
+		return false
+	}
+
+	expected := h.confirmationToken(body, ids, expires)
+
+	return hmac.Equal([]byte(expected), []byte(body.Confirm))
+}
+
+// validateBulkRequest checks that body describes a bulk operation, removing
+// repeated IDs, and returns its filter when it has one. Updates are checked
+// as in a patch and replaced with the updates they make, so invalid fields
+// are answered with 422 Unprocessable Entity and other problems with 400 Bad
+// Request.
+func validateBulkRequest(body *bulkRequest) (storage.TicketFilter, error) {
+	var filter storage.TicketFilter
+
+	switch body.Action {
+	case _bulkUpdate:
+		if len(body.Updates) == 0 {
+			return filter, badRequest(errors.New("updates are required"))
+		}
+
+		updates, err := ticketUpdates(body.Updates, false)
+		if err != nil {
+			return filter, err
+		}
+
+		body.Updates = updates
+	case _bulkAssign:
+		if body.AssignedTo == nil {
+			return filter, badRequest(errors.New("assignedTo is required"))
+		}
+	case _bulkDelete:
+	default:
+		return filter, badRequest(fmt.Errorf("unknown action %q", body.Action))
+	}
+
+	if (len(body.IDs) == 0) == (body.Filter == "") {
+		return filter, badRequest(errors.New("either ids or filter is required"))
+	}
+
+	if body.Filter == "" {
+		seen := make(map[string]bool, len(body.IDs))
+		body.IDs = slices.DeleteFunc(body.IDs, func(id string) bool {
+			repeated := seen[id]
+			seen[id] = true
+
+			return repeated
+		})
+
+		if len(body.IDs) > _bulkLimit {
+			return filter, badRequest(fmt.Errorf("at most %d tickets can be changed at once", _bulkLimit))
+		}
+
+		return filter, nil
+	}
+
+	values, err := url.ParseQuery(body.Filter)
+	if err != nil {
+		return filter, badRequest(fmt.Errorf("invalid filter: %w", err))
+	}
+
+	filter = parseTicketFilter(values)
+
+	if err := filter.Validate(); err != nil {
+		return filter, badRequest(err)
+	}
+
+	if filter.IsZero() {
+		return filter, badRequest(errors.New("filter matches every ticket"))
+	}
+
+	return filter, nil
+}
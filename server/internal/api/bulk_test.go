@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestValidateBulkRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    bulkRequest
+		status  int
+		updates map[string]any
+	}{
+		{
+			name:    "update",
+			body:    bulkRequest{Action: _bulkUpdate, IDs: []string{"a"}, Updates: map[string]any{"status": "Closed", "site": nil}},
+			updates: map[string]any{"status": "Closed", "site": ""},
+		},
+		{
+			name:   "unknown status",
+			body:   bulkRequest{Action: _bulkUpdate, IDs: []string{"a"}, Updates: map[string]any{"status": "Bogus"}},
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "priority as text",
+			body:   bulkRequest{Action: _bulkUpdate, IDs: []string{"a"}, Updates: map[string]any{"priority": "high"}},
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "empty title",
+			body:   bulkRequest{Action: _bulkUpdate, IDs: []string{"a"}, Updates: map[string]any{"title": ""}},
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "tags as text",
+			body:   bulkRequest{Action: _bulkUpdate, IDs: []string{"a"}, Updates: map[string]any{"tags": "x"}},
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "read-only field",
+			body:   bulkRequest{Action: _bulkUpdate, IDs: []string{"a"}, Updates: map[string]any{"createdOn": "2024-01-01T00:00:00Z"}},
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "unknown field",
+			body:   bulkRequest{Action: _bulkUpdate, IDs: []string{"a"}, Updates: map[string]any{"owner": "ana"}},
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "no updates",
+			body:   bulkRequest{Action: _bulkUpdate, IDs: []string{"a"}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "no tickets",
+			body:   bulkRequest{Action: _bulkDelete},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "filter matching every ticket",
+			body:   bulkRequest{Action: _bulkDelete, Filter: "limit=10"},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validateBulkRequest(&tt.body)
+
+			if tt.status == 0 {
+				if err != nil {
+					t.Fatalf("validateBulkRequest = %v", err)
+				}
+
+				for field, want := range tt.updates {
+					if got := tt.body.Updates[field]; got != want {
+						t.Errorf("updates[%q] = %#v, want %#v", field, got, want)
+					}
+				}
+
+				return
+			}
+
+			if err == nil {
+				t.Fatal("validateBulkRequest accepted the request")
+			}
+
+			if problem := problemFor(err); problem.Status != tt.status {
+				t.Errorf("status = %d, want %d: %v", problem.Status, tt.status, err)
+			}
+		})
+	}
+}
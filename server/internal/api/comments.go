@@ -0,0 +1,115 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"github.com/digitalnest-wit/nestqueue/internal/storage"
+	"go.uber.org/zap"
+)
+
+// _maxCommentLength caps the length of a comment, in bytes
+var _maxCommentLength = 16 << 10
+
+// CommentHandler handles ticket comment API requests
+type CommentHandler struct {
+	store  *storage.CommentStore
+	logger *zap.Logger
+}
+
+// NewCommentHandler creates a new comment handler
+func NewCommentHandler(store *storage.CommentStore, logger *zap.Logger) *CommentHandler {
+	return &CommentHandler{
+		store:  store,
+		logger: logger.Named("handler.comments"),
+	}
+}
+
+// Logger simply returns this handler's logger. This method is implemented to
+// satisfy logHandler.
+func (h *CommentHandler) Logger() *zap.Logger {
+	return h.logger
+}
+
+// RegisterRoutes registers the comment API routes
+func (h *CommentHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/tickets/{id}/comments", h.handleGetComments)
+	mux.HandleFunc("POST /api/v1/tickets/{id}/comments", h.handleAddComment)
+}
+
+// handleGetComments handles listing the comments on a ticket, oldest first
+func (h *CommentHandler) handleGetComments(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	results, err := h.store.FindComments(ctx, r.PathValue("id"))
+	if err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := map[string]any{
+		"count":    len(results),
+		"comments": results,
+	}
+
+	encodeJSON(h, w, response)
+}
+
+// handleAddComment handles adding a comment to a ticket
+func (h *CommentHandler) handleAddComment(w http.ResponseWriter, r *http.Request) {
+	var (
+		body struct {
+			Author string `json:"author"`
+			Body   string `json:"body"`
+		}
+	)
+
+	if err := decodeInto(r.Body, &body); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	comment := models.Comment{
+		Ticket: r.PathValue("id"),
+		Author: strings.TrimSpace(body.Author),
+		Body:   strings.TrimSpace(body.Body),
+	}
+
+	if err := validateComment(comment); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	added, err := h.store.AddComment(ctx, comment)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(h, w, http.StatusCreated, added)
+}
+
+// validateComment checks that comment says something and who said it
+func validateComment(comment models.Comment) error {
+	switch {
+	case comment.Author == "":
+		return errors.New("comment author is required")
+	case comment.Body == "":
+		return errors.New("comment body is required")
+	case len(comment.Body) > _maxCommentLength:
+		return errors.New("comment body is too long")
+	}
+
+	return nil
+}
@@ -29,14 +29,30 @@ func decodeInto(r io.ReadCloser, val any) error {
 	return nil
 }
 
-// encodeJSON tries to encode val into w using json.Encode.
+// encodeJSON tries to encode val into w using json.Marshal, answering with
+// 200 OK.
 //
-// Errors are written to w and a 500 is sent.
+// Errors are logged and answered with a 500 problem.
 func encodeJSON(h logHandler, w http.ResponseWriter, val any) {
-	if err := json.NewEncoder(w).Encode(val); err != nil {
+	writeJSON(h, w, http.StatusOK, val)
+}
+
+// writeJSON answers with status and val encoded as JSON. val is encoded before
+// anything is written, so a value that cannot be encoded is answered with a
+// 500 problem instead; once the response has started, failing to write it
+// can only be logged.
+func writeJSON(h logHandler, w http.ResponseWriter, status int, val any) {
+	body, err := json.Marshal(val)
+	if err != nil {
 		h.Logger().Sugar().Error(err)
-		http.Error(w, errInternal.Error(), http.StatusInternalServerError)
+		writeProblem(h.Logger(), w, nil, newProblem(http.StatusInternalServerError, errInternal.Error()))
 
 		return
 	}
+
+	w.WriteHeader(status)
+
+	if _, err := w.Write(append(body, '\n')); err != nil {
+		h.Logger().Sugar().Debugw("failed to write response", "error", err)
+	}
 }
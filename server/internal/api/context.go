@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RouteTimeouts overrides the timeout policy of routes by their pattern, e.g.
+// "GET /api/v1/reports/sla"
+type RouteTimeouts map[string]time.Duration
+
+// ParseRouteTimeouts parses timeouts written as comma-separated
+// pattern=duration pairs, e.g. "GET /api/v1/reports/sla=1m,POST
+// /api/v1/tickets/import=30m"
+func ParseRouteTimeouts(s string) (RouteTimeouts, error) {
+	timeouts := make(RouteTimeouts)
+
+	for _, pair := range strings.Split(s, ",") {
+		pattern, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid route timeout %q", pair)
+		}
+
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid route timeout duration %q", value)
+		}
+
+		timeouts[strings.TrimSpace(pattern)] = timeout
+	}
+
+	return timeouts, nil
+}
+
+// Check checks that every route timeout is for a route of mux, so a mistyped
+// pattern cannot be ignored
+func (t RouteTimeouts) Check(mux *http.ServeMux) error {
+	var errs []error
+
+	for pattern := range t {
+		method, path, ok := strings.Cut(pattern, " ")
+		if !ok {
+			errs = append(errs, fmt.Errorf("route timeout %q has no method", pattern))
+			continue
+		}
+
+		// Path parameters are matched by their own names, e.g. {id}
+		req := &http.Request{Method: method, URL: &url.URL{Path: path}}
+
+		if _, routed := mux.Handler(req); routed != pattern {
+			errs = append(errs, fmt.Errorf("route timeout %q is for a route that does not exist", pattern))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+type timeoutKey struct{}
+
+// WithTimeout returns a copy of ctx overriding the timeout policy of the
+// route serving the request with timeout
+func WithTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, timeout)
+}
+
+// requestContext returns the context of the work done for r, canceled when
+// the client goes away or after the route's timeout: policy, unless
+// overridden with WithTimeout
+func requestContext(r *http.Request, policy time.Duration) (context.Context, context.CancelFunc) {
+	ctx := r.Context()
+
+	if timeout, ok := ctx.Value(timeoutKey{}).(time.Duration); ok {
+		policy = timeout
+	}
+
+	return context.WithTimeout(ctx, policy)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// _docsPolicy is the content security policy of the docs page. Its scripts
+// and styles are served from openapi/docs, so the page works offline; Swagger
+// UI still sets inline styles and draws inline images.
+const _docsPolicy = "default-src 'self'; style-src 'self' 'unsafe-inline'; img-src 'self' data:"
+
+// _docs holds the OpenAPI document describing every route and the page
+// browsing it, along with the Swagger UI assets it loads (swagger-ui-dist
+// 5.18.2, under the Apache License 2.0)
+//
+//go:embed openapi
+var _docs embed.FS
+
+// DocsHandler handles API documentation requests
+type DocsHandler struct {
+	logger *zap.Logger
+}
+
+// NewDocsHandler creates a new docs handler
+func NewDocsHandler(logger *zap.Logger) *DocsHandler {
+	return &DocsHandler{
+		logger: logger.Named("handler.docs"),
+	}
+}
+
+// Logger simply returns this handler's logger. This method is implemented to
+// satisfy logHandler.
+func (h *DocsHandler) Logger() *zap.Logger {
+	return h.logger
+}
+
+// RegisterRoutes registers the docs API routes
+func (h *DocsHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/openapi.json", h.handleGetOpenAPI)
+	mux.HandleFunc("GET /api/v1/docs", h.handleGetDocs)
+	mux.HandleFunc("GET /api/v1/docs/{file}", h.handleGetDocsAsset)
+}
+
+// handleGetOpenAPI handles retrieving the OpenAPI document
+func (h *DocsHandler) handleGetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	http.ServeFileFS(w, r, _docs, "openapi/openapi.json")
+}
+
+// handleGetDocs handles retrieving the page browsing the OpenAPI document
+func (h *DocsHandler) handleGetDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Security-Policy", _docsPolicy)
+	http.ServeFileFS(w, r, _docs, "openapi/index.html")
+}
+
+// handleGetDocsAsset handles retrieving a script or style loaded by the docs
+// page
+func (h *DocsHandler) handleGetDocsAsset(w http.ResponseWriter, r *http.Request) {
+	// Path values never hold a slash, so only files in openapi/docs are served
+	name := "openapi/docs/" + r.PathValue("file")
+
+	if _, err := fs.Stat(_docs, name); err != nil {
+		writeProblem(h.logger, w, r, newProblem(http.StatusNotFound, fmt.Sprintf("no docs asset named %q", r.PathValue("file"))))
+
+		return
+	}
+
+	http.ServeFileFS(w, r, _docs, name)
+}
@@ -0,0 +1,355 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"go.uber.org/zap"
+)
+
+// _specURL is the location the OpenAPI document is loaded under, so schemas
+// can be compiled from JSON pointers into it
+const _specURL = "file:///openapi.json"
+
+// openAPIDoc is the part of the OpenAPI document the tests read
+type openAPIDoc struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Responses map[string]openAPIResponse `json:"responses"`
+	} `json:"components"`
+}
+
+// openAPIOperation is an operation of the OpenAPI document
+type openAPIOperation struct {
+	Responses map[string]openAPIResponse `json:"responses"`
+}
+
+// openAPIResponse is a response of an operation, or a reference to one in
+// components
+type openAPIResponse struct {
+	Ref     string                     `json:"$ref"`
+	Content map[string]json.RawMessage `json:"content"`
+}
+
+// loadSpec reads the embedded OpenAPI document
+func loadSpec(t *testing.T) openAPIDoc {
+	t.Helper()
+
+	data, err := _docs.ReadFile("openapi/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc openAPIDoc
+
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+
+	return doc
+}
+
+// newTestMux registers the routes of every handler. Handlers are given no
+// stores, so only requests answered before reaching the database can be
+// served.
+func newTestMux() *http.ServeMux {
+	var (
+		logger = zap.NewNop()
+		mux    = http.NewServeMux()
+		health = NewHealthHandler(logger)
+	)
+
+	health.AddCheck("mongo", func(ctx context.Context) error {
+		return errors.New("no database in tests")
+	})
+
+	NewTicketHandler(nil, nil, nil, nil, logger).RegisterRoutes(mux)
+	NewQueueHandler(nil, logger).RegisterRoutes(mux)
+	NewRuleHandler(nil, nil, logger).RegisterRoutes(mux)
+	NewTagHandler(nil, logger).RegisterRoutes(mux)
+	NewFieldHandler(nil, logger).RegisterRoutes(mux)
+	NewLinkHandler(nil, logger).RegisterRoutes(mux)
+	NewCommentHandler(nil, logger).RegisterRoutes(mux)
+	NewImportHandler(nil, logger).RegisterRoutes(mux)
+	NewReportHandler(nil, logger).RegisterRoutes(mux)
+	NewDocsHandler(logger).RegisterRoutes(mux)
+	health.RegisterRoutes(mux)
+
+	return mux
+}
+
+// servedRoutes returns the patterns registered by the handlers of this
+// package, read from the string literals passed to Handle and HandleFunc
+func servedRoutes(t *testing.T) []string {
+	t.Helper()
+
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		fset   = token.NewFileSet()
+		routes []string
+	)
+
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || (sel.Sel.Name != "Handle" && sel.Sel.Name != "HandleFunc") {
+				return true
+			}
+
+			if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				pattern, err := strconv.Unquote(lit.Value)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				routes = append(routes, pattern)
+			}
+
+			return true
+		})
+	}
+
+	return routes
+}
+
+func TestDocumentedRoutesAreServed(t *testing.T) {
+	var (
+		doc = loadSpec(t)
+		mux = newTestMux()
+	)
+
+	for path, item := range doc.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+
+			// Path parameters are matched by their own names, e.g. {id}
+			route := strings.ToUpper(method) + " " + path
+			req := &http.Request{Method: strings.ToUpper(method), URL: &url.URL{Path: path}}
+
+			if _, pattern := mux.Handler(req); pattern != route {
+				t.Errorf("%s is documented but routed to %q", route, pattern)
+			}
+		}
+	}
+}
+
+func TestServedRoutesAreDocumented(t *testing.T) {
+	var (
+		doc    = loadSpec(t)
+		mux    = newTestMux()
+		routes = servedRoutes(t)
+	)
+
+	if len(routes) == 0 {
+		t.Fatal("found no routes")
+	}
+
+	for _, route := range routes {
+		method, path, ok := strings.Cut(route, " ")
+		if !ok {
+			t.Errorf("route %q has no method", route)
+			continue
+		}
+
+		req := &http.Request{Method: method, URL: &url.URL{Path: path}}
+
+		if _, pattern := mux.Handler(req); pattern != route {
+			t.Errorf("%s is not registered by newTestMux", route)
+		}
+
+		if _, ok := doc.Paths[path][strings.ToLower(method)]; !ok {
+			t.Errorf("%s is served but not documented", route)
+		}
+	}
+}
+
+func TestResponsesMatchSchemas(t *testing.T) {
+	var (
+		doc     = loadSpec(t)
+		mux     = newTestMux()
+		schemas = newSchemaCompiler(t)
+		id      = "0123456789abcdef01234567"
+	)
+
+	tests := []struct {
+		method      string
+		target      string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"GET", "/api/v1/openapi.json", "", "", http.StatusOK},
+		{"GET", "/api/v1/docs", "", "", http.StatusOK},
+		{"GET", "/api/v1/docs/swagger-ui-bundle.js", "", "", http.StatusOK},
+		{"GET", "/api/v1/docs/swagger-ui.css", "", "", http.StatusOK},
+		{"GET", "/api/v1/docs/index.js", "", "", http.StatusOK},
+		{"GET", "/api/v1/docs/missing.js", "", "", http.StatusNotFound},
+		{"GET", "/healthz", "", "", http.StatusOK},
+		{"GET", "/readyz", "", "", http.StatusServiceUnavailable},
+		{"GET", "/api/v1/tickets?limit=none", "", "", http.StatusBadRequest},
+		{"POST", "/api/v1/tickets", "application/json", "{", http.StatusBadRequest},
+		{"GET", "/api/v1/tickets/export?format=xml", "", "", http.StatusBadRequest},
+		{"POST", "/api/v1/tickets/import?format=xml", "text/csv", "", http.StatusBadRequest},
+		{"POST", "/api/v1/tickets/duplicates", "application/json", "{", http.StatusBadRequest},
+		{"POST", "/api/v1/tickets/bulk", "application/json", "{", http.StatusBadRequest},
+		{"POST", "/api/v1/tickets/bulk", "application/json", `{"action":"update","ids":["a"],"updates":{"status":"Bogus"}}`, http.StatusUnprocessableEntity},
+		{"PUT", "/api/v1/tickets/" + id, "application/json", "{", http.StatusBadRequest},
+		{"PATCH", "/api/v1/tickets/" + id, "text/plain", "{}", http.StatusUnsupportedMediaType},
+		{"POST", "/api/v1/tickets/" + id + "/merge", "application/json", "{}", http.StatusBadRequest},
+		{"POST", "/api/v1/tickets/" + id + "/links", "application/json", "{", http.StatusBadRequest},
+		{"POST", "/api/v1/tickets/" + id + "/comments", "application/json", `{"author":"ana@example.com","body":" "}`, http.StatusBadRequest},
+		{"POST", "/api/v1/tickets/" + id + "/comments", "application/json", `{"body":"Replaced the toner"}`, http.StatusBadRequest},
+		{"POST", "/api/v1/queues", "application/json", "{", http.StatusBadRequest},
+		{"PUT", "/api/v1/queues/" + id, "application/json", `{"name":""}`, http.StatusBadRequest},
+		{"PUT", "/api/v1/queues/" + id, "application/json", `{"strategy":"random"}`, http.StatusBadRequest},
+		{"PUT", "/api/v1/queues/" + id, "application/json", `{"members":[]}`, http.StatusBadRequest},
+		{"PUT", "/api/v1/queues/" + id, "application/json", `{"sites":"HQ"}`, http.StatusBadRequest},
+		{"POST", "/api/v1/rules", "application/json", "{", http.StatusBadRequest},
+		{"POST", "/api/v1/rules/dry-run", "application/json", "{", http.StatusBadRequest},
+		{"PUT", "/api/v1/tags/urgent", "application/json", "{", http.StatusBadRequest},
+		{"PUT", "/api/v1/fields/Hardware", "application/json", "{", http.StatusBadRequest},
+		{"GET", "/api/v1/reports/backlog?priority=0", "", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+
+			_, pattern := mux.Handler(req)
+			_, path, _ := strings.Cut(pattern, " ")
+
+			var op openAPIOperation
+
+			if err := json.Unmarshal(doc.Paths[path][strings.ToLower(tt.method)], &op); err != nil {
+				t.Fatalf("%s is not documented: %v", pattern, err)
+			}
+
+			pointer := "/paths/" + escapePointer(path) + "/" + strings.ToLower(tt.method) + "/responses/" + strconv.Itoa(rec.Code)
+
+			response, ok := op.Responses[strconv.Itoa(rec.Code)]
+			if !ok {
+				t.Fatalf("%s does not document status %d", pattern, rec.Code)
+			}
+
+			if response.Ref != "" {
+				pointer = strings.TrimPrefix(response.Ref, "#")
+				response = doc.Components.Responses[pointer[strings.LastIndex(pointer, "/")+1:]]
+			}
+
+			mediaType, _, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+			if err != nil {
+				t.Fatalf("invalid Content-Type %q: %v", rec.Header().Get("Content-Type"), err)
+			}
+
+			if _, ok := response.Content[mediaType]; !ok {
+				t.Fatalf("%s does not document %s responses with status %d", pattern, mediaType, rec.Code)
+			}
+
+			if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+				return
+			}
+
+			body, err := jsonschema.UnmarshalJSON(bytes.NewReader(rec.Body.Bytes()))
+			if err != nil {
+				t.Fatalf("invalid JSON body: %v", err)
+			}
+
+			schema := schemas.compile(t, pointer+"/content/"+escapePointer(mediaType)+"/schema")
+
+			if err := schema.Validate(body); err != nil {
+				t.Errorf("body does not match the documented schema: %v", err)
+			}
+		})
+	}
+}
+
+// schemaCompiler compiles the schemas of the OpenAPI document
+type schemaCompiler struct {
+	compiler *jsonschema.Compiler
+}
+
+// newSchemaCompiler loads the embedded OpenAPI document for compiling its
+// schemas. OpenAPI 3.1 schemas are JSON Schema 2020-12.
+func newSchemaCompiler(t *testing.T) *schemaCompiler {
+	t.Helper()
+
+	data, err := _docs.ReadFile("openapi/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft2020)
+
+	if err := compiler.AddResource(_specURL, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	return &schemaCompiler{compiler: compiler}
+}
+
+// compile compiles the schema at pointer in the OpenAPI document
+func (c *schemaCompiler) compile(t *testing.T, pointer string) *jsonschema.Schema {
+	t.Helper()
+
+	schema, err := c.compiler.Compile(_specURL + "#" + pointer)
+	if err != nil {
+		t.Fatalf("invalid schema at %s: %v", pointer, err)
+	}
+
+	return schema
+}
+
+// escapePointer escapes token for use in a JSON pointer (RFC 6901)
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
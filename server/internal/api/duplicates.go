@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"github.com/digitalnest-wit/nestqueue/internal/similarity"
+	"github.com/digitalnest-wit/nestqueue/internal/storage"
+)
+
+var (
+	// _duplicateWindow is how far back tickets are considered as duplicates
+	_duplicateWindow = 30 * 24 * time.Hour
+
+	// _duplicateCandidates caps the recent tickets compared against
+	_duplicateCandidates = 500
+
+	// _duplicateThreshold is the similarity score a ticket needs to be
+	// reported as a likely duplicate
+	_duplicateThreshold = 0.35
+
+	// _duplicateLimit caps the duplicates reported for a ticket
+	_duplicateLimit = 5
+)
+
+// duplicate is an open ticket that looks like the ticket being written
+type duplicate struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Site      string    `json:"site"`
+	Status    string    `json:"status"`
+	CreatedOn time.Time `json:"createdOn"`
+	Score     float64   `json:"score"`
+}
+
+// handleSuggestDuplicates handles suggesting open tickets at the same site
+// that look like a ticket being written, so the client can point them out
+// while the user types
+func (h *TicketHandler) handleSuggestDuplicates(w http.ResponseWriter, r *http.Request) {
+	var (
+		body struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			Site        string `json:"site"`
+		}
+	)
+
+	if err := decodeInto(r.Body, &body); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	duplicates, err := h.findDuplicates(ctx, models.Ticket{
+		Title:       body.Title,
+		Description: body.Description,
+		Site:        body.Site,
+	})
+	if err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := map[string]any{
+		"count":      len(duplicates),
+		"duplicates": duplicates,
+	}
+
+	encodeJSON(h, w, response)
+}
+
+// findDuplicates compares ticket against the open tickets created at its site
+// within _duplicateWindow and returns the most similar ones
+func (h *TicketHandler) findDuplicates(ctx context.Context, ticket models.Ticket) ([]duplicate, error) {
+	var duplicates = []duplicate{}
+
+	if ticket.Site == "" {
+		return duplicates, nil
+	}
+
+	candidates, err := h.store.FindTickets(ctx, storage.TicketFilter{
+		Site:         ticket.Site,
+		Statuses:     models.OpenStatuses,
+		CreatedAfter: time.Now().Add(-_duplicateWindow),
+		Sort:         "-createdOn",
+		Limit:        _duplicateCandidates,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		documents = make([]similarity.Document, 0, len(candidates))
+		byId      = make(map[string]models.Ticket, len(candidates))
+	)
+
+	for _, candidate := range candidates {
+		documents = append(documents, similarity.Document{ID: candidate.ID, Text: ticketText(candidate)})
+		byId[candidate.ID] = candidate
+	}
+
+	for _, match := range similarity.Rank(ticketText(ticket), documents, _duplicateThreshold, _duplicateLimit) {
+		candidate := byId[match.ID]
+
+		duplicates = append(duplicates, duplicate{
+			ID:        candidate.ID,
+			Title:     candidate.Title,
+			Site:      candidate.Site,
+			Status:    candidate.Status,
+			CreatedOn: candidate.CreatedOn,
+			Score:     match.Score,
+		})
+	}
+
+	return duplicates, nil
+}
+
+// ticketText is the text of a ticket compared for similarity. The title is
+// repeated so that it weighs more than the description.
+func ticketText(ticket models.Ticket) string {
+	return ticket.Title + " " + ticket.Title + " " + ticket.Description
+}
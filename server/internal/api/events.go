@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/logging"
+)
+
+var (
+	// _eventsTimeoutPolicy bounds an event stream. Clients reconnect once it
+	// ends, resuming after the last event they read.
+	_eventsTimeoutPolicy = 30 * time.Minute
+
+	// _eventsKeepAlive is the longest an event stream goes without writing,
+	// so idle streams are not closed by proxies
+	_eventsKeepAlive = 15 * time.Second
+)
+
+// _eventStreamReset is sent first when a stream could not resume after the
+// client's last event, which may have been followed by events it missed
+const _eventStreamReset = "stream.reset"
+
+// handleTicketEvents handles streaming the changes made to tickets and their
+// comments as server-sent events. A client reconnecting with Last-Event-ID
+// resumes after the last event it read.
+func (h *TicketHandler) handleTicketEvents(w http.ResponseWriter, r *http.Request) {
+	var (
+		sugar = logging.FromContext(r.Context(), h.logger).Sugar()
+		after = r.Header.Get("Last-Event-ID")
+		reset bool
+	)
+
+	ctx, cancel := requestContext(r, _eventsTimeoutPolicy)
+	defer cancel()
+
+	// Streams end when the server shuts down rather than holding it up
+	defer context.AfterFunc(h.streams, cancel)()
+
+	watch, err := h.store.WatchTickets(ctx, after, _eventsKeepAlive)
+
+	// An event too old to resume after, or no event at all, starts the stream
+	// from now
+	if err != nil && after != "" && ctx.Err() == nil {
+		reset = true
+		watch, err = h.store.WatchTickets(ctx, "", _eventsKeepAlive)
+	}
+
+	if err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	defer watch.Close(context.WithoutCancel(ctx))
+
+	// Let the stream outlast the server's write timeout
+	controller := http.NewResponseController(w)
+	deadline, _ := ctx.Deadline()
+
+	if err := controller.SetWriteDeadline(deadline); err != nil {
+		sugar.Debugw("failed to extend write deadline", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if reset {
+		err = writeEvent(w, "", _eventStreamReset, struct{}{})
+	}
+
+	for count := 0; err == nil; {
+		// Flushing is best effort; not every writer supports it
+		_ = controller.Flush()
+
+		event, nextErr := watch.Next(ctx)
+
+		switch {
+		case ctx.Err() != nil:
+			// The client went away, the stream timed out or the server is
+			// shutting down
+			sugar.Debugw("event stream ended", "count", count)
+			return
+		case nextErr != nil:
+			sugar.Errorw("event stream interrupted", "count", count, "error", nextErr)
+			return
+		case event == nil:
+			_, err = io.WriteString(w, ": keep-alive\n\n")
+		default:
+			err = writeEvent(w, event.ID, event.Type, event)
+			count++
+		}
+	}
+
+	// The client cannot be written to, so it is gone
+	sugar.Debugw("failed to write event", "error", err)
+}
+
+// writeEvent writes a server-sent event of type name holding data as JSON.
+// The event is only given an ID when id is set.
+func writeEvent(w io.Writer, id, name string, data any) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, encoded)
+
+	return err
+}
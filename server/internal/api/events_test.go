@@ -0,0 +1,48 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/digitalnest-wit/nestqueue/internal/storage"
+)
+
+func TestWriteEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		data any
+		want string
+	}{
+		{
+			name: "ticket.updated",
+			id:   "8263A1",
+			data: storage.TicketEvent{ID: "8263A1", Type: storage.EventTicketUpdated, TicketID: "0123456789abcdef01234567"},
+			want: "id: 8263A1\nevent: ticket.updated\ndata: {\"type\":\"ticket.updated\",\"ticketId\":\"0123456789abcdef01234567\"}\n\n",
+		},
+		{
+			name: _eventStreamReset,
+			data: struct{}{},
+			want: "event: stream.reset\ndata: {}\n\n",
+		},
+		{
+			// Newlines in values are escaped, so they cannot end the event
+			name: "ticket.created",
+			id:   "8263A2",
+			data: map[string]string{"title": "line\nbreak"},
+			want: "id: 8263A2\nevent: ticket.created\ndata: {\"title\":\"line\\nbreak\"}\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var b strings.Builder
+
+		if err := writeEvent(&b, tt.id, tt.name, tt.data); err != nil {
+			t.Fatal(err)
+		}
+
+		if b.String() != tt.want {
+			t.Errorf("writeEvent(%q) = %q, want %q", tt.name, b.String(), tt.want)
+		}
+	}
+}
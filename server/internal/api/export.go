@@ -0,0 +1,378 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/logging"
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	_formatCSV    = "csv"
+	_formatNDJSON = "ndjson"
+)
+
+var (
+	// _exportTimeoutPolicy bounds an export, which reads every matching
+	// ticket and may outlast the server's write timeout
+	_exportTimeoutPolicy = 5 * time.Minute
+
+	// _exportFlushInterval is how many tickets are written between flushes
+	_exportFlushInterval = 100
+
+	// _exportColumns lists the built-in ticket fields that can be exported,
+	// in their default order
+	_exportColumns = []string{
+		"id",
+		"title",
+		"description",
+		"site",
+		"category",
+		"assignedTo",
+		"createdBy",
+		"priority",
+		"status",
+		"tags",
+		"createdOn",
+		"updatedAt",
+		"closedOn",
+	}
+)
+
+// exporter writes tickets in an export format
+type exporter interface {
+	// header starts the export
+	header(columns []string) error
+
+	// write writes the values of a ticket's columns
+	write(columns []string, values []any) error
+
+	// flush writes any buffered output
+	flush() error
+}
+
+// handleExportTickets handles streaming the tickets matching the listing
+// filters as CSV or NDJSON. Tickets are written as they are read rather than
+// collected first, so exports of any size use little memory.
+func (h *TicketHandler) handleExportTickets(w http.ResponseWriter, r *http.Request) {
+	var (
+		query  = r.URL.Query()
+		filter = parseTicketFilter(query)
+		format = query.Get("format")
+		sugar  = logging.FromContext(r.Context(), h.logger).Sugar()
+	)
+
+	if format == "" {
+		format = _formatCSV
+	}
+
+	columns, location, err := parseExportOptions(query.Get("columns"), query.Get("tz"))
+	if err == nil {
+		err = filter.Validate()
+	}
+
+	if err == nil && format != _formatCSV && format != _formatNDJSON {
+		err = fmt.Errorf("unknown format %q", format)
+	}
+
+	if err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	ctx, cancel := requestContext(r, _exportTimeoutPolicy)
+	defer cancel()
+
+	// Let the export outlast the server's write timeout
+	controller := http.NewResponseController(w)
+	deadline, _ := ctx.Deadline()
+
+	if err := controller.SetWriteDeadline(deadline); err != nil {
+		sugar.Debugw("failed to extend write deadline", "error", err)
+	}
+
+	var (
+		out     exporter
+		started bool
+		count   int
+	)
+
+	switch format {
+	case _formatCSV:
+		out = &csvExporter{writer: csv.NewWriter(w)}
+	case _formatNDJSON:
+		out = &ndjsonExporter{writer: w}
+	}
+
+	// Headers are only sent once the query has succeeded, so a failing query
+	// can still be answered with an error
+	start := func() error {
+		if started {
+			return nil
+		}
+
+		started = true
+		filename := fmt.Sprintf("tickets-%s.%s", time.Now().In(location).Format("20060102"), format)
+
+		if format == _formatCSV {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+		return out.header(columns)
+	}
+
+	err = h.store.StreamTickets(ctx, filter, func(ticket models.Ticket) error {
+		if err := start(); err != nil {
+			return err
+		}
+
+		if err := out.write(columns, exportValues(ticket, columns, location)); err != nil {
+			return err
+		}
+
+		count++
+
+		if count%_exportFlushInterval == 0 {
+			if err := out.flush(); err != nil {
+				return err
+			}
+
+			// Flushing is best effort; not every writer supports it
+			_ = controller.Flush()
+		}
+
+		return nil
+	})
+	if err == nil {
+		err = start()
+	}
+
+	if err != nil {
+		if !started {
+			writeError(h.logger, w, r, err)
+
+			return
+		}
+
+		// The response is already under way, so the export is cut short
+		sugar.Errorw("export interrupted", "count", count, "error", err)
+
+		return
+	}
+
+	if err := out.flush(); err != nil {
+		sugar.Errorw("failed to finish export", "count", count, "error", err)
+		return
+	}
+
+	sugar.Debugw("exported tickets", "count", count, "format", format, "filter", filter)
+}
+
+// parseExportOptions parses the comma-separated columns to export and the
+// time zone timestamps are written in. Custom fields are selected with the
+// "cf." prefix, e.g. "id,title,cf.assetTag". All built-in columns are
+// exported in UTC by default.
+func parseExportOptions(columnList, tz string) ([]string, *time.Location, error) {
+	var (
+		columns  = splitList([]string{columnList})
+		location = time.UTC
+	)
+
+	if len(columns) == 0 {
+		columns = _exportColumns
+	}
+
+	for _, column := range columns {
+		key, custom := strings.CutPrefix(column, "cf.")
+
+		if (custom && !models.IsValidFieldKey(key)) || (!custom && !slices.Contains(_exportColumns, column)) {
+			return nil, nil, fmt.Errorf("unknown column %q", column)
+		}
+	}
+
+	if tz != "" {
+		loaded, err := time.LoadLocation(tz)
+		if err != nil || tz == "Local" {
+			return nil, nil, fmt.Errorf("unknown time zone %q", tz)
+		}
+
+		location = loaded
+	}
+
+	return columns, location, nil
+}
+
+// exportValues returns the values of the columns of ticket. Timestamps,
+// including date custom fields, are written in location as RFC 3339 and are
+// empty when unset.
+func exportValues(ticket models.Ticket, columns []string, location *time.Location) []any {
+	var (
+		values    = make([]any, len(columns))
+		timestamp = func(t time.Time) any {
+			if t.IsZero() {
+				return nil
+			}
+
+			return t.In(location).Format(time.RFC3339)
+		}
+	)
+
+	for i, column := range columns {
+		if key, ok := strings.CutPrefix(column, "cf."); ok {
+			// Date custom fields are stored as dates and read back as BSON
+			// dates
+			switch value := ticket.CustomFields[key].(type) {
+			case bson.DateTime:
+				values[i] = timestamp(value.Time())
+			case time.Time:
+				values[i] = timestamp(value)
+			default:
+				values[i] = value
+			}
+
+			continue
+		}
+
+		switch column {
+		case "id":
+			values[i] = ticket.ID
+		case "title":
+			values[i] = ticket.Title
+		case "description":
+			values[i] = ticket.Description
+		case "site":
+			values[i] = ticket.Site
+		case "category":
+			values[i] = ticket.Category
+		case "assignedTo":
+			values[i] = ticket.AssignedTo
+		case "createdBy":
+			values[i] = ticket.CreatedBy
+		case "priority":
+			values[i] = ticket.Priority
+		case "status":
+			values[i] = ticket.Status
+		case "tags":
+			values[i] = models.NormalizeTags(ticket.Tags)
+		case "createdOn":
+			values[i] = timestamp(ticket.CreatedOn)
+		case "updatedAt":
+			values[i] = timestamp(ticket.UpdatedAt)
+		case "closedOn":
+			values[i] = timestamp(ticket.ClosedOn)
+		}
+	}
+
+	return values
+}
+
+// csvExporter writes tickets as CSV with a header row. encoding/csv quotes
+// fields containing commas, quotes and line breaks, so multi-line
+// descriptions stay in one cell.
+type csvExporter struct {
+	writer *csv.Writer
+}
+
+func (e *csvExporter) header(columns []string) error {
+	return e.writer.Write(columns)
+}
+
+func (e *csvExporter) write(_ []string, values []any) error {
+	record := make([]string, len(values))
+
+	for i, value := range values {
+		record[i] = csvCell(value)
+	}
+
+	return e.writer.Write(record)
+}
+
+func (e *csvExporter) flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// csvCell formats a value as a CSV cell. Lists are joined with commas, and
+// text a spreadsheet would run as a formula is prefixed with a quote.
+func csvCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+			return "'" + v
+		}
+
+		return v
+	case []string:
+		return csvCell(strings.Join(v, ","))
+	case []any:
+		items := make([]string, len(v))
+
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+
+		return csvCell(strings.Join(items, ","))
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// ndjsonExporter writes tickets as newline-delimited JSON objects holding the
+// selected columns in order
+type ndjsonExporter struct {
+	writer io.Writer
+	buffer bytes.Buffer
+}
+
+func (e *ndjsonExporter) header(_ []string) error {
+	return nil
+}
+
+func (e *ndjsonExporter) write(columns []string, values []any) error {
+	e.buffer.WriteByte('{')
+
+	for i, column := range columns {
+		if i > 0 {
+			e.buffer.WriteByte(',')
+		}
+
+		key, err := json.Marshal(column)
+		if err != nil {
+			return err
+		}
+
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return err
+		}
+
+		e.buffer.Write(key)
+		e.buffer.WriteByte(':')
+		e.buffer.Write(value)
+	}
+
+	e.buffer.WriteString("}\n")
+
+	return nil
+}
+
+func (e *ndjsonExporter) flush() error {
+	_, err := e.buffer.WriteTo(e.writer)
+	return err
+}
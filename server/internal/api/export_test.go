@@ -0,0 +1,40 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestExportValues(t *testing.T) {
+	var (
+		created = time.Date(2024, 3, 1, 18, 30, 0, 0, time.UTC)
+		bought  = time.Date(2023, 11, 20, 0, 0, 0, 0, time.UTC)
+		ticket  = models.Ticket{
+			ID:        "t1",
+			CreatedOn: created,
+			CustomFields: map[string]any{
+				"bought":   bson.NewDateTimeFromTime(bought),
+				"checked":  bought,
+				"assetTag": "=A-12",
+				"cost":     120.5,
+			},
+		}
+		columns = []string{"id", "createdOn", "closedOn", "cf.bought", "cf.checked", "cf.assetTag", "cf.cost", "cf.missing"}
+	)
+
+	location, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+
+	want := []string{"t1", "2024-03-01T10:30:00-08:00", "", "2023-11-19T16:00:00-08:00", "2023-11-19T16:00:00-08:00", "'=A-12", "120.5", ""}
+
+	for i, value := range exportValues(ticket, columns, location) {
+		if got := csvCell(value); got != want[i] {
+			t.Errorf("%s = %q, want %q", columns[i], got, want[i])
+		}
+	}
+}
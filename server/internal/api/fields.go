@@ -0,0 +1,162 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"github.com/digitalnest-wit/nestqueue/internal/storage"
+	"go.uber.org/zap"
+)
+
+// FieldHandler handles custom field schema API requests
+type FieldHandler struct {
+	store  *storage.FieldStore
+	logger *zap.Logger
+}
+
+// NewFieldHandler creates a new field schema handler
+func NewFieldHandler(store *storage.FieldStore, logger *zap.Logger) *FieldHandler {
+	return &FieldHandler{
+		store:  store,
+		logger: logger.Named("handler.fields"),
+	}
+}
+
+// Logger simply returns this handler's logger. This method is implemented to
+// satisfy logHandler.
+func (h *FieldHandler) Logger() *zap.Logger {
+	return h.logger
+}
+
+// RegisterRoutes registers the field schema API routes
+func (h *FieldHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/fields", h.handleGetSchemas)
+	mux.HandleFunc("GET /api/v1/fields/{category}", h.handleGetSchema)
+	mux.HandleFunc("PUT /api/v1/fields/{category}", h.handleSaveSchema)
+	mux.HandleFunc("DELETE /api/v1/fields/{category}", h.handleDeleteSchema)
+}
+
+// handleGetSchemas handles listing the field schemas of every category
+func (h *FieldHandler) handleGetSchemas(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	results, err := h.store.FindSchemas(ctx)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := map[string]any{
+		"count":   len(results),
+		"schemas": results,
+	}
+
+	encodeJSON(h, w, response)
+}
+
+// handleGetSchema handles retrieving the field schema of a category
+func (h *FieldHandler) handleGetSchema(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	schema, err := h.store.FindSchema(ctx, r.PathValue("category"))
+	if err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	encodeJSON(h, w, schema)
+}
+
+// handleSaveSchema handles creating or replacing the field schema of a
+// category
+func (h *FieldHandler) handleSaveSchema(w http.ResponseWriter, r *http.Request) {
+	var (
+		body struct {
+			Fields []models.FieldDefinition `json:"fields"`
+		}
+	)
+
+	if err := decodeInto(r.Body, &body); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	schema := models.FieldSchema{
+		Category: r.PathValue("category"),
+		Fields:   body.Fields,
+	}
+
+	if err := validateSchema(schema); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	saved, err := h.store.SaveSchema(ctx, schema)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	encodeJSON(h, w, saved)
+}
+
+// handleDeleteSchema handles removing the field schema of a category
+func (h *FieldHandler) handleDeleteSchema(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	if err := h.store.DeleteSchema(ctx, r.PathValue("category")); err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateSchema checks that every field of schema has a unique, usable key
+// and a known type
+func validateSchema(schema models.FieldSchema) error {
+	if schema.Category == "" {
+		return errors.New("category is required")
+	}
+
+	seen := make(map[string]bool, len(schema.Fields))
+
+	for _, field := range schema.Fields {
+		if !models.IsValidFieldKey(field.Key) {
+			return fmt.Errorf("invalid field key %q", field.Key)
+		}
+
+		if seen[field.Key] {
+			return fmt.Errorf("duplicate field key %q", field.Key)
+		}
+
+		seen[field.Key] = true
+
+		if !models.IsValidFieldType(field.Type) {
+			return fmt.Errorf("field %q has unknown type %q", field.Key, field.Type)
+		}
+
+		if field.Type == models.FieldEnum && len(field.Options) == 0 {
+			return fmt.Errorf("enum field %q has no options", field.Key)
+		}
+	}
+
+	return nil
+}
@@ -2,32 +2,71 @@ package api
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/digitalnest-wit/nestqueue/internal/logging"
 	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"github.com/digitalnest-wit/nestqueue/internal/rules"
 	"github.com/digitalnest-wit/nestqueue/internal/storage"
 	"go.uber.org/zap"
 )
 
 var (
 	errInternal            = errors.New("an internal server error occurred")
+	errInvalidCustomFields = errors.New("invalid custom fields")
+
+	// _databaseTimeoutPolicy bounds the database work of a request
 	_databaseTimeoutPolicy = 8 * time.Second
+
+	// _maxPageSize caps the tickets listed in a single page
+	_maxPageSize = 1000
 )
 
 // TicketHandler handles ticket-related API requests
 type TicketHandler struct {
 	store  *storage.TicketStore
+	fields *storage.FieldStore
+	rules  *rules.Engine
 	logger *zap.Logger
+
+	// confirmKey signs bulk confirmation tokens, which are valid on every
+	// server sharing the key
+	confirmKey []byte
+
+	// streams is canceled to end the open event streams
+	streams     context.Context
+	stopStreams context.CancelFunc
 }
 
-// NewTicketHandler creates a new ticket handler
-func NewTicketHandler(store *storage.TicketStore, logger *zap.Logger) *TicketHandler {
+// NewTicketHandler creates a new ticket handler. Custom fields are validated
+// against the schemas in fields, and tickets are passed through the rule
+// engine as they are created and updated. Bulk confirmation tokens are signed
+// with confirmKey; when it is empty a random key is used, and tokens are only
+// valid on this server until it restarts.
+func NewTicketHandler(store *storage.TicketStore, fields *storage.FieldStore, engine *rules.Engine, confirmKey []byte, logger *zap.Logger) *TicketHandler {
+	if len(confirmKey) == 0 {
+		confirmKey = make([]byte, 32)
+		_, _ = rand.Read(confirmKey)
+	}
+
+	streams, stopStreams := context.WithCancel(context.Background())
+
 	return &TicketHandler{
-		store:  store,
-		logger: logger.Named("handler"),
+		store:       store,
+		fields:      fields,
+		rules:       engine,
+		logger:      logger.Named("handler"),
+		confirmKey:  confirmKey,
+		streams:     streams,
+		stopStreams: stopStreams,
 	}
 }
 
@@ -37,75 +76,136 @@ func (h *TicketHandler) Logger() *zap.Logger {
 	return h.logger
 }
 
+// CloseStreams ends the open event streams, e.g. when the server shuts down,
+// so their clients reconnect to another server instead of holding this one up
+func (h *TicketHandler) CloseStreams() {
+	h.stopStreams()
+}
+
 // RegisterRoutes registers the ticket API routes
 func (h *TicketHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/v1/tickets", h.handleCreateTicket)
 	mux.HandleFunc("GET /api/v1/tickets", h.handleGetTickets)
+	mux.HandleFunc("GET /api/v1/tickets/export", h.handleExportTickets)
+	mux.HandleFunc("GET /api/v1/tickets/events", h.handleTicketEvents)
+	mux.HandleFunc("POST /api/v1/tickets/duplicates", h.handleSuggestDuplicates)
+	mux.HandleFunc("POST /api/v1/tickets/bulk", h.handleBulkTickets)
 	mux.HandleFunc("GET /api/v1/tickets/{id}", h.handleGetTicket)
-	mux.HandleFunc("PUT /api/v1/tickets/{id}", h.handleUpdateTicket)
+	mux.HandleFunc("PUT /api/v1/tickets/{id}", h.handleReplaceTicket)
+	mux.HandleFunc("PATCH /api/v1/tickets/{id}", h.handlePatchTicket)
 	mux.HandleFunc("DELETE /api/v1/tickets/{id}", h.handleDeleteTicket)
+	mux.HandleFunc("POST /api/v1/tickets/{id}/merge", h.handleMergeTicket)
 }
 
 // handleCreateTicket handles creating a new ticket
 func (h *TicketHandler) handleCreateTicket(w http.ResponseWriter, r *http.Request) {
 	var (
 		newTicket models.Ticket
-		sugar     = h.logger.Sugar()
+		sugar     = logging.FromContext(r.Context(), h.logger).Sugar()
 		response  map[string]any
 	)
 
 	if err := decodeInto(r.Body, &newTicket); err != nil {
-		e := fmt.Errorf("bad request: %w", err)
-		sugar.Debug(e)
-		http.Error(w, e.Error(), http.StatusBadRequest)
+		writeError(h.logger, w, r, badRequest(err))
 
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), _databaseTimeoutPolicy)
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
 	defer cancel()
 
+	if err := h.checkCustomFields(ctx, &newTicket); err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	// Look for duplicates before the ticket is stored so it does not match
+	// itself. Failing to do so should not prevent the ticket from being
+	// created.
+	duplicates, err := h.findDuplicates(ctx, newTicket)
+	if err != nil {
+		sugar.Errorw("failed to find duplicate tickets", "error", err)
+		duplicates = []duplicate{}
+	}
+
+	// A failing rule should not prevent the ticket from being created, so the
+	// ticket is stored as submitted instead
+	result, err := h.rules.Evaluate(ctx, models.EventCreate, newTicket)
+	if err != nil {
+		sugar.Errorw("failed to evaluate rules", "error", err)
+	} else {
+		newTicket = result.Ticket
+	}
+
 	id, err := h.store.CreateTicket(ctx, newTicket)
 	if err != nil {
-		sugar.Error(err)
-		http.Error(w, errInternal.Error(), http.StatusInternalServerError)
+		writeError(h.logger, w, r, err)
 
 		return
 	}
 
-	w.Header().Set("Content-Type", "application.json")
-	w.WriteHeader(http.StatusCreated)
+	if result != nil && result.Pending() {
+		if created, err := h.store.FindTicket(ctx, id); err == nil {
+			h.rules.Dispatch(ctx, models.EventCreate, result, *created)
+		} else {
+			sugar.Errorw("failed to dispatch rule actions", "ticket.id", id, "error", err)
+		}
+	}
 
-	response = map[string]any{"id": id}
+	w.Header().Set("Content-Type", "application/json")
+
+	response = map[string]any{
+		"id":         id,
+		"duplicates": duplicates,
+	}
 
-	encodeJSON(h, w, response)
+	writeJSON(h, w, http.StatusCreated, response)
 }
 
-// handleGetTickets handles listing all tickets with optional query filtering
+// handleGetTickets handles listing all tickets with optional filtering. q
+// matches a ticket's title and description; site and status match exactly,
+// status taking comma-separated statuses; tags.any, tags.all and tags.none
+// take comma-separated tags; cf.<key> matches a custom field, a date like
+// 2024-05-01 matching the whole day; sort takes
+// comma-separated fields, e.g. sort=-priority,cf.serial; limit and offset
+// read the results a page at a time.
 func (h *TicketHandler) handleGetTickets(w http.ResponseWriter, r *http.Request) {
 	var (
-		query   = r.URL.Query().Get("q")
+		filter  = parseTicketFilter(r.URL.Query())
 		results []models.Ticket
-		sugar   = h.logger.Sugar()
+		sugar   = logging.FromContext(r.Context(), h.logger).Sugar()
 	)
 
-	ctx, cancel := context.WithTimeout(context.Background(), _databaseTimeoutPolicy)
+	err := parsePage(r.URL.Query(), &filter)
+	if err == nil {
+		err = filter.Validate()
+	}
+
+	if err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
 	defer cancel()
 
-	results, err := h.store.FindTickets(ctx, query)
+	results, err = h.store.FindTickets(ctx, filter)
 	if err != nil {
-		sugar.Error(err)
-		http.Error(w, errInternal.Error(), http.StatusInternalServerError)
+		writeError(h.logger, w, r, err)
 
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 
-	// If no results were returned and query is non-empty, respond with not found
-	if len(results) == 0 && query != "" {
-		sugar.Debugw("no tickets found", "query", query)
-		w.WriteHeader(http.StatusNotFound)
+	// If no results were returned and a filter is set, respond with not found.
+	// Pages past the first are only empty once every match has been read.
+	status := http.StatusOK
+
+	if len(results) == 0 && !filter.IsZero() && filter.Offset == 0 {
+		sugar.Debugw("no tickets found", "filter", filter)
+		status = http.StatusNotFound
 	}
 
 	response := map[string]any{
@@ -113,34 +213,70 @@ func (h *TicketHandler) handleGetTickets(w http.ResponseWriter, r *http.Request)
 		"tickets": results,
 	}
 
-	encodeJSON(h, w, response)
+	writeJSON(h, w, status, response)
 }
 
 // handleGetTicket handles retrieving a ticket by ID
 func (h *TicketHandler) handleGetTicket(w http.ResponseWriter, r *http.Request) {
 	var (
 		ticketId = r.PathValue("id")
-		sugar    = h.logger.Sugar()
+		sugar    = logging.FromContext(r.Context(), h.logger).Sugar()
 	)
 
-	ctx, cancel := context.WithTimeout(context.Background(), _databaseTimeoutPolicy)
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
 	defer cancel()
 
 	ticket, err := h.store.FindTicket(ctx, ticketId)
 
 	if err != nil {
-		switch {
-		case errors.Is(err, storage.ErrTicketNotFound):
-			sugar.Debug(err.Error())
-			http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	// Merged tickets redirect to the ticket they were merged into, unless the
+	// merged ticket itself is asked for
+	if ticket.MergedInto != "" && r.URL.Query().Get("redirect") != "false" {
+		sugar.Debugw("redirecting merged ticket", "ticket.id", ticketId, "mergedInto", ticket.MergedInto)
+		http.Redirect(w, r, "/api/v1/tickets/"+ticket.MergedInto, http.StatusMovedPermanently)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 
-			return
-		default:
-			sugar.Error(err)
-			http.Error(w, errInternal.Error(), http.StatusInternalServerError)
+	encodeJSON(h, w, ticket)
+}
 
-			return
+// handleMergeTicket handles merging a duplicate ticket into another. The
+// ticket in the path is the duplicate; the merged ticket is returned.
+func (h *TicketHandler) handleMergeTicket(w http.ResponseWriter, r *http.Request) {
+	var (
+		ticketId = r.PathValue("id")
+		body     struct {
+			Into string `json:"into"`
 		}
+	)
+
+	if err := decodeInto(r.Body, &body); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	if body.Into == "" {
+		writeError(h.logger, w, r, newProblem(http.StatusBadRequest, "into is required"))
+		return
+	}
+
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	ticket, err := h.store.MergeTickets(ctx, ticketId, body.Into)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -148,69 +284,384 @@ func (h *TicketHandler) handleGetTicket(w http.ResponseWriter, r *http.Request)
 	encodeJSON(h, w, ticket)
 }
 
-// handleUpdateTicket handles updating an existing ticket
-func (h *TicketHandler) handleUpdateTicket(w http.ResponseWriter, r *http.Request) {
+// handleReplaceTicket handles replacing the editable fields of a ticket.
+// Fields left out of the body are cleared.
+func (h *TicketHandler) handleReplaceTicket(w http.ResponseWriter, r *http.Request) {
+	h.updateTicket(w, r, true)
+}
+
+// handlePatchTicket handles applying a JSON Merge Patch (RFC 7396) to a
+// ticket. Fields left out of the patch are kept and null clears a field.
+func (h *TicketHandler) handlePatchTicket(w http.ResponseWriter, r *http.Request) {
+	if !isMergePatch(r.Header.Get("Content-Type")) {
+		w.Header().Set("Accept-Patch", _mergePatchType)
+		writeError(h.logger, w, r, newProblem(http.StatusUnsupportedMediaType, errUnsupportedPatch.Error()))
+
+		return
+	}
+
+	h.updateTicket(w, r, false)
+}
+
+// updateTicket handles replacing or patching a ticket. Malformed bodies are
+// answered with 400 Bad Request and invalid fields with 422 Unprocessable
+// Entity.
+func (h *TicketHandler) updateTicket(w http.ResponseWriter, r *http.Request, replace bool) {
 	var (
 		ticketId = r.PathValue("id")
-		updates  = make(map[string]any, 10)
-		sugar    = h.logger.Sugar()
+		body     map[string]any
 	)
 
-	if err := decodeInto(r.Body, &updates); err != nil {
-		sugar.Error(err)
-		http.Error(w, errInternal.Error(), http.StatusInternalServerError)
+	if err := decodeInto(r.Body, &body); err != nil || body == nil {
+		if err == nil {
+			err = errors.New("body must be a JSON object")
+		}
+
+		writeError(h.logger, w, r, badRequest(err))
 
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), _databaseTimeoutPolicy)
+	updates, err := ticketUpdates(body, replace)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
 	defer cancel()
 
+	current, err := h.store.FindTicket(ctx, ticketId)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	// An empty patch changes nothing
+	if len(updates) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		encodeJSON(h, w, current)
+
+		return
+	}
+
+	if fields, ok := updates["customFields"]; ok && (replace || fields == nil) {
+		clearCustomFields(current, updates)
+	}
+
+	if err := h.checkCustomFieldUpdates(ctx, current, updates, nil); err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
 	ticket, err := h.store.UpdateTicket(ctx, ticketId, updates)
 	if err != nil {
-		switch {
-		case errors.Is(err, storage.ErrTicketNotFound):
-			sugar.Debug(err)
-			http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(h.logger, w, r, err)
+
+		return
+	}
 
-			return
-		default:
-			sugar.Error(err)
-			http.Error(w, errInternal.Error(), http.StatusInternalServerError)
+	ticket = h.applyUpdateRules(ctx, ticket)
 
-			return
+	w.Header().Set("Content-Type", "application/json")
+
+	encodeJSON(h, w, ticket)
+}
+
+// applyUpdateRules evaluates the update rules against ticket, stores the
+// changes they make and dispatches their side effects. Changes made by rules
+// do not trigger the rules again. On failure the error is logged and ticket
+// is returned unchanged.
+func (h *TicketHandler) applyUpdateRules(ctx context.Context, ticket *models.Ticket) *models.Ticket {
+	result, err := h.rules.Evaluate(ctx, models.EventUpdate, *ticket)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Sugar().Errorw("failed to evaluate rules", "ticket.id", ticket.ID, "error", err)
+		return ticket
+	}
+
+	return h.applyRuleResult(ctx, result, ticket)
+}
+
+// applyRuleResult stores the changes the update rules in result made to
+// ticket and dispatches their side effects. On failure the error is logged
+// and ticket is returned unchanged.
+func (h *TicketHandler) applyRuleResult(ctx context.Context, result *rules.Result, ticket *models.Ticket) *models.Ticket {
+	var sugar = logging.FromContext(ctx, h.logger).Sugar()
+
+	if len(result.Changes) > 0 {
+		updated, err := h.store.UpdateTicket(ctx, ticket.ID, result.Changes)
+		if err != nil {
+			sugar.Errorw("failed to store rule changes", "ticket.id", ticket.ID, "error", err)
+			return ticket
 		}
+
+		ticket = updated
 	}
 
-	w.WriteHeader(http.StatusOK)
+	h.rules.Dispatch(ctx, models.EventUpdate, result, *ticket)
 
-	encodeJSON(h, w, ticket)
+	return ticket
 }
 
 // handleDeleteTicket handles deleting a ticket
 func (h *TicketHandler) handleDeleteTicket(w http.ResponseWriter, r *http.Request) {
-	var (
-		ticketId = r.PathValue("id")
-		sugar    = h.logger.Sugar()
-	)
+	var ticketId = r.PathValue("id")
 
-	ctx, cancel := context.WithTimeout(context.Background(), _databaseTimeoutPolicy)
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
 	defer cancel()
 
 	if err := h.store.DeleteTicket(ctx, ticketId); err != nil {
-		switch {
-		case errors.Is(err, storage.ErrTicketNotFound):
-			sugar.Debugw(err.Error())
-			http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(h.logger, w, r, err)
 
-			return
-		default:
-			sugar.Error(err)
-			http.Error(w, errInternal.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
 
-			return
+// checkCustomFields validates the custom fields of a new ticket against the
+// schema of its category and normalizes their values. Validation failures
+// wrap errInvalidCustomFields.
+func (h *TicketHandler) checkCustomFields(ctx context.Context, ticket *models.Ticket) error {
+	schema, err := h.findSchema(ctx, ticket.Category)
+	if err != nil {
+		return err
+	}
+
+	present := make([]string, 0, len(ticket.CustomFields))
+
+	for key, value := range ticket.CustomFields {
+		if value != nil {
+			present = append(present, key)
 		}
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	normalized, err := models.ValidateCustomFields(schema, ticket.CustomFields, present)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errInvalidCustomFields, err)
+	}
+
+	// Fields explicitly set to null on creation are simply left out
+	for key, value := range normalized {
+		if value == nil {
+			delete(normalized, key)
+		}
+	}
+
+	ticket.CustomFields = normalized
+
+	return nil
+}
+
+// changesCustomFields reports whether updates may change the custom fields of
+// a ticket, directly or by moving it to another category
+func changesCustomFields(updates map[string]any) bool {
+	_, hasFields := updates["customFields"]
+	_, hasCategory := updates["category"].(string)
+
+	return hasFields || hasCategory
+}
+
+// checkCustomFieldUpdates validates the custom fields in updates against the
+// schema of the category of current, the ticket being updated, taking a
+// category change into account, and replaces them with their normalized
+// values. Schemas are looked up in schemas, when not nil, before the store,
+// and added to it. Validation failures wrap errInvalidCustomFields.
+func (h *TicketHandler) checkCustomFieldUpdates(ctx context.Context, current *models.Ticket, updates map[string]any, schemas map[string]*models.FieldSchema) error {
+	if !changesCustomFields(updates) {
+		return nil
+	}
+
+	rawFields, hasFields := updates["customFields"]
+	category, hasCategory := updates["category"].(string)
+
+	patch, ok := rawFields.(map[string]any)
+	if hasFields && !ok {
+		return fmt.Errorf("%w: customFields must be an object", errInvalidCustomFields)
+	}
+
+	if !hasCategory {
+		category = current.Category
+	}
+
+	if !hasFields && category == current.Category {
+		return nil
+	}
+
+	schema, err := h.cachedSchema(ctx, category, schemas)
+	if err != nil {
+		return err
+	}
+
+	// The fields a ticket carries belong to its category, so moving it to
+	// another category removes the fields the new schema does not define
+	// and checks the rest against it
+	if category != current.Category {
+		if patch == nil {
+			patch = make(map[string]any, len(current.CustomFields))
+		}
+
+		for key, value := range current.CustomFields {
+			if _, patched := patch[key]; patched || value == nil {
+				continue
+			}
+
+			if schema == nil {
+				patch[key] = nil
+				continue
+			}
+
+			if _, defined := schema.Field(key); !defined {
+				patch[key] = nil
+				continue
+			}
+
+			if patch[key], err = decodedValue(value); err != nil {
+				return err
+			}
+		}
+
+		hasFields = true
+	}
+
+	// Work out which fields the ticket will carry once patch is applied
+	var present []string
+
+	for key, value := range current.CustomFields {
+		if v, patched := patch[key]; value != nil && (!patched || v != nil) {
+			present = append(present, key)
+		}
+	}
+
+	for key, value := range patch {
+		if _, existing := current.CustomFields[key]; !existing && value != nil {
+			present = append(present, key)
+		}
+	}
+
+	normalized, err := models.ValidateCustomFields(schema, patch, present)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errInvalidCustomFields, err)
+	}
+
+	if hasFields {
+		updates["customFields"] = normalized
+	}
+
+	return nil
+}
+
+// decodedValue converts a stored custom field value into the form it is
+// decoded from JSON in, so it can be validated again, e.g. a date as a string
+func decodedValue(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded any
+
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+
+	return decoded, nil
+}
+
+// cachedSchema returns the field schema of category like findSchema, looking
+// it up in schemas first when schemas is not nil
+func (h *TicketHandler) cachedSchema(ctx context.Context, category string, schemas map[string]*models.FieldSchema) (*models.FieldSchema, error) {
+	if schema, ok := schemas[category]; ok {
+		return schema, nil
+	}
+
+	schema, err := h.findSchema(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+
+	if schemas != nil {
+		schemas[category] = schema
+	}
+
+	return schema, nil
+}
+
+// findSchema returns the field schema of category, or nil if the category
+// has no custom fields
+func (h *TicketHandler) findSchema(ctx context.Context, category string) (*models.FieldSchema, error) {
+	schema, err := h.fields.FindSchema(ctx, category)
+	if errors.Is(err, storage.ErrSchemaNotFound) {
+		return nil, nil
+	}
+
+	return schema, err
+}
+
+// parseTicketFilter builds a ticket filter from the query parameters of a
+// listing request
+func parseTicketFilter(values url.Values) storage.TicketFilter {
+	filter := storage.TicketFilter{
+		Query:    values.Get("q"),
+		Site:     values.Get("site"),
+		Statuses: splitList(values["status"]),
+		AnyTags:  splitList(values["tags.any"]),
+		AllTags:  splitList(values["tags.all"]),
+		NoTags:   splitList(values["tags.none"]),
+		Sort:     values.Get("sort"),
+	}
+
+	for key := range values {
+		if field, ok := strings.CutPrefix(key, "cf."); ok {
+			if filter.CustomFields == nil {
+				filter.CustomFields = make(map[string]string)
+			}
+
+			filter.CustomFields[field] = values.Get(key)
+		}
+	}
+
+	return filter
+}
+
+// parsePage reads the limit and offset query parameters of a listing request
+// into filter
+func parsePage(values url.Values, filter *storage.TicketFilter) error {
+	for key, dst := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		value := values.Get(key)
+		if value == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("%s must be a non-negative number", key)
+		}
+
+		*dst = n
+	}
+
+	if filter.Limit > _maxPageSize {
+		return fmt.Errorf("limit must be at most %d", _maxPageSize)
+	}
+
+	return nil
+}
+
+// splitList flattens repeated and comma-separated query parameter values
+// into a single list, e.g. ?tags.any=a,b&tags.any=c becomes [a b c]
+func splitList(values []string) []string {
+	var result []string
+
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				result = append(result, item)
+			}
+		}
+	}
+
+	return result
 }
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/logging"
+	"go.uber.org/zap"
+)
+
+var (
+	// _readinessTimeout bounds each readiness check, so a hung dependency
+	// fails the probe instead of stalling it
+	_readinessTimeout = 2 * time.Second
+)
+
+const (
+	_statusOK          = "ok"
+	_statusUnavailable = "unavailable"
+	_statusDraining    = "draining"
+)
+
+// ReadinessCheck reports an error while a dependency of the server is not
+// ready to serve requests
+type ReadinessCheck func(ctx context.Context) error
+
+// namedCheck is a readiness check with the name it is reported under
+type namedCheck struct {
+	name  string
+	check ReadinessCheck
+}
+
+// health is the body of a health or readiness response
+type health struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks,omitempty"`
+}
+
+// checkResult is the outcome of a readiness check
+type checkResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// HealthHandler handles the liveness and readiness probes of the container
+// orchestrator
+type HealthHandler struct {
+	checks   []namedCheck
+	draining atomic.Bool
+	logger   *zap.Logger
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(logger *zap.Logger) *HealthHandler {
+	return &HealthHandler{
+		logger: logger.Named("handler.health"),
+	}
+}
+
+// Logger simply returns this handler's logger. This method is implemented to
+// satisfy logHandler.
+func (h *HealthHandler) Logger() *zap.Logger {
+	return h.logger
+}
+
+// RegisterRoutes registers the health API routes
+func (h *HealthHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", h.handleGetHealth)
+	mux.HandleFunc("GET /readyz", h.handleGetReadiness)
+}
+
+// AddCheck adds a check of a dependency to the readiness probe, reported
+// under name. Checks must be added before the server starts.
+func (h *HealthHandler) AddCheck(name string, check ReadinessCheck) {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// Drain fails the readiness probe from now on, so the orchestrator stops
+// routing requests to the server while it shuts down
+func (h *HealthHandler) Drain() {
+	h.draining.Store(true)
+}
+
+// handleGetHealth handles the liveness probe, which passes as long as the
+// server answers
+func (h *HealthHandler) handleGetHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	encodeJSON(h, w, health{Status: _statusOK})
+}
+
+// handleGetReadiness handles the readiness probe, running every check at once
+// and answering 503 Service Unavailable when any fails or the server is
+// shutting down
+func (h *HealthHandler) handleGetReadiness(w http.ResponseWriter, r *http.Request) {
+	var (
+		response = health{Status: _statusOK, Checks: make(map[string]checkResult, len(h.checks))}
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+	)
+
+	for _, c := range h.checks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			result := h.run(r.Context(), c.check)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			response.Checks[c.name] = result
+
+			if result.Status != _statusOK {
+				response.Status = _statusUnavailable
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if h.draining.Load() {
+		response.Status = _statusDraining
+	}
+
+	status := http.StatusOK
+	if response.Status != _statusOK {
+		status = http.StatusServiceUnavailable
+
+		logging.FromContext(r.Context(), h.logger).Sugar().Debugw("server is not ready", "checks", response.Checks, "status", response.Status)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(h, w, status, response)
+}
+
+// run runs a readiness check bounded by _readinessTimeout
+func (h *HealthHandler) run(ctx context.Context, check ReadinessCheck) checkResult {
+	ctx, cancel := context.WithTimeout(ctx, _readinessTimeout)
+	defer cancel()
+
+	var (
+		start = time.Now()
+		err   = check(ctx)
+	)
+
+	result := checkResult{Status: _statusOK, Duration: time.Since(start).String()}
+
+	if err != nil {
+		result.Status, result.Error = _statusUnavailable, err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/importer"
+	"github.com/digitalnest-wit/nestqueue/internal/logging"
+	"go.uber.org/zap"
+)
+
+var (
+	// _importTimeoutPolicy bounds an import, which may read and store many
+	// thousands of tickets
+	_importTimeoutPolicy = 10 * time.Minute
+
+	// _importMaxBytes caps the size of an uploaded import
+	_importMaxBytes int64 = 256 << 20
+)
+
+// ImportHandler handles bulk ticket import API requests
+type ImportHandler struct {
+	importer *importer.Importer
+	logger   *zap.Logger
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(importer *importer.Importer, logger *zap.Logger) *ImportHandler {
+	return &ImportHandler{
+		importer: importer,
+		logger:   logger.Named("handler.import"),
+	}
+}
+
+// Logger simply returns this handler's logger. This method is implemented to
+// satisfy logHandler.
+func (h *ImportHandler) Logger() *zap.Logger {
+	return h.logger
+}
+
+// RegisterRoutes registers the import API routes
+func (h *ImportHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/v1/tickets/import", h.handleImportTickets)
+}
+
+// handleImportTickets handles importing the tickets in the request body, read
+// as CSV or NDJSON. format defaults to the one named by the Content-Type;
+// map.<column>=<field> maps a source column to a ticket field; tz is the time
+// zone of timestamps without one; dryRun=true checks every row without
+// storing anything. The response reports the rows that were not imported.
+func (h *ImportHandler) handleImportTickets(w http.ResponseWriter, r *http.Request) {
+	var sugar = logging.FromContext(r.Context(), h.logger).Sugar()
+
+	opts, err := parseImportOptions(r)
+	if err == nil {
+		err = opts.Validate()
+	}
+
+	if err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	ctx, cancel := requestContext(r, _importTimeoutPolicy)
+	defer cancel()
+
+	// Let the upload outlast the server's read and write timeouts
+	controller := http.NewResponseController(w)
+	deadline, _ := ctx.Deadline()
+
+	if err := controller.SetReadDeadline(deadline); err != nil {
+		sugar.Debugw("failed to extend read deadline", "error", err)
+	}
+
+	if err := controller.SetWriteDeadline(deadline); err != nil {
+		sugar.Debugw("failed to extend write deadline", "error", err)
+	}
+
+	body := http.MaxBytesReader(w, r.Body, _importMaxBytes)
+	defer body.Close()
+
+	report, err := h.importer.Import(ctx, body, opts)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	encodeJSON(h, w, report)
+}
+
+// parseImportOptions reads import options from the query string and
+// Content-Type of r
+func parseImportOptions(r *http.Request) (importer.Options, error) {
+	var (
+		query = r.URL.Query()
+		opts  = importer.Options{
+			Format:  query.Get("format"),
+			Mapping: parseImportMapping(query),
+		}
+	)
+
+	if opts.Format == "" {
+		opts.Format = importFormat(r.Header.Get("Content-Type"))
+	}
+
+	if tz := query.Get("tz"); tz != "" {
+		location, err := time.LoadLocation(tz)
+		if err != nil || tz == "Local" {
+			return opts, fmt.Errorf("unknown time zone %q", tz)
+		}
+
+		opts.Location = location
+	}
+
+	if value := query.Get("dryRun"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, errors.New("dryRun must be true or false")
+		}
+
+		opts.DryRun = dryRun
+	}
+
+	if value := query.Get("batchSize"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil {
+			return opts, errors.New("batchSize must be a number")
+		}
+
+		opts.BatchSize = size
+	}
+
+	return opts, nil
+}
+
+// parseImportMapping reads map.<column>=<field> query parameters, e.g.
+// ?map.Subject=title&map.Opened=createdOn
+func parseImportMapping(values url.Values) map[string]string {
+	var mapping map[string]string
+
+	for key := range values {
+		if column, ok := strings.CutPrefix(key, "map."); ok {
+			if mapping == nil {
+				mapping = make(map[string]string)
+			}
+
+			mapping[column] = values.Get(key)
+		}
+	}
+
+	return mapping
+}
+
+// importFormat returns the import format named by a Content-Type, defaulting
+// to CSV
+func importFormat(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch mediaType {
+	case "application/x-ndjson", "application/jsonl", "application/json":
+		return importer.FormatNDJSON
+	default:
+		return importer.FormatCSV
+	}
+}
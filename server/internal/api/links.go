@@ -0,0 +1,114 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"github.com/digitalnest-wit/nestqueue/internal/storage"
+	"go.uber.org/zap"
+)
+
+// LinkHandler handles ticket relationship API requests
+type LinkHandler struct {
+	store  *storage.LinkStore
+	logger *zap.Logger
+}
+
+// NewLinkHandler creates a new link handler
+func NewLinkHandler(store *storage.LinkStore, logger *zap.Logger) *LinkHandler {
+	return &LinkHandler{
+		store:  store,
+		logger: logger.Named("handler.links"),
+	}
+}
+
+// Logger simply returns this handler's logger. This method is implemented to
+// satisfy logHandler.
+func (h *LinkHandler) Logger() *zap.Logger {
+	return h.logger
+}
+
+// RegisterRoutes registers the link API routes
+func (h *LinkHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/tickets/{id}/links", h.handleGetLinks)
+	mux.HandleFunc("POST /api/v1/tickets/{id}/links", h.handleCreateLink)
+	mux.HandleFunc("DELETE /api/v1/tickets/{id}/links/{linkId}", h.handleDeleteLink)
+}
+
+// handleGetLinks handles listing the links to and from a ticket
+func (h *LinkHandler) handleGetLinks(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	results, err := h.store.FindLinks(ctx, r.PathValue("id"))
+	if err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := map[string]any{
+		"count": len(results),
+		"links": results,
+	}
+
+	encodeJSON(h, w, response)
+}
+
+// handleCreateLink handles linking a ticket to another. The ticket in the
+// path is the source of the link; "child" creates a parent link in the
+// opposite direction.
+func (h *LinkHandler) handleCreateLink(w http.ResponseWriter, r *http.Request) {
+	var (
+		body struct {
+			Type   string `json:"type"`
+			Target string `json:"target"`
+		}
+	)
+
+	if err := decodeInto(r.Body, &body); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	link := models.Link{
+		Type:   body.Type,
+		Source: r.PathValue("id"),
+		Target: body.Target,
+	}
+
+	if body.Type == "child" {
+		link = models.Link{
+			Type:   models.LinkParent,
+			Source: body.Target,
+			Target: r.PathValue("id"),
+		}
+	}
+
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	created, err := h.store.CreateLink(ctx, link)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(h, w, http.StatusCreated, created)
+}
+
+// handleDeleteLink handles removing a link from a ticket
+func (h *LinkHandler) handleDeleteLink(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	if err := h.store.DeleteLink(ctx, r.PathValue("id"), r.PathValue("linkId")); err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"mime"
+	"slices"
+	"strings"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+)
+
+// _mergePatchType is the media type of a JSON Merge Patch (RFC 7396)
+const _mergePatchType = "application/merge-patch+json"
+
+var (
+	errUnsupportedPatch = fmt.Errorf("unsupported patch format, expected %s", _mergePatchType)
+
+	// _editableFields lists the ticket fields a PUT or PATCH can change,
+	// along with addTags and removeTags, which add and remove single tags
+	_editableFields = []string{
+		"title",
+		"description",
+		"site",
+		"category",
+		"assignedTo",
+		"priority",
+		"status",
+		"tags",
+		"addTags",
+		"removeTags",
+		"customFields",
+	}
+
+	// _readOnlyFields lists the ticket fields that are only ever set by the
+	// server
+	_readOnlyFields = []string{
+		"id",
+		"createdBy",
+		"createdOn",
+		"updatedAt",
+		"closedOn",
+		"mergedInto",
+		"mergedFrom",
+	}
+
+	// _statuses lists the statuses a ticket can be given
+	_statuses = []string{models.StatusActive, models.StatusOpen, models.StatusClosed, models.StatusRejected}
+)
+
+// fieldError is a problem with a single field of a request body
+type fieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// invalidFieldsError lists the invalid fields of a request body. It is
+// answered with 422 Unprocessable Entity.
+type invalidFieldsError []fieldError
+
+func (e invalidFieldsError) Error() string {
+	problems := make([]string, 0, len(e))
+
+	for _, f := range e {
+		problems = append(problems, f.Field+": "+f.Detail)
+	}
+
+	return "invalid fields: " + strings.Join(problems, "; ")
+}
+
+// isMergePatch reports whether contentType is a JSON Merge Patch
+func isMergePatch(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == _mergePatchType
+}
+
+// ticketUpdates checks a PUT or PATCH body and turns it into the updates
+// taken by UpdateTicket. null clears a field: text becomes empty, tags are
+// removed and customFields loses every field; custom fields set to null are
+// removed one by one. addTags and removeTags are applied after tags. When replace is true body replaces the ticket, so the
+// fields it leaves out are cleared too and title, priority and status are
+// required. Read-only fields are ignored on replacement, as they are sent
+// back as returned by GET, and rejected in a patch.
+func ticketUpdates(body map[string]any, replace bool) (map[string]any, error) {
+	var (
+		updates  = make(map[string]any, len(body))
+		problems invalidFieldsError
+		invalid  = func(field, detail string) {
+			problems = append(problems, fieldError{Field: field, Detail: detail})
+		}
+	)
+
+	for _, field := range slices.Sorted(maps.Keys(body)) {
+		value := body[field]
+
+		switch {
+		case slices.Contains(_readOnlyFields, field):
+			if !replace {
+				invalid(field, "is read-only")
+			}
+
+			continue
+		case !slices.Contains(_editableFields, field):
+			invalid(field, "is not a ticket field")
+			continue
+		}
+
+		if err := checkTicketField(field, value); err != nil {
+			invalid(field, err.Error())
+			continue
+		}
+
+		updates[field] = clearedValue(field, value)
+	}
+
+	if replace {
+		for _, field := range _editableFields {
+			if _, ok := body[field]; ok {
+				continue
+			}
+
+			switch field {
+			case "title", "priority", "status":
+				invalid(field, "is required")
+			case "addTags", "removeTags":
+				// Not fields of the ticket, so there is nothing to clear
+			default:
+				updates[field] = clearedValue(field, nil)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	return updates, nil
+}
+
+// checkTicketField checks the value of an editable ticket field decoded from
+// JSON
+func checkTicketField(field string, value any) error {
+	switch field {
+	case "title":
+		if s, ok := value.(string); !ok || strings.TrimSpace(s) == "" {
+			return errors.New("must be non-empty text")
+		}
+	case "priority":
+		n, ok := value.(float64)
+		if !ok || n != float64(int(n)) || n < 1 || n > 5 {
+			return errors.New("must be a whole number from 1 to 5")
+		}
+	case "status":
+		if s, ok := value.(string); !ok || !slices.Contains(_statuses, s) {
+			return fmt.Errorf("must be one of %s", strings.Join(_statuses, ", "))
+		}
+	case "tags", "addTags", "removeTags":
+		if value == nil && field == "tags" {
+			return nil
+		}
+
+		items, ok := value.([]any)
+		if !ok {
+			return errors.New("must be a list of tags")
+		}
+
+		for _, item := range items {
+			if _, ok := item.(string); !ok {
+				return errors.New("must be a list of tags")
+			}
+		}
+	case "customFields":
+		if _, ok := value.(map[string]any); !ok && value != nil {
+			return errors.New("must be an object")
+		}
+	default:
+		if _, ok := value.(string); !ok && value != nil {
+			return errors.New("must be text")
+		}
+	}
+
+	return nil
+}
+
+// clearedValue returns the update storing value in field, where null clears
+// the field. Clearing customFields is left to the caller, which knows the
+// fields a ticket carries, so it stays null.
+func clearedValue(field string, value any) any {
+	if value != nil {
+		return value
+	}
+
+	switch field {
+	case "tags":
+		return []any{}
+	case "customFields":
+		return nil
+	default:
+		return ""
+	}
+}
+
+// clearCustomFields turns a customFields update into a patch removing every
+// custom field of ticket that it does not set. It is used when customFields
+// is set to null in a patch, and when a ticket is replaced.
+func clearCustomFields(ticket *models.Ticket, updates map[string]any) {
+	fields, _ := updates["customFields"].(map[string]any)
+	patch := make(map[string]any, len(fields)+len(ticket.CustomFields))
+
+	for key := range ticket.CustomFields {
+		patch[key] = nil
+	}
+
+	for key, value := range fields {
+		patch[key] = value
+	}
+
+	updates["customFields"] = patch
+}
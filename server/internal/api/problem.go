@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/digitalnest-wit/nestqueue/internal/importer"
+	"github.com/digitalnest-wit/nestqueue/internal/logging"
+	"github.com/digitalnest-wit/nestqueue/internal/reports"
+	"github.com/digitalnest-wit/nestqueue/internal/storage"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/zap"
+)
+
+const (
+	// _problemType is the media type of a problem details response (RFC 7807)
+	_problemType = "application/problem+json"
+
+	// _statusClientClosedRequest answers requests the client gave up on. It
+	// is never seen by the client, but tells them apart in logs and metrics.
+	_statusClientClosedRequest = 499
+)
+
+var (
+	// _notFoundErrors are answered with 404 Not Found
+	_notFoundErrors = []error{
+		storage.ErrTicketNotFound,
+		storage.ErrLinkNotFound,
+		storage.ErrQueueNotFound,
+		storage.ErrMemberNotFound,
+		storage.ErrRuleNotFound,
+		storage.ErrSchemaNotFound,
+		storage.ErrTagNotFound,
+	}
+
+	// _badRequestErrors are answered with 400 Bad Request
+	_badRequestErrors = []error{
+		errInvalidCustomFields,
+		storage.ErrInvalidFilter,
+		storage.ErrInvalidLink,
+		importer.ErrInvalidOptions,
+		importer.ErrInvalidInput,
+		reports.ErrInvalidParams,
+	}
+
+	// _conflictErrors are answered with 409 Conflict
+	_conflictErrors = []error{
+		errConfirmMismatch,
+		storage.ErrLinkExists,
+		storage.ErrLinkCycle,
+		storage.ErrMemberExists,
+		storage.ErrInvalidMerge,
+	}
+
+	errTimeout  = errors.New("the request timed out")
+	errCanceled = errors.New("the client closed the request")
+)
+
+// Problem is an error answered with a problem details response (RFC 7807)
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Errors    []fieldError `json:"errors,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+}
+
+// newProblem creates a problem with the given status and detail. Problems
+// have no type of their own, so they are typed about:blank and titled with
+// their status, as RFC 7807 asks.
+func newProblem(status int, detail string) *Problem {
+	title := http.StatusText(status)
+	if status == _statusClientClosedRequest {
+		title = "Client Closed Request"
+	}
+
+	return &Problem{
+		Type:   "about:blank",
+		Title:  title,
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// badRequest creates a 400 Bad Request problem explaining err
+func badRequest(err error) *Problem {
+	return newProblem(http.StatusBadRequest, err.Error())
+}
+
+func (p *Problem) Error() string {
+	return p.Title + ": " + p.Detail
+}
+
+// problemFor maps err to the problem it is answered with. Errors the client
+// cannot act on are answered with 500 Internal Server Error, without their
+// detail.
+func problemFor(err error) *Problem {
+	var (
+		problem  *Problem
+		invalid  invalidFieldsError
+		tooLarge *http.MaxBytesError
+	)
+
+	switch {
+	case errors.As(err, &problem):
+		return problem
+	case errors.As(err, &invalid):
+		problem = newProblem(http.StatusUnprocessableEntity, "the request body has invalid fields")
+		problem.Errors = invalid
+
+		return problem
+	case errors.As(err, &tooLarge):
+		return newProblem(http.StatusRequestEntityTooLarge, err.Error())
+	case isAny(err, _notFoundErrors):
+		return newProblem(http.StatusNotFound, err.Error())
+	case isAny(err, _badRequestErrors):
+		return badRequest(err)
+	case isAny(err, _conflictErrors), mongo.IsDuplicateKeyError(err):
+		return newProblem(http.StatusConflict, err.Error())
+	case errors.Is(err, context.DeadlineExceeded), mongo.IsTimeout(err):
+		return newProblem(http.StatusGatewayTimeout, errTimeout.Error())
+	case errors.Is(err, context.Canceled):
+		return newProblem(_statusClientClosedRequest, errCanceled.Error())
+	default:
+		return newProblem(http.StatusInternalServerError, errInternal.Error())
+	}
+}
+
+// isAny reports whether err matches any of targets
+func isAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeError logs err and answers r with the problem it maps to. Server
+// errors are logged as errors and client errors at debug level.
+func writeError(logger *zap.Logger, w http.ResponseWriter, r *http.Request, err error) {
+	var (
+		problem = problemFor(err)
+		sugar   = logger.Sugar()
+	)
+
+	if r != nil {
+		sugar = logging.FromContext(r.Context(), logger).Sugar()
+	}
+
+	if problem.Status >= http.StatusInternalServerError {
+		sugar.Error(err)
+	} else {
+		sugar.Debug(err)
+	}
+
+	writeProblem(logger, w, r, problem)
+}
+
+// writeProblem answers r with problem. The problem is copied so that shared
+// problems are not modified.
+func writeProblem(logger *zap.Logger, w http.ResponseWriter, r *http.Request, problem *Problem) {
+	var response = *problem
+
+	response.RequestID = requestID(w, r)
+
+	if r != nil {
+		response.Instance = r.URL.Path
+	}
+
+	w.Header().Set("Content-Type", _problemType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(response.Status)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		if r != nil {
+			logger = logging.FromContext(r.Context(), logger)
+		}
+
+		logger.Sugar().Debugw("failed to write problem", "error", err)
+	}
+}
+
+// requestID returns the ID of the request being answered, preferring the one
+// already set on the response
+func requestID(w http.ResponseWriter, r *http.Request) string {
+	if id := w.Header().Get("X-Request-ID"); id != "" {
+		return id
+	}
+
+	if r != nil {
+		return r.Header.Get("X-Request-ID")
+	}
+
+	return ""
+}
@@ -0,0 +1,292 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"github.com/digitalnest-wit/nestqueue/internal/storage"
+	"go.uber.org/zap"
+)
+
+// QueueHandler handles assignment queue API requests
+type QueueHandler struct {
+	store  *storage.QueueStore
+	logger *zap.Logger
+}
+
+// NewQueueHandler creates a new queue handler
+func NewQueueHandler(store *storage.QueueStore, logger *zap.Logger) *QueueHandler {
+	return &QueueHandler{
+		store:  store,
+		logger: logger.Named("handler.queues"),
+	}
+}
+
+// Logger simply returns this handler's logger. This method is implemented to
+// satisfy logHandler.
+func (h *QueueHandler) Logger() *zap.Logger {
+	return h.logger
+}
+
+// RegisterRoutes registers the queue API routes
+func (h *QueueHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/v1/queues", h.handleCreateQueue)
+	mux.HandleFunc("GET /api/v1/queues", h.handleGetQueues)
+	mux.HandleFunc("GET /api/v1/queues/{id}", h.handleGetQueue)
+	mux.HandleFunc("PUT /api/v1/queues/{id}", h.handleUpdateQueue)
+	mux.HandleFunc("DELETE /api/v1/queues/{id}", h.handleDeleteQueue)
+	mux.HandleFunc("POST /api/v1/queues/{id}/members", h.handleAddMember)
+	mux.HandleFunc("PUT /api/v1/queues/{id}/members/{email}", h.handleUpdateMember)
+	mux.HandleFunc("DELETE /api/v1/queues/{id}/members/{email}", h.handleRemoveMember)
+}
+
+// handleCreateQueue handles creating a new queue
+func (h *QueueHandler) handleCreateQueue(w http.ResponseWriter, r *http.Request) {
+	var newQueue models.Queue
+
+	if err := decodeInto(r.Body, &newQueue); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	if newQueue.Strategy == "" {
+		newQueue.Strategy = models.StrategyRoundRobin
+	}
+
+	if err := validateQueue(newQueue); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	id, err := h.store.CreateQueue(ctx, newQueue)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(h, w, http.StatusCreated, map[string]any{"id": id})
+}
+
+// handleGetQueues handles listing all queues
+func (h *QueueHandler) handleGetQueues(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	results, err := h.store.FindQueues(ctx)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := map[string]any{
+		"count":  len(results),
+		"queues": results,
+	}
+
+	encodeJSON(h, w, response)
+}
+
+// handleGetQueue handles retrieving a queue by ID
+func (h *QueueHandler) handleGetQueue(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	queue, err := h.store.FindQueue(ctx, r.PathValue("id"))
+	if err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	encodeJSON(h, w, queue)
+}
+
+// handleUpdateQueue handles updating the name, sites, categories or strategy
+// of an existing queue. Fields left out are not changed.
+func (h *QueueHandler) handleUpdateQueue(w http.ResponseWriter, r *http.Request) {
+	var (
+		queueId = r.PathValue("id")
+		update  storage.QueueUpdate
+	)
+
+	if err := decodeInto(r.Body, &update); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	if err := validateQueueUpdate(update); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	// An empty update changes nothing
+	if update.IsZero() {
+		queue, err := h.store.FindQueue(ctx, queueId)
+		if err != nil {
+			writeError(h.logger, w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		encodeJSON(h, w, queue)
+
+		return
+	}
+
+	queue, err := h.store.UpdateQueue(ctx, queueId, update)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	encodeJSON(h, w, queue)
+}
+
+// handleDeleteQueue handles deleting a queue
+func (h *QueueHandler) handleDeleteQueue(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	if err := h.store.DeleteQueue(ctx, r.PathValue("id")); err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAddMember handles adding a member to a queue. Members are available
+// unless the request says otherwise.
+func (h *QueueHandler) handleAddMember(w http.ResponseWriter, r *http.Request) {
+	var member models.QueueMember
+
+	if err := decodeInto(r.Body, &member); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	if member.Email == "" {
+		writeError(h.logger, w, r, newProblem(http.StatusBadRequest, "member email is required"))
+		return
+	}
+
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	queue, err := h.store.AddMember(ctx, r.PathValue("id"), member)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(h, w, http.StatusCreated, queue)
+}
+
+// handleUpdateMember handles changing the availability of a queue member
+func (h *QueueHandler) handleUpdateMember(w http.ResponseWriter, r *http.Request) {
+	var (
+		body struct {
+			Available *bool `json:"available"`
+		}
+	)
+
+	if err := decodeInto(r.Body, &body); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	if body.Available == nil {
+		writeError(h.logger, w, r, newProblem(http.StatusBadRequest, "available is required"))
+		return
+	}
+
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	queue, err := h.store.UpdateMember(ctx, r.PathValue("id"), r.PathValue("email"), *body.Available)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	encodeJSON(h, w, queue)
+}
+
+// handleRemoveMember handles removing a member from a queue
+func (h *QueueHandler) handleRemoveMember(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	if _, err := h.store.RemoveMember(ctx, r.PathValue("id"), r.PathValue("email")); err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateQueue checks that queue can be stored and used for routing
+func validateQueue(queue models.Queue) error {
+	if queue.Name == "" {
+		return errors.New("queue name is required")
+	}
+
+	if !models.IsValidStrategy(queue.Strategy) {
+		return fmt.Errorf("unknown strategy %q", queue.Strategy)
+	}
+
+	seen := make(map[string]bool, len(queue.Members))
+
+	for _, member := range queue.Members {
+		if member.Email == "" {
+			return errors.New("member email is required")
+		}
+
+		if seen[member.Email] {
+			return fmt.Errorf("duplicate member %q", member.Email)
+		}
+
+		seen[member.Email] = true
+	}
+
+	return nil
+}
+
+// validateQueueUpdate checks that update leaves a queue that can be stored
+// and used for routing
+func validateQueueUpdate(update storage.QueueUpdate) error {
+	if update.Name != nil && *update.Name == "" {
+		return errors.New("queue name cannot be empty")
+	}
+
+	if update.Strategy != nil && !models.IsValidStrategy(*update.Strategy) {
+		return fmt.Errorf("unknown strategy %q", *update.Strategy)
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/reports"
+	"go.uber.org/zap"
+)
+
+// _reportTimeoutPolicy bounds report aggregations, which scan far more
+// tickets than other requests
+var _reportTimeoutPolicy = 30 * time.Second
+
+// ReportHandler handles reporting API requests
+type ReportHandler struct {
+	reporter *reports.Reporter
+	logger   *zap.Logger
+}
+
+// NewReportHandler creates a new report handler
+func NewReportHandler(reporter *reports.Reporter, logger *zap.Logger) *ReportHandler {
+	return &ReportHandler{
+		reporter: reporter,
+		logger:   logger.Named("handler.reports"),
+	}
+}
+
+// Logger simply returns this handler's logger. This method is implemented to
+// satisfy logHandler.
+func (h *ReportHandler) Logger() *zap.Logger {
+	return h.logger
+}
+
+// RegisterRoutes registers the report API routes
+func (h *ReportHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/reports/resolution", func(w http.ResponseWriter, r *http.Request) {
+		handleReport(h, w, r, h.reporter.Resolution)
+	})
+	mux.HandleFunc("GET /api/v1/reports/backlog", func(w http.ResponseWriter, r *http.Request) {
+		handleReport(h, w, r, h.reporter.Backlog)
+	})
+	mux.HandleFunc("GET /api/v1/reports/throughput", func(w http.ResponseWriter, r *http.Request) {
+		handleReport(h, w, r, h.reporter.Throughput)
+	})
+	mux.HandleFunc("GET /api/v1/reports/sla", func(w http.ResponseWriter, r *http.Request) {
+		handleReport(h, w, r, h.reporter.SLA)
+	})
+}
+
+// handleReport handles running a report with the parameters in the query
+// string
+func handleReport[T any](h *ReportHandler, w http.ResponseWriter, r *http.Request, run func(context.Context, reports.Params) ([]T, error)) {
+	// Validating fills in the defaults echoed in the response
+	params, err := parseReportParams(r)
+	if err == nil {
+		err = params.Validate()
+	}
+
+	if err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	ctx, cancel := requestContext(r, _reportTimeoutPolicy)
+	defer cancel()
+
+	results, err := run(ctx, params)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := map[string]any{
+		"from":    params.From.In(params.Location),
+		"to":      params.To.In(params.Location),
+		"groupBy": params.GroupBy,
+		"count":   len(results),
+		"results": results,
+	}
+
+	encodeJSON(h, w, response)
+}
+
+// parseReportParams reads report parameters from the query string of r.
+// Times are RFC 3339 timestamps or dates, which start at midnight in the
+// requested time zone.
+func parseReportParams(r *http.Request) (reports.Params, error) {
+	var (
+		query  = r.URL.Query()
+		params = reports.Params{
+			GroupBy:    query.Get("groupBy"),
+			Interval:   query.Get("interval"),
+			Site:       query.Get("site"),
+			Category:   query.Get("category"),
+			AssignedTo: query.Get("assignedTo"),
+			Location:   time.UTC,
+		}
+	)
+
+	if tz := query.Get("tz"); tz != "" {
+		location, err := time.LoadLocation(tz)
+		if err != nil || tz == "Local" {
+			return params, fmt.Errorf("unknown time zone %q", tz)
+		}
+
+		params.Location = location
+	}
+
+	for key, dst := range map[string]*time.Time{"from": &params.From, "to": &params.To} {
+		value := query.Get(key)
+		if value == "" {
+			continue
+		}
+
+		t, err := parseReportTime(value, params.Location)
+		if err != nil {
+			return params, fmt.Errorf("%s must be a date or an RFC 3339 timestamp", key)
+		}
+
+		*dst = t
+	}
+
+	if value := query.Get("priority"); value != "" {
+		priority, err := strconv.Atoi(value)
+		if err != nil || priority < 1 || priority > 5 {
+			return params, errors.New("priority must be a number between 1 and 5")
+		}
+
+		params.Priority = priority
+	}
+
+	return params, nil
+}
+
+// parseReportTime parses an RFC 3339 timestamp, or a date starting at
+// midnight in location
+func parseReportTime(value string, location *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.ParseInLocation(time.DateOnly, value, location)
+}
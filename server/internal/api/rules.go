@@ -0,0 +1,222 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"github.com/digitalnest-wit/nestqueue/internal/rules"
+	"github.com/digitalnest-wit/nestqueue/internal/storage"
+	"go.uber.org/zap"
+)
+
+// RuleHandler handles triage rule API requests
+type RuleHandler struct {
+	store   *storage.RuleStore
+	tickets *storage.TicketStore
+	logger  *zap.Logger
+}
+
+// NewRuleHandler creates a new rule handler. tickets is used to load the
+// ticket of a dry run by ID.
+func NewRuleHandler(store *storage.RuleStore, tickets *storage.TicketStore, logger *zap.Logger) *RuleHandler {
+	return &RuleHandler{
+		store:   store,
+		tickets: tickets,
+		logger:  logger.Named("handler.rules"),
+	}
+}
+
+// Logger simply returns this handler's logger. This method is implemented to
+// satisfy logHandler.
+func (h *RuleHandler) Logger() *zap.Logger {
+	return h.logger
+}
+
+// RegisterRoutes registers the rule API routes
+func (h *RuleHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/v1/rules", h.handleCreateRule)
+	mux.HandleFunc("GET /api/v1/rules", h.handleGetRules)
+	mux.HandleFunc("POST /api/v1/rules/dry-run", h.handleDryRun)
+	mux.HandleFunc("GET /api/v1/rules/{id}", h.handleGetRule)
+	mux.HandleFunc("PUT /api/v1/rules/{id}", h.handleReplaceRule)
+	mux.HandleFunc("DELETE /api/v1/rules/{id}", h.handleDeleteRule)
+}
+
+// handleCreateRule handles creating a new rule
+func (h *RuleHandler) handleCreateRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.decodeRule(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	id, err := h.store.CreateRule(ctx, rule)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(h, w, http.StatusCreated, map[string]any{"id": id})
+}
+
+// handleGetRules handles listing all rules in evaluation order
+func (h *RuleHandler) handleGetRules(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	results, err := h.store.FindRules(ctx, false)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := map[string]any{
+		"count": len(results),
+		"rules": results,
+	}
+
+	encodeJSON(h, w, response)
+}
+
+// handleGetRule handles retrieving a rule by ID
+func (h *RuleHandler) handleGetRule(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	rule, err := h.store.FindRule(ctx, r.PathValue("id"))
+	if err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	encodeJSON(h, w, rule)
+}
+
+// handleReplaceRule handles replacing an existing rule
+func (h *RuleHandler) handleReplaceRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.decodeRule(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	updated, err := h.store.ReplaceRule(ctx, r.PathValue("id"), rule)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	encodeJSON(h, w, updated)
+}
+
+// handleDeleteRule handles deleting a rule
+func (h *RuleHandler) handleDeleteRule(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	if err := h.store.DeleteRule(ctx, r.PathValue("id")); err != nil {
+		writeError(h.logger, w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDryRun handles reporting which rules would fire for a ticket and the
+// changes they would make, without storing anything or performing side
+// effects. The ticket is either given inline or loaded by ID.
+func (h *RuleHandler) handleDryRun(w http.ResponseWriter, r *http.Request) {
+	var (
+		body struct {
+			Event    string         `json:"event"`
+			TicketID string         `json:"ticketId"`
+			Ticket   *models.Ticket `json:"ticket"`
+		}
+	)
+
+	if err := decodeInto(r.Body, &body); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	if body.Event == "" {
+		body.Event = models.EventCreate
+	}
+
+	if body.Event != models.EventCreate && body.Event != models.EventUpdate {
+		writeError(h.logger, w, r, newProblem(http.StatusBadRequest, fmt.Sprintf("unknown event %q", body.Event)))
+		return
+	}
+
+	if (body.Ticket == nil) == (body.TicketID == "") {
+		writeError(h.logger, w, r, newProblem(http.StatusBadRequest, "expected exactly one of ticket or ticketId"))
+		return
+	}
+
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	if body.TicketID != "" {
+		ticket, err := h.tickets.FindTicket(ctx, body.TicketID)
+		if err != nil {
+			writeError(h.logger, w, r, err)
+
+			return
+		}
+
+		body.Ticket = ticket
+	}
+
+	enabled, err := h.store.FindRules(ctx, true)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	result, err := rules.Apply(enabled, body.Event, *body.Ticket)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	encodeJSON(h, w, result)
+}
+
+// decodeRule decodes and validates the rule in the request body. If the rule
+// is not acceptable, a response is written and ok is false.
+func (h *RuleHandler) decodeRule(w http.ResponseWriter, r *http.Request) (rule models.Rule, ok bool) {
+	rule = models.Rule{Enabled: true, Match: models.MatchAll}
+
+	if err := decodeInto(r.Body, &rule); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return rule, false
+	}
+
+	if err := rules.Validate(rule); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return rule, false
+	}
+
+	return rule, true
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"github.com/digitalnest-wit/nestqueue/internal/storage"
+	"go.uber.org/zap"
+)
+
+// TagHandler handles tag registry API requests
+type TagHandler struct {
+	store  *storage.TagStore
+	logger *zap.Logger
+}
+
+// NewTagHandler creates a new tag handler
+func NewTagHandler(store *storage.TagStore, logger *zap.Logger) *TagHandler {
+	return &TagHandler{
+		store:  store,
+		logger: logger.Named("handler.tags"),
+	}
+}
+
+// Logger simply returns this handler's logger. This method is implemented to
+// satisfy logHandler.
+func (h *TagHandler) Logger() *zap.Logger {
+	return h.logger
+}
+
+// RegisterRoutes registers the tag API routes
+func (h *TagHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/tags", h.handleGetTags)
+	mux.HandleFunc("PUT /api/v1/tags/{name}", h.handleSaveTag)
+	mux.HandleFunc("DELETE /api/v1/tags/{name}", h.handleDeleteTag)
+}
+
+// handleGetTags handles listing registered and in-use tags with their usage
+// counts
+func (h *TagHandler) handleGetTags(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	results, err := h.store.FindTags(ctx)
+	if err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := map[string]any{
+		"count": len(results),
+		"tags":  results,
+	}
+
+	encodeJSON(h, w, response)
+}
+
+// handleSaveTag handles registering a tag or changing its color
+func (h *TagHandler) handleSaveTag(w http.ResponseWriter, r *http.Request) {
+	var (
+		body struct {
+			Color string `json:"color"`
+		}
+		name = models.NormalizeTag(r.PathValue("name"))
+	)
+
+	if err := decodeInto(r.Body, &body); err != nil {
+		writeError(h.logger, w, r, badRequest(err))
+
+		return
+	}
+
+	if name == "" {
+		writeError(h.logger, w, r, newProblem(http.StatusBadRequest, "tag name is required"))
+		return
+	}
+
+	if !models.IsValidTagColor(body.Color) {
+		writeError(h.logger, w, r, newProblem(http.StatusBadRequest, fmt.Sprintf("invalid color %q", body.Color)))
+		return
+	}
+
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	tag := models.Tag{Name: name, Color: body.Color, Registered: true}
+
+	if err := h.store.SaveTag(ctx, tag); err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	encodeJSON(h, w, tag)
+}
+
+// handleDeleteTag handles removing a tag from the registry. With purge=true,
+// the tag is also removed from every ticket.
+func (h *TagHandler) handleDeleteTag(w http.ResponseWriter, r *http.Request) {
+	var (
+		name  = models.NormalizeTag(r.PathValue("name"))
+		purge = r.URL.Query().Get("purge") == "true"
+	)
+
+	ctx, cancel := requestContext(r, _databaseTimeoutPolicy)
+	defer cancel()
+
+	if err := h.store.DeleteTag(ctx, name, purge); err != nil {
+		writeError(h.logger, w, r, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,332 @@
+// Package backup dumps collections of the ticket database to a portable
+// archive and restores them from it.
+//
+// An archive is a gzipped tar file holding manifest.json followed by one
+// <collection>.ndjson file per collection, with a document per line in
+// canonical Extended JSON so every BSON type survives the round trip.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// _formatVersion is the version of the archive format written
+const _formatVersion = 1
+
+// _manifestFile names the manifest in an archive
+const _manifestFile = "manifest.json"
+
+// _defaultBatchSize is how many documents are restored at once by default
+const _defaultBatchSize = 500
+
+var (
+	ErrInvalidArchive = errors.New("invalid backup archive")
+	ErrNotEmpty       = errors.New("collection is not empty")
+)
+
+// Manifest describes the contents of an archive
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedOn time.Time `json:"createdOn"`
+	Database  string    `json:"database"`
+
+	// Collections maps the collections in the archive to their number of
+	// documents
+	Collections map[string]int `json:"collections"`
+}
+
+// Dump writes the documents of collections in db to w as an archive
+func Dump(ctx context.Context, db *mongo.Database, collections []string, w io.Writer) (*Manifest, error) {
+	var (
+		manifest = &Manifest{
+			Version:     _formatVersion,
+			CreatedOn:   time.Now().UTC(),
+			Database:    db.Name(),
+			Collections: make(map[string]int, len(collections)),
+		}
+		files = make(map[string]*os.File, len(collections))
+	)
+
+	defer func() {
+		for _, file := range files {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	// A tar header holds the size of its file, so each collection is
+	// spooled to a temporary file before it is archived
+	for _, name := range collections {
+		file, err := os.CreateTemp("", "nqbackup-*.ndjson")
+		if err != nil {
+			return nil, err
+		}
+
+		files[name] = file
+
+		count, err := dumpCollection(ctx, db.Collection(name), file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dump %s: %w", name, err)
+		}
+
+		manifest.Collections[name] = count
+	}
+
+	var (
+		gz = gzip.NewWriter(w)
+		tw = tar.NewWriter(gz)
+	)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeFile(tw, _manifestFile, int64(len(data)), bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	for _, name := range collections {
+		file := files[name]
+
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		if err := writeFile(tw, name+".ndjson", info.Size(), file); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	return manifest, gz.Close()
+}
+
+// dumpCollection writes every document of collection to w as a line of
+// canonical Extended JSON and returns how many were written
+func dumpCollection(ctx context.Context, collection *mongo.Collection, w io.Writer) (int, error) {
+	var (
+		count    int
+		buffered = bufio.NewWriter(w)
+	)
+
+	cursor, err := collection.Find(ctx, bson.D{})
+	if err != nil {
+		return 0, err
+	}
+
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		line, err := bson.MarshalExtJSON(cursor.Current, true, false)
+		if err != nil {
+			return count, err
+		}
+
+		buffered.Write(line)
+		buffered.WriteByte('\n')
+		count++
+	}
+
+	if err := cursor.Err(); err != nil {
+		return count, err
+	}
+
+	return count, buffered.Flush()
+}
+
+// writeFile adds a file of size bytes read from r to tw
+func writeFile(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: time.Now(),
+	}
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(tw, r)
+
+	return err
+}
+
+// RestoreOptions control a restore
+type RestoreOptions struct {
+	// Drop empties the collections in the archive before restoring them.
+	// Otherwise restoring fails with ErrNotEmpty unless they are empty.
+	Drop bool
+
+	// BatchSize is how many documents are inserted at once. Defaults to 500.
+	BatchSize int
+}
+
+// Restore restores the collections in the archive read from r into db
+func Restore(ctx context.Context, db *mongo.Database, r io.Reader, opts RestoreOptions) (*Manifest, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = _defaultBatchSize
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+
+	manifest, err := readManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check every collection before anything is written, so a refused
+	// restore leaves the database untouched
+	for name := range manifest.Collections {
+		count, err := db.Collection(name).EstimatedDocumentCount(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if count > 0 && !opts.Drop {
+			return nil, fmt.Errorf("%w: %s holds %d documents", ErrNotEmpty, name, count)
+		}
+	}
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+		}
+
+		name := strings.TrimSuffix(path.Base(header.Name), ".ndjson")
+		if _, ok := manifest.Collections[name]; !ok {
+			return nil, fmt.Errorf("%w: unexpected file %s", ErrInvalidArchive, header.Name)
+		}
+
+		collection := db.Collection(name)
+
+		if opts.Drop {
+			if _, err := collection.DeleteMany(ctx, bson.D{}); err != nil {
+				return nil, err
+			}
+		}
+
+		count, err := restoreCollection(ctx, collection, tr, opts.BatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", name, err)
+		}
+
+		if count != manifest.Collections[name] {
+			return nil, fmt.Errorf("%w: %s holds %d documents, expected %d", ErrInvalidArchive, name, count, manifest.Collections[name])
+		}
+	}
+
+	return manifest, nil
+}
+
+// readManifest reads the manifest, which is the first file of an archive
+func readManifest(tr *tar.Reader) (*Manifest, error) {
+	var manifest Manifest
+
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+
+	if header.Name != _manifestFile {
+		return nil, fmt.Errorf("%w: expected %s first, found %s", ErrInvalidArchive, _manifestFile, header.Name)
+	}
+
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+
+	if manifest.Version != _formatVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidArchive, manifest.Version)
+	}
+
+	return &manifest, nil
+}
+
+// restoreCollection inserts the documents read from r into collection,
+// batchSize at a time, and returns how many were inserted
+func restoreCollection(ctx context.Context, collection *mongo.Collection, r io.Reader, batchSize int) (int, error) {
+	var (
+		count   int
+		batch   []any
+		scanner = bufio.NewScanner(r)
+	)
+
+	// Documents may be up to 16 MB
+	scanner.Buffer(make([]byte, 64*1024), 17*1024*1024)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if _, err := collection.InsertMany(ctx, batch); err != nil {
+			return err
+		}
+
+		count += len(batch)
+		batch = nil
+
+		return nil
+	}
+
+	for line := 1; scanner.Scan(); line++ {
+		var doc bson.D
+
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		if err := bson.UnmarshalExtJSON(scanner.Bytes(), true, &doc); err != nil {
+			return count, fmt.Errorf("%w: line %d: %w", ErrInvalidArchive, line, err)
+		}
+
+		if batch = append(batch, doc); len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return count, err
+	}
+
+	return count, flush()
+}
@@ -0,0 +1,282 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+)
+
+// _skip is the field of a column that is not imported
+const _skip = "-"
+
+var (
+	// _importFields lists the built-in ticket fields columns can be read into
+	_importFields = []string{
+		"title",
+		"description",
+		"site",
+		"category",
+		"assignedTo",
+		"createdBy",
+		"priority",
+		"status",
+		"tags",
+		"createdOn",
+		"updatedAt",
+		"closedOn",
+	}
+
+	// _statuses lists the statuses an imported ticket can have
+	_statuses = []string{models.StatusActive, models.StatusOpen, models.StatusClosed, models.StatusRejected}
+
+	// _timeLayouts lists the timestamp layouts read, the last ones as
+	// commonly written by spreadsheets
+	_timeLayouts = []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05",
+		time.DateTime,
+		"2006-01-02 15:04",
+		time.DateOnly,
+		"1/2/2006 15:04:05",
+		"1/2/2006 15:04",
+		"1/2/2006",
+	}
+)
+
+// isField reports whether field is a ticket field a column can be read into
+func isField(field string) bool {
+	if key, ok := strings.CutPrefix(field, "cf."); ok {
+		return models.IsValidFieldKey(key)
+	}
+
+	return slices.Contains(_importFields, field)
+}
+
+// fieldFor returns the field column is read into, which is _skip when there
+// is none
+func fieldFor(column string, mapping map[string]string) string {
+	if field, ok := mapping[column]; ok {
+		return field
+	}
+
+	if isField(column) {
+		return column
+	}
+
+	return _skip
+}
+
+// columnFor returns the column read into field, for pointing out where a
+// missing or invalid value comes from
+func columnFor(field string, mapping map[string]string) string {
+	for column, mapped := range mapping {
+		if mapped == field {
+			return column
+		}
+	}
+
+	return field
+}
+
+// isBlank reports whether value is missing, as empty CSV cells and JSON nulls
+// are
+func isBlank(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	default:
+		return false
+	}
+}
+
+// setField reads value into field of ticket. Timestamps without a time zone
+// are read in location.
+func setField(ticket *models.Ticket, field string, value any, location *time.Location) error {
+	if key, ok := strings.CutPrefix(field, "cf."); ok {
+		if ticket.CustomFields == nil {
+			ticket.CustomFields = make(map[string]any)
+		}
+
+		if s, ok := value.(string); ok {
+			value = strings.TrimSpace(s)
+		}
+
+		ticket.CustomFields[key] = value
+
+		return nil
+	}
+
+	switch field {
+	case "priority":
+		priority, err := parsePriority(value)
+		if err != nil {
+			return err
+		}
+
+		ticket.Priority = priority
+
+		return nil
+	case "tags":
+		tags, err := parseTags(value)
+		if err != nil {
+			return err
+		}
+
+		ticket.Tags = tags
+
+		return nil
+	case "createdOn", "updatedAt", "closedOn":
+		t, err := parseTime(value, location)
+		if err != nil {
+			return err
+		}
+
+		switch field {
+		case "createdOn":
+			ticket.CreatedOn = t
+		case "updatedAt":
+			ticket.UpdatedAt = t
+		default:
+			ticket.ClosedOn = t
+		}
+
+		return nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return errors.New("expected text")
+	}
+
+	s = strings.TrimSpace(s)
+
+	switch field {
+	case "title":
+		ticket.Title = s
+	case "description":
+		ticket.Description = s
+	case "site":
+		ticket.Site = s
+	case "category":
+		ticket.Category = s
+	case "assignedTo":
+		ticket.AssignedTo = s
+	case "createdBy":
+		ticket.CreatedBy = s
+	case "status":
+		index := slices.IndexFunc(_statuses, func(status string) bool {
+			return strings.EqualFold(status, s)
+		})
+		if index < 0 {
+			return fmt.Errorf("expected one of %v, got %q", _statuses, s)
+		}
+
+		ticket.Status = _statuses[index]
+	}
+
+	return nil
+}
+
+// parsePriority reads a priority from 1 to 5
+func parsePriority(value any) (int, error) {
+	var priority int
+
+	switch v := value.(type) {
+	case string:
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return 0, fmt.Errorf("expected a priority from 1 to 5, got %q", v)
+		}
+
+		priority = n
+	case float64:
+		if v != float64(int(v)) {
+			return 0, fmt.Errorf("expected a priority from 1 to 5, got %v", v)
+		}
+
+		priority = int(v)
+	default:
+		return 0, errors.New("expected a priority from 1 to 5")
+	}
+
+	if priority < 1 || priority > 5 {
+		return 0, fmt.Errorf("expected a priority from 1 to 5, got %d", priority)
+	}
+
+	return priority, nil
+}
+
+// parseTags reads tags from a comma-separated list or a JSON array
+func parseTags(value any) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return models.NormalizeTags(strings.Split(v, ",")), nil
+	case []any:
+		tags := make([]string, 0, len(v))
+
+		for _, item := range v {
+			tag, ok := item.(string)
+			if !ok {
+				return nil, errors.New("expected a list of tags")
+			}
+
+			tags = append(tags, tag)
+		}
+
+		return models.NormalizeTags(tags), nil
+	default:
+		return nil, errors.New("expected a list of tags")
+	}
+}
+
+// parseTime reads a timestamp in one of _timeLayouts, in location unless it
+// has a time zone
+func parseTime(value any, location *time.Location) (time.Time, error) {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, errors.New("expected a timestamp")
+	}
+
+	s = strings.TrimSpace(s)
+
+	for _, layout := range _timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, location); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("expected a timestamp like %s, got %q", time.RFC3339, s)
+}
+
+// convertCustomFields converts the text of number fields read from CSV into
+// numbers, so they can be validated like values read from JSON
+func convertCustomFields(schema *models.FieldSchema, values map[string]any) error {
+	if schema == nil {
+		return nil
+	}
+
+	for key, value := range values {
+		field, ok := schema.Field(key)
+		s, isText := value.(string)
+
+		if !ok || !isText || field.Type != models.FieldNumber {
+			continue
+		}
+
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("custom field %q: expected a number, got %q", key, s)
+		}
+
+		values[key] = n
+	}
+
+	return nil
+}
@@ -0,0 +1,313 @@
+// Package importer reads tickets exported from other systems as CSV or NDJSON,
+// checks every row and stores the valid ones in batches, reporting the rows
+// that could not be imported.
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"github.com/digitalnest-wit/nestqueue/internal/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+var (
+	ErrInvalidOptions = errors.New("invalid import options")
+
+	// _defaultBatchSize is how many tickets are inserted at once by default
+	_defaultBatchSize = 500
+
+	// _maxBatchSize caps the tickets inserted at once
+	_maxBatchSize = 5000
+)
+
+// Options control how an import is read and stored
+type Options struct {
+	// Format is the format of the input: csv or ndjson
+	Format string
+
+	// Mapping maps source columns to ticket fields, e.g. "Subject" to
+	// "title". Custom fields are prefixed with "cf.", e.g. "cf.assetTag",
+	// and a column mapped to "-" is skipped. Columns left out of the mapping
+	// are read into the field of the same name, if there is one.
+	Mapping map[string]string
+
+	// Location is the time zone of timestamps without one. Defaults to UTC.
+	Location *time.Location
+
+	// DryRun checks every row without storing anything
+	DryRun bool
+
+	// BatchSize is how many tickets are inserted at once. Defaults to 500.
+	BatchSize int
+}
+
+// Validate fills in the defaults of o and checks that it describes an import.
+// Errors wrap ErrInvalidOptions.
+func (o *Options) Validate() error {
+	if o.Format != FormatCSV && o.Format != FormatNDJSON {
+		return fmt.Errorf("%w: unknown format %q", ErrInvalidOptions, o.Format)
+	}
+
+	for column, field := range o.Mapping {
+		if field != _skip && !isField(field) {
+			return fmt.Errorf("%w: column %q is mapped to unknown field %q", ErrInvalidOptions, column, field)
+		}
+	}
+
+	if o.Location == nil {
+		o.Location = time.UTC
+	}
+
+	switch {
+	case o.BatchSize == 0:
+		o.BatchSize = _defaultBatchSize
+	case o.BatchSize < 0 || o.BatchSize > _maxBatchSize:
+		return fmt.Errorf("%w: batch size must be between 1 and %d", ErrInvalidOptions, _maxBatchSize)
+	}
+
+	return nil
+}
+
+// RowError explains why a row was not imported. Rows are numbered by the line
+// they start on, counting a CSV header as line 1; row 0 stands for the input
+// as a whole.
+type RowError struct {
+	Row    int    `json:"row"`
+	Column string `json:"column,omitempty"`
+	Error  string `json:"error"`
+}
+
+// Report summarizes an import
+type Report struct {
+	DryRun bool `json:"dryRun"`
+
+	// Rows counts the rows read
+	Rows int `json:"rows"`
+
+	// Valid counts the rows that passed validation
+	Valid int `json:"valid"`
+
+	// Imported counts the tickets stored, which is zero for a dry run
+	Imported int `json:"imported"`
+
+	// Failed counts the rows that were not imported
+	Failed int `json:"failed"`
+
+	// Ignored lists the source columns that are not read into any field
+	Ignored []string `json:"ignored,omitempty"`
+
+	Errors []RowError `json:"errors"`
+}
+
+// Importer stores imported tickets
+type Importer struct {
+	tickets *storage.TicketStore
+	fields  *storage.FieldStore
+	log     *zap.Logger
+}
+
+// NewImporter creates a new Importer storing tickets in tickets. Custom fields
+// are checked against the schemas in fields.
+func NewImporter(tickets *storage.TicketStore, fields *storage.FieldStore, logger *zap.Logger) *Importer {
+	return &Importer{
+		tickets: tickets,
+		fields:  fields,
+		log:     logger.Named("importer"),
+	}
+}
+
+// pending is a valid ticket waiting to be inserted
+type pending struct {
+	row    int
+	ticket models.Ticket
+}
+
+// Import reads tickets from r and stores them in batches. Rows that fail
+// validation or whose batch fails to insert are listed in the report, as is
+// a failure part way through reading. An error is only returned when the
+// options are invalid or the input cannot be read at all.
+func (i *Importer) Import(ctx context.Context, r io.Reader, opts Options) (*Report, error) {
+	var sugar = i.log.Sugar()
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	var (
+		report  = &Report{DryRun: opts.DryRun, Errors: []RowError{}}
+		batch   = make([]pending, 0, opts.BatchSize)
+		schemas = make(map[string]*models.FieldSchema)
+	)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		if opts.DryRun {
+			batch = batch[:0]
+			return
+		}
+
+		tickets := make([]models.Ticket, len(batch))
+		for j, p := range batch {
+			tickets[j] = p.ticket
+		}
+
+		results, err := i.tickets.ImportTickets(ctx, tickets)
+		if err != nil {
+			for _, p := range batch {
+				report.fail(p.row, "", fmt.Errorf("failed to store ticket: %w", err))
+			}
+		}
+
+		for j, result := range results {
+			if result.Err != nil {
+				report.fail(batch[j].row, "", fmt.Errorf("failed to store ticket: %w", result.Err))
+				continue
+			}
+
+			report.Imported++
+		}
+
+		batch = batch[:0]
+	}
+
+	rows, err := newRowReader(r, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	ignored := make(map[string]bool)
+
+	for {
+		row, err := rows.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		// Input that cannot be read, or running out of time, ends the import.
+		// The rows stored so far are kept and reported.
+		if err == nil {
+			err = ctx.Err()
+		}
+
+		if err != nil {
+			report.Errors = append(report.Errors, RowError{Error: fmt.Sprintf("stopped reading: %s", err)})
+			break
+		}
+
+		report.Rows++
+
+		if row.err != nil {
+			report.fail(row.line, "", row.err)
+			continue
+		}
+
+		for column := range row.values {
+			if _, mapped := opts.Mapping[column]; !mapped && !isField(column) {
+				ignored[column] = true
+			}
+		}
+
+		ticket, column, err := i.parse(ctx, row.values, opts, schemas)
+		if err != nil {
+			report.fail(row.line, column, err)
+			continue
+		}
+
+		report.Valid++
+		batch = append(batch, pending{row: row.line, ticket: ticket})
+
+		if len(batch) == opts.BatchSize {
+			flush()
+		}
+	}
+
+	flush()
+
+	report.Ignored = slices.Sorted(maps.Keys(ignored))
+
+	sugar.Infow("imported tickets", "rows", report.Rows, "imported", report.Imported, "failed", report.Failed, "dryRun", opts.DryRun)
+
+	return report, nil
+}
+
+// fail records that row was not imported
+func (r *Report) fail(row int, column string, err error) {
+	r.Failed++
+	r.Errors = append(r.Errors, RowError{Row: row, Column: column, Error: err.Error()})
+}
+
+// parse turns the values of a row into a ticket, returning the column at
+// fault when it is invalid
+func (i *Importer) parse(ctx context.Context, values map[string]any, opts Options, schemas map[string]*models.FieldSchema) (models.Ticket, string, error) {
+	var ticket = models.Ticket{Status: models.StatusOpen}
+
+	for _, column := range slices.Sorted(maps.Keys(values)) {
+		value := values[column]
+		field := fieldFor(column, opts.Mapping)
+		if field == _skip || isBlank(value) {
+			continue
+		}
+
+		if err := setField(&ticket, field, value, opts.Location); err != nil {
+			return ticket, column, err
+		}
+	}
+
+	if strings.TrimSpace(ticket.Title) == "" {
+		return ticket, columnFor("title", opts.Mapping), errors.New("title is required")
+	}
+
+	if !ticket.UpdatedAt.IsZero() && ticket.UpdatedAt.Before(ticket.CreatedOn) {
+		return ticket, columnFor("updatedAt", opts.Mapping), errors.New("ticket was updated before it was created")
+	}
+
+	if !ticket.ClosedOn.IsZero() && ticket.ClosedOn.Before(ticket.CreatedOn) {
+		return ticket, columnFor("closedOn", opts.Mapping), errors.New("ticket was closed before it was created")
+	}
+
+	// Schemas are looked up once per category for the whole import
+	schema, ok := schemas[ticket.Category]
+	if !ok && ticket.Category != "" {
+		found, err := i.fields.FindSchema(ctx, ticket.Category)
+		if err != nil && !errors.Is(err, storage.ErrSchemaNotFound) {
+			return ticket, "", err
+		}
+
+		schema = found
+		schemas[ticket.Category] = schema
+	}
+
+	if err := convertCustomFields(schema, ticket.CustomFields); err != nil {
+		return ticket, "", err
+	}
+
+	present := make([]string, 0, len(ticket.CustomFields))
+	for key := range ticket.CustomFields {
+		present = append(present, key)
+	}
+
+	normalized, err := models.ValidateCustomFields(schema, ticket.CustomFields, present)
+	if err != nil {
+		return ticket, "", err
+	}
+
+	ticket.CustomFields = normalized
+
+	return ticket, "", nil
+}
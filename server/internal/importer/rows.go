@@ -0,0 +1,131 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrInvalidInput = errors.New("invalid import input")
+
+	// _maxLineSize caps the length of an NDJSON line
+	_maxLineSize = 16 << 20
+)
+
+// row is a row of an import, identified by the line it starts on. err
+// explains why the values of the row could not be read.
+type row struct {
+	line   int
+	values map[string]any
+	err    error
+}
+
+// rowReader reads the rows of an import one at a time. next returns io.EOF
+// once every row has been read, and any other error when the rest of the
+// input cannot be read.
+type rowReader interface {
+	next() (row, error)
+}
+
+// newRowReader returns a reader of rows in format from r
+func newRowReader(r io.Reader, format string) (rowReader, error) {
+	if format == FormatNDJSON {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), _maxLineSize)
+
+		return &ndjsonRows{scanner: scanner}, nil
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read CSV header: %w", ErrInvalidInput, err)
+	}
+
+	// Spreadsheets often start UTF-8 files with a byte order mark
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	for i, column := range header {
+		header[i] = strings.TrimSpace(column)
+
+		if slices.Contains(header[:i], header[i]) {
+			return nil, fmt.Errorf("%w: duplicate CSV column %q", ErrInvalidInput, header[i])
+		}
+	}
+
+	return &csvRows{reader: reader, header: header}, nil
+}
+
+// csvRows reads rows from CSV with a header row naming the columns
+type csvRows struct {
+	reader *csv.Reader
+	header []string
+}
+
+func (c *csvRows) next() (row, error) {
+	record, err := c.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+
+		if errors.As(err, &parseErr) {
+			return row{line: parseErr.StartLine, err: parseErr.Err}, nil
+		}
+
+		return row{}, err
+	}
+
+	line, _ := c.reader.FieldPos(0)
+
+	if len(record) != len(c.header) {
+		return row{line: line, err: fmt.Errorf("expected %d columns, got %d", len(c.header), len(record))}, nil
+	}
+
+	values := make(map[string]any, len(record))
+
+	for i, value := range record {
+		values[c.header[i]] = value
+	}
+
+	return row{line: line, values: values}, nil
+}
+
+// ndjsonRows reads rows from newline-delimited JSON objects. Blank lines are
+// skipped.
+type ndjsonRows struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (n *ndjsonRows) next() (row, error) {
+	for n.scanner.Scan() {
+		n.line++
+
+		text := n.scanner.Bytes()
+		if len(bytes.TrimSpace(text)) == 0 {
+			continue
+		}
+
+		var values map[string]any
+
+		if err := json.Unmarshal(text, &values); err != nil {
+			return row{line: n.line, err: fmt.Errorf("expected a JSON object: %w", err)}, nil
+		}
+
+		return row{line: n.line, values: values}, nil
+	}
+
+	if err := n.scanner.Err(); err != nil {
+		return row{}, err
+	}
+
+	return row{}, io.EOF
+}
@@ -0,0 +1,35 @@
+// Package logging carries the logger of a request in its context, so every
+// line logged while serving the request holds its request and trace IDs.
+package logging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx carrying logger, which should be derived
+// from the server's logger with the fields of a request
+func NewContext(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns logger with the fields of the logger carried by ctx,
+// keeping the name of logger, e.g. handler.tickets. Both must share the
+// server's core; fields added to logger itself are not kept. It returns logger
+// as is when ctx carries none.
+func FromContext(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	carried, ok := ctx.Value(loggerKey{}).(*zap.Logger)
+	if !ok {
+		return logger
+	}
+
+	// Fields added with With are held by the core, while names are held by
+	// the logger, so swapping cores keeps both
+	return logger.WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core {
+		return carried.Core()
+	}))
+}
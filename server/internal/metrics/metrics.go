@@ -0,0 +1,96 @@
+// Package metrics serves metrics in the Prometheus exposition format with the
+// Prometheus client.
+//
+// Metrics are registered with Registry when they are created and served by
+// Handler, along with the metrics of the Go runtime and the process.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// _collectTimeout is how long gauges computed on scrape may take
+var _collectTimeout = 5 * time.Second
+
+var (
+	// Registry holds every metric served by Handler
+	Registry = prometheus.NewRegistry()
+
+	// Factory creates metrics registered with Registry
+	Factory = promauto.With(Registry)
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler returns a handler serving every metric of Registry. A gauge failing
+// to be computed is left out.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
+
+// Sample is the value of a gauge with label values
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// GaugeFunc is a gauge split by labels whose values are computed whenever
+// metrics are scraped
+type GaugeFunc struct {
+	desc *prometheus.Desc
+	fn   func(ctx context.Context) ([]Sample, error)
+}
+
+// NewGaugeFunc creates a gauge split by labels whose values are returned by
+// fn whenever metrics are scraped, and registers it with Registry. The gauge
+// is left out of a scrape when fn fails.
+func NewGaugeFunc(name, help string, labels []string, fn func(ctx context.Context) ([]Sample, error)) *GaugeFunc {
+	g := &GaugeFunc{
+		desc: prometheus.NewDesc(name, help, labels, nil),
+		fn:   fn,
+	}
+
+	Registry.MustRegister(g)
+
+	return g
+}
+
+// Describe implements prometheus.Collector
+func (g *GaugeFunc) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.desc
+}
+
+// Collect implements prometheus.Collector. Samples with the wrong number of
+// label values are reported as invalid, which fails the scrape of the gauge
+// rather than the server.
+func (g *GaugeFunc) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), _collectTimeout)
+	defer cancel()
+
+	samples, err := g.fn(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(g.desc, err)
+		return
+	}
+
+	for _, sample := range samples {
+		metric, err := prometheus.NewConstMetric(g.desc, prometheus.GaugeValue, sample.Value, sample.Labels...)
+		if err != nil {
+			metric = prometheus.NewInvalidMetric(g.desc, err)
+		}
+
+		ch <- metric
+	}
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	gauges := []*GaugeFunc{
+		NewGaugeFunc("test_gauge", "A gauge.", []string{"site"}, func(ctx context.Context) ([]Sample, error) {
+			return []Sample{{Labels: []string{"Watsonville"}, Value: 3}}, nil
+		}),
+		NewGaugeFunc("test_mismatched_gauge", "A gauge with the wrong number of label values.", []string{"site", "status"}, func(ctx context.Context) ([]Sample, error) {
+			return []Sample{{Labels: []string{"Watsonville"}, Value: 1}}, nil
+		}),
+		NewGaugeFunc("test_failing_gauge", "A gauge failing to be computed.", nil, func(ctx context.Context) ([]Sample, error) {
+			return nil, errors.New("no database")
+		}),
+	}
+
+	// The gauges are registered with the package registry, so they are
+	// removed again for the test to be run more than once
+	t.Cleanup(func() {
+		for _, g := range gauges {
+			Registry.Unregister(g)
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, want := range []string{`test_gauge{site="Watsonville"} 3`, "go_goroutines ", "process_start_time_seconds "} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics do not include %q", want)
+		}
+	}
+
+	for _, unwanted := range []string{"test_mismatched_gauge{", "test_failing_gauge "} {
+		if strings.Contains(string(body), unwanted) {
+			t.Errorf("metrics include %q", unwanted)
+		}
+	}
+}
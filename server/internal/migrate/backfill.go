@@ -0,0 +1,107 @@
+package migrate
+
+import (
+	"context"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// _defaultBatchSize is how many documents a backfill updates at once by
+// default
+const _defaultBatchSize = 1000
+
+// Backfill updates every document of a collection matching a filter, a batch
+// at a time, so a large collection is never held up by a single write and an
+// interrupted backfill resumes where it stopped
+type Backfill struct {
+	Collection string
+	Filter     bson.D
+
+	// Update is an update document or pipeline, e.g. mongo.Pipeline to set a
+	// field from another
+	Update any
+
+	// BatchSize is how many documents are updated at once. Defaults to 1000.
+	BatchSize int
+}
+
+// Up runs the backfill. It can be used as the Up function of a migration.
+func (b Backfill) Up(ctx context.Context, db *mongo.Database) error {
+	var (
+		collection = db.Collection(b.Collection)
+		batchSize  = b.BatchSize
+		last       any
+	)
+
+	if batchSize <= 0 {
+		batchSize = _defaultBatchSize
+	}
+
+	// Documents are walked in _id order, so each batch starts after the last
+	// even when the update leaves documents matching the filter
+	for {
+		filter := slices.Clone(b.Filter)
+
+		if last != nil {
+			filter = append(filter, bson.E{Key: "_id", Value: bson.D{{Key: "$gt", Value: last}}})
+		}
+
+		opts := options.Find().
+			SetProjection(bson.D{{Key: "_id", Value: 1}}).
+			SetSort(bson.D{{Key: "_id", Value: 1}}).
+			SetLimit(int64(batchSize))
+
+		cursor, err := collection.Find(ctx, filter, opts)
+		if err != nil {
+			return err
+		}
+
+		var docs []struct {
+			ID any `bson:"_id"`
+		}
+
+		if err := cursor.All(ctx, &docs); err != nil {
+			return err
+		}
+
+		if len(docs) == 0 {
+			return nil
+		}
+
+		ids := make(bson.A, 0, len(docs))
+
+		for _, doc := range docs {
+			ids = append(ids, doc.ID)
+		}
+
+		// The filter is applied again in case a document changed since
+		// it was read
+		batch := append(slices.Clone(b.Filter), bson.E{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}})
+
+		if _, err := collection.UpdateMany(ctx, batch, b.Update); err != nil {
+			return err
+		}
+
+		if len(docs) < batchSize {
+			return nil
+		}
+
+		last = ids[len(ids)-1]
+	}
+}
+
+// steps returns a migration applying each of ups in turn
+func steps(ups ...func(ctx context.Context, db *mongo.Database) error) func(ctx context.Context, db *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		for _, up := range ups {
+			if err := up(ctx, db); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,146 @@
+package migrate
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// _lockCollection holds the locks taken by processes sharing the database
+const _lockCollection = "locks"
+
+// _lockID identifies the lock held while migrating
+const _lockID = "migrations"
+
+var (
+	// _lockTTL is how long a lock is held without being renewed, which frees
+	// the lock of a process that died holding it
+	_lockTTL = time.Minute
+
+	// _lockRetry is how often a held lock is tried again
+	_lockRetry = 2 * time.Second
+)
+
+// lock takes the migration lock, waiting until it is free or ctx is done,
+// so only one process migrates at a time. The lock is renewed until unlock
+// is called.
+func (m *Migrator) lock(ctx context.Context) (unlock func(), err error) {
+	var (
+		sugar      = m.log.Sugar()
+		collection = m.db.Collection(_lockCollection)
+		owner      = lockOwner()
+		waiting    = false
+	)
+
+	for {
+		acquired, err := tryLock(ctx, collection, owner)
+		if err != nil {
+			return nil, err
+		}
+
+		if acquired {
+			break
+		}
+
+		if !waiting {
+			sugar.Infow("waiting for another process to finish migrating")
+			waiting = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(_lockRetry):
+		}
+	}
+
+	renewCtx, stopRenewing := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(_lockTTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-renewCtx.Done():
+				return
+			case <-ticker.C:
+				filter := bson.D{{Key: "_id", Value: _lockID}, {Key: "owner", Value: owner}}
+				update := bson.D{{Key: "$set", Value: bson.D{{Key: "expiresAt", Value: time.Now().Add(_lockTTL)}}}}
+
+				if _, err := collection.UpdateOne(renewCtx, filter, update); err != nil && renewCtx.Err() == nil {
+					sugar.Errorw("failed to renew migration lock", "error", err)
+				}
+			}
+		}
+	}()
+
+	unlock = func() {
+		stopRenewing()
+		<-done
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if _, err := collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: _lockID}, {Key: "owner", Value: owner}}); err != nil {
+			sugar.Errorw("failed to release migration lock", "error", err)
+		}
+	}
+
+	return unlock, nil
+}
+
+// tryLock takes the lock for owner if it is free or has expired, and
+// reports whether it did
+func tryLock(ctx context.Context, collection *mongo.Collection, owner string) (bool, error) {
+	var (
+		now    = time.Now()
+		filter = bson.D{
+			{Key: "_id", Value: _lockID},
+			{Key: "expiresAt", Value: bson.D{{Key: "$lt", Value: now}}},
+		}
+		update = bson.D{{Key: "$set", Value: bson.D{
+			{Key: "owner", Value: owner},
+			{Key: "lockedOn", Value: now},
+			{Key: "expiresAt", Value: now.Add(_lockTTL)},
+		}}}
+	)
+
+	// A lock held by another process does not match the filter, so the
+	// upsert inserts a second document with the same ID, which fails
+	_, err := collection.UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+
+	switch {
+	case err == nil:
+		return true, nil
+	case mongo.IsDuplicateKeyError(err):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// lockOwner returns a name identifying this process among every process
+// sharing the database
+func lockOwner() string {
+	var suffix = make([]byte, 4)
+
+	_, _ = rand.Read(suffix)
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	return fmt.Sprintf("%s/%d/%s", host, os.Getpid(), hex.EncodeToString(suffix))
+}
@@ -0,0 +1,193 @@
+// Package migrate applies versioned changes to the documents of the ticket
+// database, recording the migrations applied in their own collection.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+// Collection is the collection recording the migrations applied
+const Collection = "migrations"
+
+var (
+	ErrUnknownVersion = errors.New("unknown migration version")
+)
+
+// Migration is a change to the database, applied once
+type Migration struct {
+	// Version orders migrations; each has its own
+	Version int
+	Name    string
+
+	// Up applies the migration. It may be run again after failing part way,
+	// so it must skip documents already migrated.
+	Up func(ctx context.Context, db *mongo.Database) error
+}
+
+// Status tells whether a migration was applied
+type Status struct {
+	Version   int       `json:"version"`
+	Name      string    `json:"name"`
+	Applied   bool      `json:"applied"`
+	AppliedOn time.Time `json:"appliedOn,omitzero"`
+}
+
+// applied is the record of an applied migration
+type applied struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedOn time.Time `bson:"appliedOn"`
+}
+
+// Migrator applies migrations to the ticket database
+type Migrator struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+	migrations []Migration
+	log        *zap.Logger
+}
+
+// NewMigrator creates a new Migrator applying Migrations with a Mongo DB
+// client config and logger
+func NewMigrator(client *mongo.Client, logger *zap.Logger) *Migrator {
+	db := storage.Database(client)
+
+	return &Migrator{
+		db:         db,
+		collection: db.Collection(Collection),
+		migrations: Migrations,
+		log:        logger.Named("migrate"),
+	}
+}
+
+// Status returns the status of every migration, in order
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+
+	for _, migration := range m.migrations {
+		record, ok := done[migration.Version]
+
+		statuses = append(statuses, Status{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedOn: record.AppliedOn,
+		})
+	}
+
+	return statuses, nil
+}
+
+// Pending returns the migrations not yet applied, in order
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+
+	for _, migration := range m.migrations {
+		if _, ok := done[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Migrate applies the pending migrations up to version target in order,
+// returning the migrations applied. A target of zero applies every pending
+// migration. It waits for any other process applying migrations to finish
+// first.
+func (m *Migrator) Migrate(ctx context.Context, target int) ([]Migration, error) {
+	var (
+		sugar = m.log.Sugar()
+		ran   []Migration
+	)
+
+	if target != 0 && !slices.ContainsFunc(m.migrations, func(migration Migration) bool { return migration.Version == target }) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+
+	// Processes sharing the database take turns, so each migration is
+	// applied once even when several servers start together
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer unlock()
+
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, migration := range m.migrations {
+		if target != 0 && migration.Version > target {
+			break
+		}
+
+		if _, ok := done[migration.Version]; ok {
+			continue
+		}
+
+		sugar.Infow("applying migration", "version", migration.Version, "name", migration.Name)
+
+		start := time.Now()
+
+		if err := migration.Up(ctx, m.db); err != nil {
+			return ran, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+
+		record := applied{Version: migration.Version, Name: migration.Name, AppliedOn: time.Now()}
+
+		if _, err := m.collection.InsertOne(ctx, record); err != nil {
+			return ran, err
+		}
+
+		sugar.Infow("applied migration", "version", migration.Version, "duration", time.Since(start))
+
+		ran = append(ran, migration)
+	}
+
+	return ran, nil
+}
+
+// applied returns the records of the applied migrations by version
+func (m *Migrator) applied(ctx context.Context) (map[int]applied, error) {
+	var records []applied
+
+	cursor, err := m.collection.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	done := make(map[int]applied, len(records))
+
+	for _, record := range records {
+		done[record.Version] = record
+	}
+
+	return done, nil
+}
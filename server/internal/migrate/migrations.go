@@ -0,0 +1,63 @@
+package migrate
+
+import (
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"github.com/digitalnest-wit/nestqueue/internal/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Migrations lists every migration in the order they are applied. New
+// migrations are appended with the next version; applied migrations must
+// never change. A migration changing the layout of tickets also raises
+// models.TicketSchemaVersion and sets it on the tickets it migrates.
+var Migrations = []Migration{
+	{
+		// Tickets stored before tags, custom fields and update times existed
+		// get their empty values
+		Version: 1,
+		Name:    "backfill ticket defaults",
+		Up: steps(
+			Backfill{
+				Collection: storage.TicketsCollection,
+				Filter:     bson.D{{Key: "tags", Value: nil}},
+				Update:     bson.D{{Key: "$set", Value: bson.D{{Key: "tags", Value: bson.A{}}}}},
+			}.Up,
+			Backfill{
+				Collection: storage.TicketsCollection,
+				Filter:     bson.D{{Key: "customFields", Value: nil}},
+				Update:     bson.D{{Key: "$set", Value: bson.D{{Key: "customFields", Value: bson.D{}}}}},
+			}.Up,
+			Backfill{
+				Collection: storage.TicketsCollection,
+				Filter:     bson.D{{Key: "updatedAt", Value: nil}},
+				Update:     mongo.Pipeline{{{Key: "$set", Value: bson.D{{Key: "updatedAt", Value: "$createdOn"}}}}},
+			}.Up,
+		),
+	},
+	{
+		// Resolved tickets stored before closing times were recorded take
+		// their last update time as closing time
+		Version: 2,
+		Name:    "backfill closedOn of resolved tickets",
+		Up: Backfill{
+			Collection: storage.TicketsCollection,
+			Filter: bson.D{
+				{Key: "status", Value: bson.D{{Key: "$in", Value: models.ResolvedStatuses}}},
+				{Key: "closedOn", Value: nil},
+			},
+			Update: mongo.Pipeline{{{Key: "$set", Value: bson.D{{Key: "closedOn", Value: "$updatedAt"}}}}},
+		}.Up,
+	},
+	{
+		// Tickets stored before schema versions existed have the first
+		// layout
+		Version: 3,
+		Name:    "stamp tickets with their schema version",
+		Up: Backfill{
+			Collection: storage.TicketsCollection,
+			Filter:     bson.D{{Key: "schemaVersion", Value: nil}},
+			Update:     bson.D{{Key: "$set", Value: bson.D{{Key: "schemaVersion", Value: 1}}}},
+		}.Up,
+	},
+}
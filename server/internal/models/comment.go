@@ -0,0 +1,33 @@
+package models
+
+import (
+	"bytes"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Comment is a note left on a ticket, e.g. what was tried while working it
+type Comment struct {
+	ID        string    `json:"id" bson:"_id"`
+	Ticket    string    `json:"ticket"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedOn time.Time `json:"createdOn"`
+}
+
+// UnmarshalBSON provides a custom unmarshal implementation for Comment,
+// enabling the decoder to implicitly decode ObjectIDs, including the ID of
+// the ticket, as Hex strings.
+func (c *Comment) UnmarshalBSON(data []byte) error {
+	// comment has the same fields as Comment but none of its methods, so
+	// decoding into it does not recurse back into UnmarshalBSON
+	type comment Comment
+
+	decoder := bson.NewDecoder(bson.NewDocumentReader(bytes.NewReader(data)))
+	decoder.ObjectIDAsHexString()
+
+	*c = Comment{}
+
+	return decoder.Decode((*comment)(c))
+}
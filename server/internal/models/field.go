@@ -0,0 +1,160 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// Custom field types
+const (
+	FieldText   = "text"
+	FieldNumber = "number"
+	FieldEnum   = "enum"
+	FieldDate   = "date"
+	FieldUser   = "user"
+)
+
+var _fieldKey = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// FieldSchema lists the custom fields tickets of a category carry, such as an
+// asset tag for Hardware tickets. Values are stored in Ticket.CustomFields
+// under each field's key.
+type FieldSchema struct {
+	Category  string            `json:"category" bson:"_id"`
+	Fields    []FieldDefinition `json:"fields"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+// FieldDefinition describes a single custom field. Options lists the allowed
+// values of an enum field.
+type FieldDefinition struct {
+	Key      string   `json:"key"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options,omitempty"`
+}
+
+// IsValidFieldKey reports whether key can be used as a custom field key.
+// Keys are used in Mongo field paths and query parameters, so they are
+// limited to letters, digits and underscores.
+func IsValidFieldKey(key string) bool {
+	return _fieldKey.MatchString(key)
+}
+
+// IsValidFieldType reports whether t is a known custom field type
+func IsValidFieldType(t string) bool {
+	switch t {
+	case FieldText, FieldNumber, FieldEnum, FieldDate, FieldUser:
+		return true
+	default:
+		return false
+	}
+}
+
+// Field returns the definition of the field with key, if the schema has one
+func (s *FieldSchema) Field(key string) (FieldDefinition, bool) {
+	for _, field := range s.Fields {
+		if field.Key == key {
+			return field, true
+		}
+	}
+
+	return FieldDefinition{}, false
+}
+
+// ValidateCustomFields checks values against schema and returns them in the
+// form they are stored in: numbers as float64, dates as time.Time and users
+// as bare email addresses. A nil value removes a field and is kept as is.
+// Required fields are checked against present, which holds the keys of the
+// fields the ticket will carry once values are applied.
+func ValidateCustomFields(schema *FieldSchema, values map[string]any, present []string) (map[string]any, error) {
+	if schema == nil {
+		schema = &FieldSchema{}
+	}
+
+	normalized := make(map[string]any, len(values))
+
+	for key, value := range values {
+		field, ok := schema.Field(key)
+		if !ok {
+			return nil, fmt.Errorf("unknown custom field %q", key)
+		}
+
+		if value == nil {
+			normalized[key] = nil
+			continue
+		}
+
+		v, err := ValidateCustomField(field, value)
+		if err != nil {
+			return nil, fmt.Errorf("custom field %q: %w", key, err)
+		}
+
+		normalized[key] = v
+	}
+
+	for _, field := range schema.Fields {
+		if field.Required && !slices.Contains(present, field.Key) {
+			return nil, fmt.Errorf("custom field %q is required", field.Key)
+		}
+	}
+
+	return normalized, nil
+}
+
+// ValidateCustomField checks a single value decoded from JSON against field
+func ValidateCustomField(field FieldDefinition, value any) (any, error) {
+	switch field.Type {
+	case FieldNumber:
+		n, ok := value.(float64)
+		if !ok {
+			return nil, errors.New("expected a number")
+		}
+
+		return n, nil
+	case FieldDate:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("expected a date")
+		}
+
+		for _, layout := range []string{time.DateOnly, time.RFC3339} {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t.UTC(), nil
+			}
+		}
+
+		return nil, fmt.Errorf("expected a date like %s, got %q", time.DateOnly, s)
+	case FieldEnum:
+		s, ok := value.(string)
+		if !ok || !slices.Contains(field.Options, s) {
+			return nil, fmt.Errorf("expected one of %v", field.Options)
+		}
+
+		return s, nil
+	case FieldUser:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("expected an email address")
+		}
+
+		address, err := mail.ParseAddress(s)
+		if err != nil {
+			return nil, fmt.Errorf("expected an email address, got %q", s)
+		}
+
+		return address.Address, nil
+	default:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("expected text")
+		}
+
+		return s, nil
+	}
+}
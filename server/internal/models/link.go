@@ -0,0 +1,60 @@
+package models
+
+import (
+	"bytes"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Link types. Links are directional and read from Source to Target: the
+// source is the parent of, a duplicate of, blocks, or is related to the
+// target. Related links apply in both directions.
+const (
+	LinkParent    = "parent"
+	LinkDuplicate = "duplicate-of"
+	LinkBlocks    = "blocks"
+	LinkRelated   = "related"
+)
+
+// Link is a typed relationship between two tickets
+type Link struct {
+	ID        string    `json:"id" bson:"_id"`
+	Type      string    `json:"type"`
+	Source    string    `json:"source"`
+	Target    string    `json:"target"`
+	CreatedOn time.Time `json:"createdOn"`
+}
+
+// UnmarshalBSON provides a custom unmarshal implementation for Link, enabling
+// the decoder to implicitly decode ObjectIDs, including the linked ticket
+// IDs, as Hex strings.
+func (l *Link) UnmarshalBSON(data []byte) error {
+	// link has the same fields as Link but none of its methods, so decoding
+	// into it does not recurse back into UnmarshalBSON
+	type link Link
+
+	var buffer bytes.Buffer
+	_, _ = buffer.Write(data)
+
+	decoder := bson.NewDecoder(bson.NewDocumentReader(&buffer))
+	decoder.ObjectIDAsHexString()
+
+	return decoder.Decode((*link)(l))
+}
+
+// IsValidLinkType reports whether t is a known link type
+func IsValidLinkType(t string) bool {
+	switch t {
+	case LinkParent, LinkDuplicate, LinkBlocks, LinkRelated:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsAcyclicLinkType reports whether links of type t must not form cycles,
+// e.g. a ticket cannot transitively block itself
+func IsAcyclicLinkType(t string) bool {
+	return t == LinkParent || t == LinkDuplicate || t == LinkBlocks
+}
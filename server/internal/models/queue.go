@@ -0,0 +1,122 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Routing strategies used by a Queue to pick an assignee for a new ticket
+const (
+	StrategyRoundRobin = "round-robin"
+	StrategyLeastOpen  = "least-open"
+)
+
+// Queue groups the technicians responsible for tickets matching its sites and
+// categories. An empty Sites or Categories list matches any value.
+type Queue struct {
+	ID         string        `json:"id" bson:"_id"`
+	Name       string        `json:"name"`
+	Sites      []string      `json:"sites"`
+	Categories []string      `json:"categories"`
+	Strategy   string        `json:"strategy"`
+	Members    []QueueMember `json:"members"`
+	Cursor     int           `json:"-"`
+	CreatedOn  time.Time     `json:"createdOn"`
+	UpdatedAt  time.Time     `json:"updatedAt"`
+}
+
+// QueueMember is a technician belonging to a Queue. Members that are not
+// available are skipped when routing tickets.
+type QueueMember struct {
+	Email     string `json:"email"`
+	Available bool   `json:"available"`
+}
+
+// UnmarshalJSON decodes a QueueMember, which is available unless the JSON
+// says otherwise, whether it is added to a queue or created with it
+func (m *QueueMember) UnmarshalJSON(data []byte) error {
+	// member has the same fields as QueueMember but none of its methods, so
+	// decoding into it does not recurse back into UnmarshalJSON
+	type member QueueMember
+
+	decoded := member{Available: true}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&decoded); err != nil {
+		return err
+	}
+
+	*m = QueueMember(decoded)
+
+	return nil
+}
+
+// UnmarshalBSON provides a custom unmarshal implementation for Queue, enabling
+// the decoder to implicitly decode ObjectIDs as Hex strings.
+func (q *Queue) UnmarshalBSON(data []byte) error {
+	// queue has the same fields as Queue but none of its methods, so decoding
+	// into it does not recurse back into UnmarshalBSON
+	type queue Queue
+
+	var buffer bytes.Buffer
+	_, _ = buffer.Write(data)
+
+	decoder := bson.NewDecoder(bson.NewDocumentReader(&buffer))
+	decoder.ObjectIDAsHexString()
+
+	return decoder.Decode((*queue)(q))
+}
+
+// IsValidStrategy reports whether strategy is a known routing strategy
+func IsValidStrategy(strategy string) bool {
+	return strategy == StrategyRoundRobin || strategy == StrategyLeastOpen
+}
+
+// Matches reports whether ticket falls under this queue's sites and categories
+func (q *Queue) Matches(ticket Ticket) bool {
+	if len(q.Sites) > 0 && !slices.Contains(q.Sites, ticket.Site) {
+		return false
+	}
+
+	if len(q.Categories) > 0 && !slices.Contains(q.Categories, ticket.Category) {
+		return false
+	}
+
+	return true
+}
+
+// Specificity counts the criteria a queue constrains. When several queues
+// match a ticket, the most specific one is preferred.
+func (q *Queue) Specificity() int {
+	var n int
+
+	if len(q.Sites) > 0 {
+		n++
+	}
+
+	if len(q.Categories) > 0 {
+		n++
+	}
+
+	return n
+}
+
+// AvailableMembers returns the members of q currently accepting tickets, in
+// the order they were added
+func (q *Queue) AvailableMembers() []QueueMember {
+	var available []QueueMember
+
+	for _, member := range q.Members {
+		if member.Available {
+			available = append(available, member)
+		}
+	}
+
+	return available
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"bytes"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Ticket events a Rule can be evaluated on
+const (
+	EventCreate = "create"
+	EventUpdate = "update"
+)
+
+// How a Rule combines its conditions
+const (
+	MatchAll = "all"
+	MatchAny = "any"
+)
+
+// Condition operators
+const (
+	OpEquals    = "equals"
+	OpNotEquals = "notEquals"
+	OpContains  = "contains"
+	OpMatches   = "matches"
+	OpIn        = "in"
+	OpGreater   = "gt"
+	OpGreaterEq = "gte"
+	OpLess      = "lt"
+	OpLessEq    = "lte"
+)
+
+// Action types
+const (
+	ActionSetField = "setField"
+	ActionAssign   = "assign"
+	ActionAddTag   = "addTag"
+	ActionNotify   = "notify"
+	ActionWebhook  = "webhook"
+)
+
+// Rule is a triage automation evaluated when tickets are created or updated.
+// When its conditions hold, its actions are applied in order.
+type Rule struct {
+	ID         string      `json:"id" bson:"_id"`
+	Name       string      `json:"name"`
+	Enabled    bool        `json:"enabled"`
+	Order      int         `json:"order"`
+	Events     []string    `json:"events"`
+	Match      string      `json:"match"`
+	Conditions []Condition `json:"conditions"`
+	Actions    []Action    `json:"actions"`
+	CreatedOn  time.Time   `json:"createdOn"`
+	UpdatedAt  time.Time   `json:"updatedAt"`
+}
+
+// Condition compares a ticket field, named as in the ticket's JSON encoding,
+// against Value using Operator. The matches operator treats Value as a regular
+// expression.
+type Condition struct {
+	Field    string `json:"field"`
+	Operator string `json:"operator"`
+	Value    any    `json:"value"`
+}
+
+// Action is a change or side effect performed by a Rule. Value holds the field
+// value for setField, the assignee for assign, the tag for addTag and the
+// recipient for notify. URL is the endpoint called by webhook.
+type Action struct {
+	Type    string `json:"type"`
+	Field   string `json:"field,omitempty"`
+	Value   any    `json:"value,omitempty"`
+	Message string `json:"message,omitempty"`
+	URL     string `json:"url,omitempty"`
+}
+
+// UnmarshalBSON provides a custom unmarshal implementation for Rule, enabling
+// the decoder to implicitly decode ObjectIDs as Hex strings.
+func (r *Rule) UnmarshalBSON(data []byte) error {
+	// rule has the same fields as Rule but none of its methods, so decoding
+	// into it does not recurse back into UnmarshalBSON
+	type rule Rule
+
+	var buffer bytes.Buffer
+	_, _ = buffer.Write(data)
+
+	decoder := bson.NewDecoder(bson.NewDocumentReader(&buffer))
+	decoder.ObjectIDAsHexString()
+
+	return decoder.Decode((*rule)(r))
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+var _tagColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Tag is a label that can be attached to tickets, such as "zoom" or
+// "printer". Tags are registered with a display color; Count is the number of
+// tickets currently carrying the tag and is computed when tags are listed.
+type Tag struct {
+	Name       string `json:"name" bson:"_id"`
+	Color      string `json:"color"`
+	Registered bool   `json:"registered" bson:"-"`
+	Count      int    `json:"count" bson:"-"`
+}
+
+// NormalizeTag trims and lowercases tag so that "Zoom " and "zoom" are the
+// same label
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// NormalizeTags normalizes every tag in tags, dropping empty and duplicate
+// tags. The result is never nil.
+func NormalizeTags(tags []string) []string {
+	var (
+		result = make([]string, 0, len(tags))
+		seen   = make(map[string]bool, len(tags))
+	)
+
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		result = append(result, tag)
+	}
+
+	return result
+}
+
+// IsValidTagColor reports whether color is a hex color such as "#1e90ff"
+func IsValidTagColor(color string) bool {
+	return _tagColor.MatchString(color)
+}
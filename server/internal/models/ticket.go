@@ -2,67 +2,71 @@ package models
 
 import (
 	"bytes"
+	"slices"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+// Ticket statuses. Active and Open tickets still need work; Closed and
+// Rejected tickets are resolved.
+const (
+	StatusActive   = "Active"
+	StatusOpen     = "Open"
+	StatusClosed   = "Closed"
+	StatusRejected = "Rejected"
+)
+
+// TicketSchemaVersion is the version of the layout of stored tickets. Every
+// ticket is stored with it as schemaVersion; migrations changing the layout
+// raise it and bring older tickets up to date.
+const TicketSchemaVersion = 1
+
+var (
+	// OpenStatuses lists the statuses of tickets that still need work
+	OpenStatuses = []string{StatusActive, StatusOpen}
+
+	// ResolvedStatuses lists the statuses of tickets that no longer need work
+	ResolvedStatuses = []string{StatusClosed, StatusRejected}
+)
+
 // Ticket represents an IT ticket with associated metadata
 type Ticket struct {
-	ID          string    `json:"id" bson:"_id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Site        string    `json:"site"`
-	Category    string    `json:"category"`
-	AssignedTo  string    `json:"assignedTo"`
-	CreatedBy   string    `json:"createdBy"`
-	Priority    int       `json:"priority"`
-	Status      string    `json:"status"`
-	CreatedOn   time.Time `json:"createdOn"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID           string         `json:"id" bson:"_id"`
+	Title        string         `json:"title"`
+	Description  string         `json:"description"`
+	Site         string         `json:"site"`
+	Category     string         `json:"category"`
+	AssignedTo   string         `json:"assignedTo"`
+	CreatedBy    string         `json:"createdBy"`
+	Priority     int            `json:"priority"`
+	Status       string         `json:"status"`
+	Tags         []string       `json:"tags"`
+	CustomFields map[string]any `json:"customFields,omitempty"`
+	MergedInto   string         `json:"mergedInto,omitempty"`
+	MergedFrom   []string       `json:"mergedFrom,omitempty"`
+	CreatedOn    time.Time      `json:"createdOn"`
+	UpdatedAt    time.Time      `json:"updatedAt"`
+	ClosedOn     time.Time      `json:"closedOn,omitzero"`
 }
 
 // UnmarshalBSON provides a custom unmarshal implementation for Ticket, enabling
 // the decoder to implicitly decode ObjectIDs as Hex strings.
 func (t *Ticket) UnmarshalBSON(data []byte) error {
-	var (
-		buffer bytes.Buffer
-		result struct {
-			ID          string    `json:"id" bson:"_id"`
-			Title       string    `json:"title"`
-			Description string    `json:"description"`
-			Site        string    `json:"site"`
-			Category    string    `json:"category"`
-			AssignedTo  string    `json:"assignedTo"`
-			CreatedBy   string    `json:"createdBy"`
-			Priority    int       `json:"priority"`
-			Status      string    `json:"status"`
-			CreatedOn   time.Time `json:"createdOn"`
-			UpdatedAt   time.Time `json:"updatedAt"`
-		}
-	)
-	_, _ = buffer.Write(data)
+	// ticket has the fields of Ticket but not its methods, so decoding into it
+	// does not call UnmarshalBSON again
+	type ticket Ticket
 
-	decoder := bson.NewDecoder(bson.NewDocumentReader(&buffer))
+	decoder := bson.NewDecoder(bson.NewDocumentReader(bytes.NewReader(data)))
 	decoder.ObjectIDAsHexString()
 
-	if err := decoder.Decode(&result); err != nil {
-		return err
-	}
+	*t = Ticket{}
 
-	*t = Ticket{
-		ID:          result.ID,
-		Title:       result.Title,
-		Description: result.Description,
-		Site:        result.Site,
-		Category:    result.Category,
-		AssignedTo:  result.AssignedTo,
-		CreatedBy:   result.CreatedBy,
-		Priority:    result.Priority,
-		Status:      result.Status,
-		CreatedOn:   result.CreatedOn,
-		UpdatedAt:   result.UpdatedAt,
-	}
+	return decoder.Decode((*ticket)(t))
+}
 
-	return nil
+// IsResolved reports whether status is the status of a ticket that no longer
+// needs work
+func IsResolved(status string) bool {
+	return slices.Contains(ResolvedStatuses, status)
 }
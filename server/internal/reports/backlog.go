@@ -0,0 +1,136 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Backlog is the number of open tickets of a group over time
+type Backlog struct {
+	Group  any            `json:"group"`
+	Points []BacklogPoint `json:"points"`
+}
+
+// BacklogPoint is the number of tickets open at a point in time
+type BacklogPoint struct {
+	At   time.Time `json:"at"`
+	Open int       `json:"open"`
+}
+
+// Backlog reports the number of open tickets at the start of every interval
+// between p.From and p.To, and at p.To. A ticket is open from its creation
+// until it is resolved.
+func (r *Reporter) Backlog(ctx context.Context, p Params) ([]Backlog, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	points := p.points()
+	if len(points) > _maxPoints {
+		return nil, fmt.Errorf("%w: more than %d points, use a longer interval or a shorter period", ErrInvalidParams, _maxPoints)
+	}
+
+	// Each point counts the tickets created by then and not yet resolved
+	group := bson.D{{Key: "_id", Value: p.group()}}
+
+	for i, at := range points {
+		open := bson.D{{Key: "$and", Value: bson.A{
+			bson.D{{Key: "$lte", Value: bson.A{"$createdOn", at}}},
+			bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "$eq", Value: bson.A{"$resolvedOn", nil}}},
+				bson.D{{Key: "$gt", Value: bson.A{"$resolvedOn", at}}},
+			}}},
+		}}}
+
+		group = append(group, bson.E{
+			Key:   pointKey(i),
+			Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{open, 1, 0}}}}},
+		})
+	}
+
+	pipeline := mongo.Pipeline{
+		p.match(),
+		{{Key: "$match", Value: bson.D{{Key: "createdOn", Value: bson.D{{Key: "$lte", Value: p.To}}}}}},
+		resolvedOnStage(),
+		{{Key: "$match", Value: bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "resolvedOn", Value: nil}},
+			bson.D{{Key: "resolvedOn", Value: bson.D{{Key: "$gt", Value: points[0]}}}},
+		}}}}},
+		{{Key: "$group", Value: group}},
+	}
+
+	var groups []bson.M
+
+	if err := r.aggregate(ctx, pipeline, &groups); err != nil {
+		return nil, err
+	}
+
+	results := make([]Backlog, 0, len(groups))
+
+	for _, g := range groups {
+		backlog := Backlog{
+			Group:  g["_id"],
+			Points: make([]BacklogPoint, 0, len(points)),
+		}
+
+		for i, at := range points {
+			backlog.Points = append(backlog.Points, BacklogPoint{At: at, Open: toInt(g[pointKey(i)])})
+		}
+
+		results = append(results, backlog)
+	}
+
+	slices.SortFunc(results, func(a, b Backlog) int {
+		return compareGroups(a.Group, b.Group)
+	})
+
+	return results, nil
+}
+
+// points returns the start of every interval between p.From and p.To, and
+// p.To itself
+func (p Params) points() []time.Time {
+	var points []time.Time
+
+	for at := p.periodStart(p.From); at.Before(p.To); at = p.nextPeriod(at) {
+		if !at.Before(p.From) {
+			points = append(points, at)
+		}
+	}
+
+	return append(points, p.To)
+}
+
+// periodStart returns the start of the interval t falls in. Weeks start on
+// Monday.
+func (p Params) periodStart(t time.Time) time.Time {
+	t = t.In(p.Location)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, p.Location)
+
+	if p.Interval == IntervalDay {
+		return day
+	}
+
+	offset := (int(day.Weekday()) + 6) % 7
+
+	return day.AddDate(0, 0, -offset)
+}
+
+// nextPeriod returns the start of the interval after the one starting at t
+func (p Params) nextPeriod(t time.Time) time.Time {
+	if p.Interval == IntervalDay {
+		return t.AddDate(0, 0, 1)
+	}
+
+	return t.AddDate(0, 0, 7)
+}
+
+// pointKey is the accumulator the ith backlog point is counted in
+func pointKey(i int) string {
+	return fmt.Sprintf("p%d", i)
+}
@@ -0,0 +1,200 @@
+// Package reports aggregates tickets into the figures asked for every month:
+// how long tickets take to resolve, how the open backlog changes, how many
+// tickets are created and closed, and how many are resolved within their SLA.
+package reports
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"github.com/digitalnest-wit/nestqueue/internal/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/zap"
+)
+
+const (
+	IntervalDay  = "day"
+	IntervalWeek = "week"
+)
+
+var (
+	ErrInvalidParams = errors.New("invalid report parameters")
+
+	// _groupFields lists the ticket fields reports can be grouped by
+	_groupFields = []string{"site", "category", "assignedTo", "priority"}
+
+	// _defaultRange is how far back reports look when no start is given
+	_defaultRange = 30 * 24 * time.Hour
+
+	// _maxPoints caps the points of a backlog series
+	_maxPoints = 366
+)
+
+// Reporter runs report aggregations over the tickets in a TicketStore
+type Reporter struct {
+	store   *storage.TicketStore
+	targets SLATargets
+	log     *zap.Logger
+}
+
+// NewReporter creates a new Reporter over the tickets in store. Tickets are
+// measured against targets in the SLA report.
+func NewReporter(store *storage.TicketStore, targets SLATargets, logger *zap.Logger) *Reporter {
+	return &Reporter{
+		store:   store,
+		targets: targets,
+		log:     logger.Named("reports"),
+	}
+}
+
+// Params selects the tickets a report covers and how they are grouped.
+// Zero-valued filters do not filter anything.
+type Params struct {
+	// From and To bound the report period. To defaults to now and From to 30
+	// days before To.
+	From time.Time
+	To   time.Time
+
+	// GroupBy splits the report by a ticket field: site, category,
+	// assignedTo or priority. Reports are not split when empty.
+	GroupBy string
+
+	// Interval is the length of the periods time series are bucketed into:
+	// day or week
+	Interval string
+
+	// Location is the time zone periods start in. Defaults to UTC.
+	Location *time.Location
+
+	Site       string
+	Category   string
+	AssignedTo string
+	Priority   int
+}
+
+// Validate fills in the defaults of p and checks that it describes a report.
+// Errors wrap ErrInvalidParams.
+func (p *Params) Validate() error {
+	if p.To.IsZero() {
+		p.To = time.Now()
+	}
+
+	if p.From.IsZero() {
+		p.From = p.To.Add(-_defaultRange)
+	}
+
+	if p.Location == nil {
+		p.Location = time.UTC
+	}
+
+	if !p.From.Before(p.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidParams)
+	}
+
+	if p.GroupBy != "" && !slices.Contains(_groupFields, p.GroupBy) {
+		return fmt.Errorf("%w: cannot group by %q", ErrInvalidParams, p.GroupBy)
+	}
+
+	switch p.Interval {
+	case "":
+		p.Interval = IntervalWeek
+	case IntervalDay, IntervalWeek:
+	default:
+		return fmt.Errorf("%w: unknown interval %q", ErrInvalidParams, p.Interval)
+	}
+
+	// A zero priority does not filter on priority
+	if p.Priority != 0 && (p.Priority < 1 || p.Priority > 5) {
+		return fmt.Errorf("%w: priority must be between 1 and 5", ErrInvalidParams)
+	}
+
+	return nil
+}
+
+// match returns the stage selecting the tickets p filters on. Merged tickets
+// were closed as duplicates rather than resolved, so they are left out.
+func (p Params) match() bson.D {
+	filter := bson.D{{Key: "mergedInto", Value: nil}}
+
+	if p.Site != "" {
+		filter = append(filter, bson.E{Key: "site", Value: p.Site})
+	}
+
+	if p.Category != "" {
+		filter = append(filter, bson.E{Key: "category", Value: p.Category})
+	}
+
+	if p.AssignedTo != "" {
+		filter = append(filter, bson.E{Key: "assignedTo", Value: p.AssignedTo})
+	}
+
+	if p.Priority != 0 {
+		filter = append(filter, bson.E{Key: "priority", Value: p.Priority})
+	}
+
+	return bson.D{{Key: "$match", Value: filter}}
+}
+
+// group returns the expression tickets are grouped on, which is nil when the
+// report is not split
+func (p Params) group() any {
+	if p.GroupBy == "" {
+		return nil
+	}
+
+	return "$" + p.GroupBy
+}
+
+// resolvedOnStage adds the time each resolved ticket was resolved as
+// resolvedOn. Tickets resolved before resolution times were recorded fall back
+// to their last update.
+func resolvedOnStage() bson.D {
+	return bson.D{{Key: "$addFields", Value: bson.D{
+		{Key: "resolvedOn", Value: bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$in", Value: bson.A{"$status", models.ResolvedStatuses}}},
+			bson.D{{Key: "$ifNull", Value: bson.A{"$closedOn", "$updatedAt"}}},
+			nil,
+		}}}},
+	}}}
+}
+
+// between returns a condition matching times in [from, to)
+func between(from, to time.Time) bson.D {
+	return bson.D{{Key: "$gte", Value: from}, {Key: "$lt", Value: to}}
+}
+
+// aggregate runs pipeline and decodes every result into results
+func (r *Reporter) aggregate(ctx context.Context, pipeline mongo.Pipeline, results any) error {
+	if err := r.store.AggregateTickets(ctx, pipeline, results); err != nil {
+		r.log.Sugar().Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// compareGroups orders group values, which are strings or numbers, for
+// stable output
+func compareGroups(a, b any) int {
+	return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// toInt converts a number decoded from an aggregation into an int
+func toInt(value any) int {
+	switch n := value.(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	default:
+		return 0
+	}
+}
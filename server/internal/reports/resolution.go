@@ -0,0 +1,107 @@
+package reports
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Resolution is how long the tickets of a group resolved in the report
+// period took to resolve
+type Resolution struct {
+	Group       any     `json:"group"`
+	Resolved    int     `json:"resolved"`
+	MeanHours   float64 `json:"meanHours"`
+	MedianHours float64 `json:"medianHours"`
+}
+
+// Resolution reports the mean and median time to resolution of the tickets
+// resolved between p.From and p.To
+func (r *Reporter) Resolution(ctx context.Context, p Params) ([]Resolution, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Durations are pushed rather than reduced with $median so the report
+	// runs on servers older than Mongo DB 7
+	pipeline := mongo.Pipeline{
+		p.match(),
+		{{Key: "$match", Value: bson.D{{Key: "status", Value: bson.D{{Key: "$in", Value: models.ResolvedStatuses}}}}}},
+		resolvedOnStage(),
+		{{Key: "$match", Value: bson.D{{Key: "resolvedOn", Value: between(p.From, p.To)}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: p.group()},
+			{Key: "durations", Value: bson.D{{Key: "$push", Value: bson.D{
+				{Key: "$subtract", Value: bson.A{"$resolvedOn", "$createdOn"}},
+			}}}},
+		}}},
+	}
+
+	var groups []struct {
+		Group     any     `bson:"_id"`
+		Durations []int64 `bson:"durations"`
+	}
+
+	if err := r.aggregate(ctx, pipeline, &groups); err != nil {
+		return nil, err
+	}
+
+	results := make([]Resolution, 0, len(groups))
+
+	for _, group := range groups {
+		results = append(results, Resolution{
+			Group:       group.Group,
+			Resolved:    len(group.Durations),
+			MeanHours:   hours(mean(group.Durations)),
+			MedianHours: hours(median(group.Durations)),
+		})
+	}
+
+	slices.SortFunc(results, func(a, b Resolution) int {
+		return compareGroups(a.Group, b.Group)
+	})
+
+	return results, nil
+}
+
+// mean returns the mean of durations in milliseconds
+func mean(durations []int64) float64 {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	var total float64
+
+	for _, d := range durations {
+		total += float64(d)
+	}
+
+	return total / float64(len(durations))
+}
+
+// median returns the median of durations in milliseconds
+func median(durations []int64) float64 {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+
+	middle := len(sorted) / 2
+
+	if len(sorted)%2 == 0 {
+		return float64(sorted[middle-1]+sorted[middle]) / 2
+	}
+
+	return float64(sorted[middle])
+}
+
+// hours converts milliseconds to hours
+func hours(ms float64) float64 {
+	return ms / float64(time.Hour/time.Millisecond)
+}
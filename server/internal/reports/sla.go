@@ -0,0 +1,173 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// SLATargets maps a ticket priority to the time a ticket of that priority
+// should be resolved within
+type SLATargets map[int]time.Duration
+
+// DefaultSLATargets are the resolution targets used unless configured
+var DefaultSLATargets = SLATargets{
+	5: 4 * time.Hour,
+	4: 8 * time.Hour,
+	3: 24 * time.Hour,
+	2: 72 * time.Hour,
+	1: 120 * time.Hour,
+}
+
+// ParseSLATargets parses targets written as comma-separated priority=duration
+// pairs, e.g. "5=4h,4=8h,3=24h". Priorities left out have no target.
+func ParseSLATargets(s string) (SLATargets, error) {
+	targets := make(SLATargets)
+
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid SLA target %q", pair)
+		}
+
+		priority, err := strconv.Atoi(key)
+		if err != nil || priority < 1 || priority > 5 {
+			return nil, fmt.Errorf("invalid SLA priority %q", key)
+		}
+
+		target, err := time.ParseDuration(value)
+		if err != nil || target <= 0 {
+			return nil, fmt.Errorf("invalid SLA duration %q", value)
+		}
+
+		targets[priority] = target
+	}
+
+	return targets, nil
+}
+
+// SLA is how many tickets of a group were resolved within their target
+type SLA struct {
+	Group any `json:"group"`
+
+	// Total counts every ticket with a target
+	Total int `json:"total"`
+
+	// Met counts tickets resolved within their target
+	Met int `json:"met"`
+
+	// Breached counts tickets resolved late, or still open past their target
+	Breached int `json:"breached"`
+
+	// Pending counts open tickets still within their target
+	Pending int `json:"pending"`
+
+	// Compliance is the share of met tickets among met and breached ones.
+	// It is null when no ticket has met or breached its target yet.
+	Compliance *float64 `json:"compliance"`
+}
+
+// SLA reports how many of the tickets created between p.From and p.To were
+// resolved within the target for their priority. Tickets whose priority has
+// no target are left out.
+func (r *Reporter) SLA(ctx context.Context, p Params) ([]SLA, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	var (
+		priorities = slices.Sorted(maps.Keys(r.targets))
+		branches   = bson.A{}
+	)
+
+	for _, priority := range priorities {
+		branches = append(branches, bson.D{
+			{Key: "case", Value: bson.D{{Key: "$eq", Value: bson.A{"$priority", priority}}}},
+			{Key: "then", Value: r.targets[priority].Milliseconds()},
+		})
+	}
+
+	var (
+		resolved = bson.D{{Key: "$ne", Value: bson.A{"$resolvedOn", nil}}}
+		late     = bson.D{{Key: "$gt", Value: bson.A{"$elapsed", "$target"}}}
+		count    = func(condition any) bson.D {
+			return bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{condition, 1, 0}}}}}
+		}
+		and = func(conditions ...any) bson.D {
+			return bson.D{{Key: "$and", Value: bson.A(conditions)}}
+		}
+		not = func(condition any) bson.D {
+			return bson.D{{Key: "$not", Value: bson.A{condition}}}
+		}
+	)
+
+	// Open tickets are measured up to now, so they breach as soon as they
+	// pass their target
+	pipeline := mongo.Pipeline{
+		p.match(),
+		{{Key: "$match", Value: bson.D{
+			{Key: "createdOn", Value: between(p.From, p.To)},
+			{Key: "priority", Value: bson.D{{Key: "$in", Value: priorities}}},
+		}}},
+		resolvedOnStage(),
+		{{Key: "$addFields", Value: bson.D{
+			{Key: "target", Value: bson.D{{Key: "$switch", Value: bson.D{
+				{Key: "branches", Value: branches},
+				{Key: "default", Value: 0},
+			}}}},
+			{Key: "elapsed", Value: bson.D{{Key: "$subtract", Value: bson.A{
+				bson.D{{Key: "$ifNull", Value: bson.A{"$resolvedOn", "$$NOW"}}},
+				"$createdOn",
+			}}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: p.group()},
+			{Key: "total", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "met", Value: count(and(resolved, not(late)))},
+			{Key: "breached", Value: count(late)},
+		}}},
+	}
+
+	var groups []struct {
+		Group    any `bson:"_id"`
+		Total    int `bson:"total"`
+		Met      int `bson:"met"`
+		Breached int `bson:"breached"`
+	}
+
+	if err := r.aggregate(ctx, pipeline, &groups); err != nil {
+		return nil, err
+	}
+
+	results := make([]SLA, 0, len(groups))
+
+	for _, group := range groups {
+		sla := SLA{
+			Group:    group.Group,
+			Total:    group.Total,
+			Met:      group.Met,
+			Breached: group.Breached,
+			Pending:  group.Total - group.Met - group.Breached,
+		}
+
+		if decided := group.Met + group.Breached; decided > 0 {
+			compliance := float64(group.Met) / float64(decided)
+			sla.Compliance = &compliance
+		}
+
+		results = append(results, sla)
+	}
+
+	slices.SortFunc(results, func(a, b SLA) int {
+		return compareGroups(a.Group, b.Group)
+	})
+
+	return results, nil
+}
@@ -0,0 +1,121 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Throughput is the number of tickets of a group created and resolved in an
+// interval
+type Throughput struct {
+	Group   any       `json:"group"`
+	Period  time.Time `json:"period"`
+	Created int       `json:"created"`
+	Closed  int       `json:"closed"`
+}
+
+// Throughput reports the number of tickets created and resolved in every
+// interval between p.From and p.To. Intervals without either are left out.
+func (r *Reporter) Throughput(ctx context.Context, p Params) ([]Throughput, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	count := func(field string) mongo.Pipeline {
+		return mongo.Pipeline{
+			{{Key: "$match", Value: bson.D{{Key: field, Value: between(p.From, p.To)}}}},
+			{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: bson.D{
+					{Key: "group", Value: p.group()},
+					{Key: "period", Value: p.truncate("$" + field)},
+				}},
+				{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			}}},
+		}
+	}
+
+	pipeline := mongo.Pipeline{
+		p.match(),
+		resolvedOnStage(),
+		{{Key: "$facet", Value: bson.D{
+			{Key: "created", Value: count("createdOn")},
+			{Key: "closed", Value: count("resolvedOn")},
+		}}},
+	}
+
+	type bucket struct {
+		ID struct {
+			Group  any       `bson:"group"`
+			Period time.Time `bson:"period"`
+		} `bson:"_id"`
+		Count int `bson:"count"`
+	}
+
+	var facets []struct {
+		Created []bucket `bson:"created"`
+		Closed  []bucket `bson:"closed"`
+	}
+
+	if err := r.aggregate(ctx, pipeline, &facets); err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]*Throughput)
+
+	entry := func(b bucket) *Throughput {
+		key := fmt.Sprint(b.ID.Group) + "|" + b.ID.Period.String()
+
+		if _, ok := byKey[key]; !ok {
+			byKey[key] = &Throughput{Group: b.ID.Group, Period: b.ID.Period.In(p.Location)}
+		}
+
+		return byKey[key]
+	}
+
+	for _, facet := range facets {
+		for _, b := range facet.Created {
+			entry(b).Created += b.Count
+		}
+
+		for _, b := range facet.Closed {
+			entry(b).Closed += b.Count
+		}
+	}
+
+	results := make([]Throughput, 0, len(byKey))
+
+	for _, t := range byKey {
+		results = append(results, *t)
+	}
+
+	slices.SortFunc(results, func(a, b Throughput) int {
+		if c := compareGroups(a.Group, b.Group); c != 0 {
+			return c
+		}
+
+		return a.Period.Compare(b.Period)
+	})
+
+	return results, nil
+}
+
+// truncate returns the expression truncating the date at field to the start
+// of its interval in p.Location
+func (p Params) truncate(field string) bson.D {
+	args := bson.D{
+		{Key: "date", Value: field},
+		{Key: "unit", Value: p.Interval},
+		{Key: "timezone", Value: p.Location.String()},
+	}
+
+	if p.Interval == IntervalWeek {
+		args = append(args, bson.E{Key: "startOfWeek", Value: "monday"})
+	}
+
+	return bson.D{{Key: "$dateTrunc", Value: args}}
+}
@@ -0,0 +1,295 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+)
+
+var (
+	// _settableFields lists the ticket fields a setField action may change
+	_settableFields = []string{
+		"title",
+		"description",
+		"site",
+		"category",
+		"assignedTo",
+		"priority",
+		"status",
+		"tags",
+	}
+
+	_operators = []string{
+		models.OpEquals,
+		models.OpNotEquals,
+		models.OpContains,
+		models.OpMatches,
+		models.OpIn,
+		models.OpGreater,
+		models.OpGreaterEq,
+		models.OpLess,
+		models.OpLessEq,
+	}
+)
+
+// evaluate reports whether condition holds for fields
+func evaluate(condition models.Condition, fields map[string]any) (bool, error) {
+	var (
+		actual   = lookup(fields, condition.Field)
+		expected = normalize(condition.Value)
+	)
+
+	switch condition.Operator {
+	case models.OpEquals:
+		return equal(actual, expected), nil
+	case models.OpNotEquals:
+		return !equal(actual, expected), nil
+	case models.OpContains:
+		return contains(actual, expected), nil
+	case models.OpMatches:
+		pattern, _ := expected.(string)
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, err
+		}
+
+		return matchesPattern(actual, re), nil
+	case models.OpIn:
+		values, _ := expected.([]any)
+
+		return slices.ContainsFunc(values, func(v any) bool { return equal(actual, v) }), nil
+	case models.OpGreater, models.OpGreaterEq, models.OpLess, models.OpLessEq:
+		c, ok := compare(actual, expected)
+		if !ok {
+			return false, nil
+		}
+
+		switch condition.Operator {
+		case models.OpGreater:
+			return c > 0, nil
+		case models.OpGreaterEq:
+			return c >= 0, nil
+		case models.OpLess:
+			return c < 0, nil
+		default:
+			return c <= 0, nil
+		}
+	default:
+		return false, fmt.Errorf("unknown operator %q", condition.Operator)
+	}
+}
+
+// lookup returns the value at path in fields. Nested values are addressed
+// with dots, e.g. "customFields.assetTag".
+func lookup(fields map[string]any, path string) any {
+	var current any = fields
+
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		current = m[key]
+	}
+
+	return current
+}
+
+// equal compares two values, ignoring case when both are strings
+func equal(a, b any) bool {
+	as, aok := a.(string)
+	bs, bok := b.(string)
+
+	if aok && bok {
+		return strings.EqualFold(as, bs)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// contains reports whether the string actual contains expected, ignoring
+// case, or whether the list actual has an element equal to expected
+func contains(actual, expected any) bool {
+	switch v := actual.(type) {
+	case string:
+		s, ok := expected.(string)
+
+		return ok && strings.Contains(strings.ToLower(v), strings.ToLower(s))
+	case []any:
+		return slices.ContainsFunc(v, func(e any) bool { return equal(e, expected) })
+	default:
+		return false
+	}
+}
+
+// matchesPattern reports whether actual, or any element of actual if it is a
+// list, is a string matching re
+func matchesPattern(actual any, re *regexp.Regexp) bool {
+	switch v := actual.(type) {
+	case string:
+		return re.MatchString(v)
+	case []any:
+		return slices.ContainsFunc(v, func(e any) bool { return matchesPattern(e, re) })
+	default:
+		return false
+	}
+}
+
+// compare orders two numbers or two strings. ok is false if the values
+// cannot be compared.
+func compare(a, b any) (c int, ok bool) {
+	switch av := a.(type) {
+	case float64:
+		bv, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+
+		switch {
+		case av < bv:
+			return -1, true
+		case av > bv:
+			return 1, true
+		default:
+			return 0, true
+		}
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+
+		return strings.Compare(av, bv), true
+	default:
+		return 0, false
+	}
+}
+
+// Validate checks that rule is well formed before it is stored
+func Validate(rule models.Rule) error {
+	if rule.Name == "" {
+		return errors.New("rule name is required")
+	}
+
+	if len(rule.Events) == 0 {
+		return errors.New("rule must listen to at least one event")
+	}
+
+	for _, event := range rule.Events {
+		if event != models.EventCreate && event != models.EventUpdate {
+			return fmt.Errorf("unknown event %q", event)
+		}
+	}
+
+	if rule.Match != models.MatchAll && rule.Match != models.MatchAny {
+		return fmt.Errorf("unknown match %q", rule.Match)
+	}
+
+	for i, condition := range rule.Conditions {
+		if err := validateCondition(condition); err != nil {
+			return fmt.Errorf("condition %d: %w", i, err)
+		}
+	}
+
+	if len(rule.Actions) == 0 {
+		return errors.New("rule must have at least one action")
+	}
+
+	for i, action := range rule.Actions {
+		if err := validateAction(action); err != nil {
+			return fmt.Errorf("action %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// validateCondition checks a single condition of a rule
+func validateCondition(condition models.Condition) error {
+	if condition.Field == "" {
+		return errors.New("field is required")
+	}
+
+	if !slices.Contains(_operators, condition.Operator) {
+		return fmt.Errorf("unknown operator %q", condition.Operator)
+	}
+
+	switch condition.Operator {
+	case models.OpMatches:
+		pattern, ok := condition.Value.(string)
+		if !ok {
+			return errors.New("matches expects a regular expression")
+		}
+
+		if _, err := regexp.Compile(pattern); err != nil {
+			return err
+		}
+	case models.OpIn:
+		if _, ok := normalize(condition.Value).([]any); !ok {
+			return errors.New("in expects a list of values")
+		}
+	}
+
+	return nil
+}
+
+// validateAction checks a single action of a rule
+func validateAction(action models.Action) error {
+	switch action.Type {
+	case models.ActionSetField:
+		if !slices.Contains(_settableFields, action.Field) {
+			return fmt.Errorf("field %q cannot be set by a rule", action.Field)
+		}
+
+		if err := validateFieldValue(action.Field, normalize(action.Value)); err != nil {
+			return err
+		}
+	case models.ActionAssign, models.ActionAddTag, models.ActionNotify:
+		if s, ok := action.Value.(string); !ok || s == "" {
+			return fmt.Errorf("%s expects a non-empty value", action.Type)
+		}
+	case models.ActionWebhook:
+		if err := validateWebhookURL(action.URL); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown action %q", action.Type)
+	}
+
+	return nil
+}
+
+// validateFieldValue checks that value has the JSON type of the ticket field
+// it is assigned to
+func validateFieldValue(field string, value any) error {
+	switch field {
+	case "priority":
+		if _, ok := value.(float64); !ok {
+			return errors.New("priority must be a number")
+		}
+	case "tags":
+		values, ok := value.([]any)
+		if !ok {
+			return errors.New("tags must be a list of strings")
+		}
+
+		for _, v := range values {
+			if _, ok := v.(string); !ok {
+				return errors.New("tags must be a list of strings")
+			}
+		}
+	default:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s must be a string", field)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,366 @@
+// Package rules evaluates triage rules against tickets as they are created
+// and updated.
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/logging"
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"github.com/digitalnest-wit/nestqueue/internal/storage"
+	"go.uber.org/zap"
+)
+
+var (
+	_sideEffectTimeout = 10 * time.Second
+
+	errStopping = errors.New("rule engine is shutting down")
+)
+
+// Engine evaluates the enabled rules in a RuleStore and performs the side
+// effects of the rules that fire
+type Engine struct {
+	store    *storage.RuleStore
+	notifier Notifier
+	client   *http.Client
+	log      *zap.Logger
+
+	// running tracks the side effects being performed
+	running sync.WaitGroup
+
+	// stopping is set once the engine waits for its side effects to stop
+	stopping atomic.Bool
+}
+
+// Result describes the rules that fired for a ticket and what they changed
+type Result struct {
+	Fired   []Firing       `json:"fired"`
+	Changes map[string]any `json:"changes"`
+	Ticket  models.Ticket  `json:"ticket"`
+
+	effects []effect
+}
+
+// Firing records a rule whose conditions held and the actions it took
+type Firing struct {
+	RuleID  string          `json:"ruleId"`
+	Name    string          `json:"name"`
+	Actions []models.Action `json:"actions"`
+}
+
+// effect is a notify or webhook action waiting to be dispatched
+type effect struct {
+	rule   models.Rule
+	action models.Action
+}
+
+// NewEngine creates a new rule engine reading rules from store and sending
+// notifications through notifier
+func NewEngine(store *storage.RuleStore, notifier Notifier, logger *zap.Logger) *Engine {
+	return &Engine{
+		store:    store,
+		notifier: notifier,
+		client:   newWebhookClient(_sideEffectTimeout),
+		log:      logger.Named("rules"),
+	}
+}
+
+// Evaluate applies the enabled rules listening to event to ticket. The
+// returned Result holds the changed ticket; side effects are not performed
+// until Dispatch is called.
+func (e *Engine) Evaluate(ctx context.Context, event string, ticket models.Ticket) (*Result, error) {
+	rules, err := e.EnabledRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.EvaluateRules(rules, event, ticket)
+}
+
+// EnabledRules returns the enabled rules, so that many tickets can be
+// evaluated against them with EvaluateRules without reading them every time
+func (e *Engine) EnabledRules(ctx context.Context) ([]models.Rule, error) {
+	return e.store.FindRules(ctx, true)
+}
+
+// EvaluateRules is like Evaluate, but applies rules, which were returned by
+// EnabledRules, instead of reading them from the store
+func (e *Engine) EvaluateRules(rules []models.Rule, event string, ticket models.Ticket) (*Result, error) {
+	result, err := Apply(rules, event, ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Fired) > 0 {
+		e.log.Sugar().Debugw("rules fired", "event", event, "ticket.id", ticket.ID, "count", len(result.Fired))
+	}
+
+	return result, nil
+}
+
+// Pending reports whether result has side effects waiting to be dispatched
+func (r *Result) Pending() bool {
+	return len(r.effects) > 0
+}
+
+// Dispatch performs the notify and webhook actions collected in result for
+// ticket, which should be the ticket as it was stored. Actions run in the
+// background, outliving ctx, and failures are logged. Webhooks continue the
+// trace carried by ctx.
+func (e *Engine) Dispatch(ctx context.Context, event string, result *Result, ticket models.Ticket) {
+	ctx = context.WithoutCancel(ctx)
+
+	for _, eff := range result.effects {
+		e.running.Add(1)
+
+		go func() {
+			defer e.running.Done()
+			e.perform(ctx, event, eff, ticket)
+		}()
+	}
+}
+
+// Wait waits until the actions dispatched so far have been performed or ctx
+// is done, e.g. before the server stops. The engine is reported as shutting
+// down by Check from then on.
+func (e *Engine) Wait(ctx context.Context) error {
+	e.stopping.Store(true)
+
+	done := make(chan struct{})
+
+	go func() {
+		e.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Check reports an error once the engine is shutting down, so actions
+// dispatched from then on may be cut short. It is meant as a readiness
+// check.
+func (e *Engine) Check(ctx context.Context) error {
+	if e.stopping.Load() {
+		return errStopping
+	}
+
+	return nil
+}
+
+// perform runs a single side effect
+func (e *Engine) perform(ctx context.Context, event string, eff effect, ticket models.Ticket) {
+	var sugar = logging.FromContext(ctx, e.log).Sugar()
+
+	ctx, cancel := context.WithTimeout(ctx, _sideEffectTimeout)
+	defer cancel()
+
+	var err error
+
+	switch eff.action.Type {
+	case models.ActionNotify:
+		recipient, _ := eff.action.Value.(string)
+		err = e.notifier.Notify(ctx, recipient, eff.action.Message, ticket)
+	case models.ActionWebhook:
+		err = e.callWebhook(ctx, event, eff, ticket)
+	}
+
+	if err != nil {
+		sugar.Errorw("rule action failed", "rule.id", eff.rule.ID, "action", eff.action.Type, "error", err)
+	}
+}
+
+// callWebhook posts the event, rule and ticket as JSON to the action's URL
+func (e *Engine) callWebhook(ctx context.Context, event string, eff effect, ticket models.Ticket) error {
+	payload := map[string]any{
+		"event":  event,
+		"rule":   map[string]any{"id": eff.rule.ID, "name": eff.rule.Name},
+		"ticket": ticket,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, eff.action.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", res.Status)
+	}
+
+	return nil
+}
+
+// Apply evaluates rules in order against ticket for event without touching
+// the store or performing side effects. Each rule sees the changes made by
+// the rules before it.
+func Apply(rules []models.Rule, event string, ticket models.Ticket) (*Result, error) {
+	before, err := toFields(ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := toFields(ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Fired:   []Firing{},
+		Changes: map[string]any{},
+	}
+
+	for _, rule := range rules {
+		if !rule.Enabled || !slices.Contains(rule.Events, event) {
+			continue
+		}
+
+		ok, err := matches(rule, fields)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+
+		if !ok {
+			continue
+		}
+
+		for _, action := range rule.Actions {
+			switch action.Type {
+			case models.ActionSetField:
+				fields[action.Field] = normalize(action.Value)
+			case models.ActionAssign:
+				fields["assignedTo"] = normalize(action.Value)
+			case models.ActionAddTag:
+				tag, _ := action.Value.(string)
+				fields["tags"] = addTag(fields["tags"], models.NormalizeTag(tag))
+			case models.ActionNotify, models.ActionWebhook:
+				result.effects = append(result.effects, effect{rule: rule, action: action})
+			}
+		}
+
+		result.Fired = append(result.Fired, Firing{
+			RuleID:  rule.ID,
+			Name:    rule.Name,
+			Actions: rule.Actions,
+		})
+	}
+
+	for key, value := range fields {
+		if !reflect.DeepEqual(before[key], value) {
+			result.Changes[key] = value
+		}
+	}
+
+	if err := fromFields(fields, &result.Ticket); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// matches reports whether the conditions of rule hold for fields
+func matches(rule models.Rule, fields map[string]any) (bool, error) {
+	if len(rule.Conditions) == 0 {
+		return true, nil
+	}
+
+	for _, condition := range rule.Conditions {
+		ok, err := evaluate(condition, fields)
+		if err != nil {
+			return false, err
+		}
+
+		if rule.Match == models.MatchAny && ok {
+			return true, nil
+		}
+
+		if rule.Match != models.MatchAny && !ok {
+			return false, nil
+		}
+	}
+
+	return rule.Match != models.MatchAny, nil
+}
+
+// addTag appends tag to tags unless it is already present
+func addTag(tags, tag any) []any {
+	list, _ := tags.([]any)
+
+	for _, existing := range list {
+		if reflect.DeepEqual(existing, tag) {
+			return list
+		}
+	}
+
+	return append(list, tag)
+}
+
+// toFields converts ticket into the generic form rules operate on, keyed by
+// the ticket's JSON field names
+func toFields(ticket models.Ticket) (map[string]any, error) {
+	data, err := json.Marshal(ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// fromFields converts fields back into ticket
+func fromFields(fields map[string]any, ticket *models.Ticket) error {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, ticket)
+}
+
+// normalize converts a rule value, which may have been decoded from BSON,
+// into the types produced by decoding JSON so it can be compared with ticket
+// fields
+func normalize(value any) any {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var normalized any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+
+	return normalized
+}
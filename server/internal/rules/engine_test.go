@@ -0,0 +1,24 @@
+package rules
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestCheck(t *testing.T) {
+	engine := NewEngine(nil, NewLogNotifier(zap.NewNop()), zap.NewNop())
+
+	if err := engine.Check(context.Background()); err != nil {
+		t.Fatalf("Check = %v before shutting down, want nil", err)
+	}
+
+	if err := engine.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := engine.Check(context.Background()); err == nil {
+		t.Error("Check = nil while shutting down, want an error")
+	}
+}
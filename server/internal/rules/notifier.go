@@ -0,0 +1,36 @@
+package rules
+
+import (
+	"context"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"go.uber.org/zap"
+)
+
+// Notifier delivers the message of a notify action to a recipient
+type Notifier interface {
+	Notify(ctx context.Context, recipient, message string, ticket models.Ticket) error
+}
+
+// LogNotifier is a Notifier that writes notifications to a logger. It is used
+// until a delivery channel such as email is configured.
+type LogNotifier struct {
+	log *zap.Logger
+}
+
+// NewLogNotifier creates a new LogNotifier writing to logger
+func NewLogNotifier(logger *zap.Logger) *LogNotifier {
+	return &LogNotifier{log: logger.Named("notify")}
+}
+
+// Notify logs the notification
+func (n *LogNotifier) Notify(_ context.Context, recipient, message string, ticket models.Ticket) error {
+	n.log.Sugar().Infow("notification",
+		"recipient", recipient,
+		"message", message,
+		"ticket.id", ticket.ID,
+		"ticket.title", ticket.Title,
+	)
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package rules
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+var (
+	// _sharedAddressSpace is the range carrier-grade NATs use inside a
+	// provider's network, which is no more public than a private range
+	_sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+)
+
+// validateWebhookURL checks that rawURL is an http or https URL whose host is
+// not a private, loopback or link-local address. Host names are checked again
+// once resolved, when the webhook is called.
+func validateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook expects an http or https URL, got %q", rawURL)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("webhook URL %q targets a loopback address", rawURL)
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil && !isPublic(addr) {
+		return fmt.Errorf("webhook URL %q targets a private, loopback or link-local address", rawURL)
+	}
+
+	return nil
+}
+
+// isPublic tells whether addr is a public unicast address that webhooks may
+// be sent to
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !_sharedAddressSpace.Contains(addr)
+}
+
+// newWebhookClient creates a client that refuses to connect to addresses that
+// are not public, so webhooks cannot reach the server's own network, whether
+// through a host name resolving there or through a redirect
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+
+			if !isPublic(addrPort.Addr()) {
+				return fmt.Errorf("webhook target %s is not a public address", addrPort.Addr())
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+
+	// Proxies are not used, since the address of the webhook could then not be
+	// checked
+	transport.Proxy = nil
+
+	// Calls are traced as children of the span of the request triggering
+	// them, which the webhook continues
+	return &http.Client{Timeout: timeout, Transport: otelhttp.NewTransport(transport)}
+}
@@ -0,0 +1,160 @@
+// Package similarity scores how alike short texts are, for finding tickets
+// that describe the same problem.
+package similarity
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// _stopwords are common words that say nothing about a ticket's problem
+var _stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "can": true, "cant": true, "do": true,
+	"does": true, "doesnt": true, "dont": true, "for": true, "from": true,
+	"has": true, "have": true, "i": true, "im": true, "in": true, "is": true,
+	"isnt": true, "it": true, "its": true, "me": true, "my": true, "not": true,
+	"of": true, "on": true, "or": true, "our": true, "please": true, "so": true,
+	"that": true, "the": true, "this": true, "to": true, "was": true,
+	"we": true, "when": true, "with": true, "wont": true, "you": true,
+}
+
+// Document is a text to compare, identified by ID
+type Document struct {
+	ID   string
+	Text string
+}
+
+// Match is a document scored against a query. Score ranges from 0, nothing
+// in common, to 1, the same words in the same proportions.
+type Match struct {
+	ID    string
+	Score float64
+}
+
+// Tokenize splits text into lowercase words, dropping punctuation, stopwords
+// and single characters
+func Tokenize(text string) []string {
+	var tokens []string
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+
+	for _, word := range words {
+		word = strings.ReplaceAll(word, "'", "")
+
+		if len(word) < 2 || _stopwords[word] {
+			continue
+		}
+
+		tokens = append(tokens, word)
+	}
+
+	return tokens
+}
+
+// Rank scores every document against query using the cosine similarity of
+// their TF-IDF vectors, weighting words by how rare they are across the
+// documents. Matches scoring below threshold are dropped and at most limit
+// matches are returned, best first.
+func Rank(query string, documents []Document, threshold float64, limit int) []Match {
+	var (
+		queryTokens = Tokenize(query)
+		docTokens   = make([][]string, len(documents))
+		frequency   = make(map[string]int)
+	)
+
+	if len(queryTokens) == 0 || len(documents) == 0 {
+		return nil
+	}
+
+	// Count the documents each word appears in, counting the query as one
+	for i, doc := range documents {
+		docTokens[i] = Tokenize(doc.Text)
+
+		for word := range termCounts(docTokens[i]) {
+			frequency[word]++
+		}
+	}
+
+	for word := range termCounts(queryTokens) {
+		frequency[word]++
+	}
+
+	var (
+		total = float64(len(documents) + 1)
+		idf   = func(word string) float64 {
+			return math.Log((total+1)/(float64(frequency[word])+1)) + 1
+		}
+		queryVector = vectorize(queryTokens, idf)
+		matches     []Match
+	)
+
+	for i, doc := range documents {
+		score := cosine(queryVector, vectorize(docTokens[i], idf))
+
+		if score >= threshold {
+			matches = append(matches, Match{ID: doc.ID, Score: score})
+		}
+	}
+
+	slices.SortStableFunc(matches, func(a, b Match) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	return matches
+}
+
+// termCounts counts the occurrences of each token
+func termCounts(tokens []string) map[string]int {
+	counts := make(map[string]int, len(tokens))
+
+	for _, token := range tokens {
+		counts[token]++
+	}
+
+	return counts
+}
+
+// vectorize builds the TF-IDF vector of tokens
+func vectorize(tokens []string, idf func(string) float64) map[string]float64 {
+	var (
+		counts = termCounts(tokens)
+		vector = make(map[string]float64, len(counts))
+	)
+
+	for word, count := range counts {
+		tf := float64(count) / float64(len(tokens))
+		vector[word] = tf * idf(word)
+	}
+
+	return vector
+}
+
+// cosine returns the cosine similarity of two sparse vectors
+func cosine(a, b map[string]float64) float64 {
+	var dot, normA, normB float64
+
+	for word, weight := range a {
+		dot += weight * b[word]
+		normA += weight * weight
+	}
+
+	for _, weight := range b {
+		normB += weight * weight
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// TicketsCollection is the collection holding tickets
+const TicketsCollection = _ticketsCollection
+
+// Collections lists the collections holding tickets and the data related to
+// them, tickets first
+var Collections = []string{
+	_ticketsCollection,
+	_linksCollection,
+	_commentsCollection,
+	_fieldsCollection,
+	_queuesCollection,
+	_rulesCollection,
+	_tagsCollection,
+}
+
+// _indexes maps collections to the indexes used to query them
+var _indexes = map[string][]mongo.IndexModel{
+	_ticketsCollection: {
+		// Multikey index backing tag filters and usage counts
+		{Keys: bson.D{{Key: "tags", Value: 1}}},
+		// Wildcard index backing filters and sorts on custom fields, whose
+		// keys vary by category
+		{Keys: bson.D{{Key: "customFields.$**", Value: 1}}},
+	},
+	_linksCollection: {
+		{
+			Keys:    bson.D{{Key: "source", Value: 1}, {Key: "target", Value: 1}, {Key: "type", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "target", Value: 1}, {Key: "type", Value: 1}}},
+	},
+	_commentsCollection: {
+		{Keys: bson.D{{Key: "ticket", Value: 1}, {Key: "createdOn", Value: 1}}},
+	},
+}
+
+// Database returns the database holding every collection of the store
+func Database(client *mongo.Client) *mongo.Database {
+	return client.Database(_database)
+}
+
+// EnsureIndexes creates the indexes of every collection. When drop is set,
+// every other index is dropped first, rebuilding the indexes from scratch.
+func EnsureIndexes(ctx context.Context, client *mongo.Client, drop bool) error {
+	db := Database(client)
+
+	for _, name := range Collections {
+		collection := db.Collection(name)
+
+		if drop {
+			if err := collection.Indexes().DropAll(ctx); err != nil && !isNamespaceNotFound(err) {
+				return err
+			}
+		}
+
+		if err := createIndexes(ctx, collection); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createIndexes creates the indexes of collection. Creating an index that
+// already exists is a no-op.
+func createIndexes(ctx context.Context, collection *mongo.Collection) error {
+	indexes, ok := _indexes[collection.Name()]
+	if !ok {
+		return nil
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+
+	return err
+}
+
+// isNamespaceNotFound reports whether err was caused by a collection that
+// does not exist yet
+func isNamespaceNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+
+	return errors.As(err, &cmdErr) && cmdErr.HasErrorCode(26)
+}
@@ -0,0 +1,359 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Outcomes of a bulk operation on a single ticket
+const (
+	BulkUpdated  = "updated"
+	BulkDeleted  = "deleted"
+	BulkNotFound = "notFound"
+	BulkFailed   = "failed"
+)
+
+// BulkResult is the outcome of a bulk operation on a single ticket
+type BulkResult struct {
+	ID     string `json:"id"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkUpdate is the update of a single ticket in a bulk operation, taking
+// the same updates as UpdateTicket
+type BulkUpdate struct {
+	ID      string
+	Updates map[string]any
+}
+
+// FindTicketIDs returns the IDs of the tickets matching filter, reading only
+// their IDs
+func (s *TicketStore) FindTicketIDs(ctx context.Context, filter TicketFilter) (_ []string, err error) {
+	defer observe("FindTicketIDs", time.Now(), &err)
+
+	var sugar = s.log.Sugar()
+
+	opts := options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}})
+	if sort := filter.sortDocument(); len(sort) > 0 {
+		opts.SetSort(sort)
+	}
+
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+
+	cursor, err := s.collection.Find(ctx, filter.document(), opts)
+	if err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	var docs []struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+
+	if err := cursor.All(ctx, &docs); err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(docs))
+
+	for _, doc := range docs {
+		ids = append(ids, doc.ID.Hex())
+	}
+
+	return ids, nil
+}
+
+// FindTicketsByID finds the tickets identified by ids in a single query and
+// returns them by ID. IDs of tickets that do not exist are left out.
+func (s *TicketStore) FindTicketsByID(ctx context.Context, ids []string) (_ map[string]*models.Ticket, err error) {
+	defer observe("FindTicketsByID", time.Now(), &err)
+
+	var (
+		sugar     = s.log.Sugar()
+		objectIds = make([]bson.ObjectID, 0, len(ids))
+		tickets   = make(map[string]*models.Ticket, len(ids))
+	)
+
+	for _, id := range ids {
+		if objectId, err := bson.ObjectIDFromHex(id); err == nil {
+			objectIds = append(objectIds, objectId)
+		}
+	}
+
+	if len(objectIds) == 0 {
+		return tickets, nil
+	}
+
+	filter := bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: objectIds}}}}
+
+	cursor, err := s.collection.Find(ctx, filter)
+	if err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	var found []*models.Ticket
+
+	if err := cursor.All(ctx, &found); err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	for _, ticket := range found {
+		tickets[ticket.ID] = ticket
+	}
+
+	return tickets, nil
+}
+
+// BulkUpdateTickets applies every update in a single bulk write and returns
+// the outcome for each ticket, in order. Tickets that do not exist are
+// reported as not found rather than failing the whole operation; an error is
+// only returned when the write cannot be made at all.
+func (s *TicketStore) BulkUpdateTickets(ctx context.Context, updates []BulkUpdate) (_ []BulkResult, err error) {
+	defer observe("BulkUpdateTickets", time.Now(), &err)
+
+	var (
+		sugar   = s.log.Sugar()
+		ids     = make([]string, len(updates))
+		writes  []mongo.WriteModel
+		indexes []int
+	)
+
+	for i, u := range updates {
+		ids[i] = u.ID
+	}
+
+	results, objectIds, err := s.resolveBulk(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, u := range updates {
+		if results[i].Result == BulkNotFound {
+			continue
+		}
+
+		filter := bson.D{{Key: "_id", Value: objectIds[i]}}
+
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(updateDocuments(u.Updates)))
+		indexes = append(indexes, i)
+	}
+
+	if len(writes) == 0 {
+		return results, nil
+	}
+
+	s.bulkWrite(ctx, writes, indexes, results, BulkUpdated)
+
+	// Resolution times and child tickets follow status changes
+	var (
+		byStatus = make(map[string][]bson.ObjectID)
+		closed   []bson.ObjectID
+	)
+
+	for i, u := range updates {
+		status, ok := u.Updates["status"].(string)
+		if !ok || results[i].Result != BulkUpdated {
+			continue
+		}
+
+		byStatus[status] = append(byStatus[status], objectIds[i])
+
+		if status == models.StatusClosed {
+			closed = append(closed, objectIds[i])
+		}
+	}
+
+	for status, statusIds := range byStatus {
+		if err := s.trackResolution(ctx, status, statusIds...); err != nil {
+			sugar.Errorw("failed to record resolution times", "status", status, "error", err)
+		}
+	}
+
+	if len(closed) > 0 {
+		if err := s.closeChildren(ctx, closed...); err != nil {
+			sugar.Errorw("failed to close child tickets", "error", err)
+		}
+	}
+
+	sugar.Debugw("bulk updated tickets", "count", len(writes))
+
+	return results, nil
+}
+
+// BulkDeleteTickets deletes the tickets identified by ids, along with their
+// links and comments, in a single bulk write and returns the outcome for each ticket, in
+// order
+func (s *TicketStore) BulkDeleteTickets(ctx context.Context, ids []string) (_ []BulkResult, err error) {
+	defer observe("BulkDeleteTickets", time.Now(), &err)
+
+	var (
+		sugar   = s.log.Sugar()
+		writes  []mongo.WriteModel
+		indexes []int
+		deleted []bson.ObjectID
+	)
+
+	results, objectIds, err := s.resolveBulk(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range ids {
+		if results[i].Result == BulkNotFound {
+			continue
+		}
+
+		writes = append(writes, mongo.NewDeleteOneModel().SetFilter(bson.D{{Key: "_id", Value: objectIds[i]}}))
+		indexes = append(indexes, i)
+	}
+
+	if len(writes) == 0 {
+		return results, nil
+	}
+
+	s.bulkWrite(ctx, writes, indexes, results, BulkDeleted)
+
+	for i, result := range results {
+		if result.Result == BulkDeleted {
+			deleted = append(deleted, objectIds[i])
+		}
+	}
+
+	// Links and comments of deleted tickets would dangle, so they go with
+	// them
+	linkFilter := bson.D{{Key: "$or", Value: []bson.D{
+		{{Key: "source", Value: bson.D{{Key: "$in", Value: deleted}}}},
+		{{Key: "target", Value: bson.D{{Key: "$in", Value: deleted}}}},
+	}}}
+
+	if _, err := s.links.DeleteMany(ctx, linkFilter); err != nil {
+		sugar.Errorw("failed to delete ticket links", "error", err)
+	}
+
+	commentFilter := bson.D{{Key: "ticket", Value: bson.D{{Key: "$in", Value: deleted}}}}
+
+	if _, err := s.comments.DeleteMany(ctx, commentFilter); err != nil {
+		sugar.Errorw("failed to delete ticket comments", "error", err)
+	}
+
+	sugar.Debugw("bulk deleted tickets", "count", len(deleted))
+
+	return results, nil
+}
+
+// resolveBulk parses ids and looks up which tickets exist. Tickets that do not
+// are reported as not found; the others are left for the write to report.
+func (s *TicketStore) resolveBulk(ctx context.Context, ids []string) ([]BulkResult, []bson.ObjectID, error) {
+	var (
+		results   = make([]BulkResult, len(ids))
+		objectIds = make([]bson.ObjectID, len(ids))
+		valid     []bson.ObjectID
+	)
+
+	for i, id := range ids {
+		results[i] = BulkResult{ID: id}
+
+		objectId, err := bson.ObjectIDFromHex(id)
+		if err != nil {
+			results[i].Result = BulkNotFound
+			continue
+		}
+
+		objectIds[i] = objectId
+		valid = append(valid, objectId)
+	}
+
+	existing, err := s.existingIDs(ctx, valid)
+	if err != nil {
+		s.log.Sugar().Error(err)
+		return nil, nil, err
+	}
+
+	for i := range ids {
+		if results[i].Result == "" && !existing[objectIds[i]] {
+			results[i].Result = BulkNotFound
+		}
+	}
+
+	return results, objectIds, nil
+}
+
+// existingIDs returns the set of ids that identify stored tickets
+func (s *TicketStore) existingIDs(ctx context.Context, ids []bson.ObjectID) (map[bson.ObjectID]bool, error) {
+	var existing = make(map[bson.ObjectID]bool, len(ids))
+
+	if len(ids) == 0 {
+		return existing, nil
+	}
+
+	filter := bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}}
+	opts := options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	for _, doc := range docs {
+		existing[doc.ID] = true
+	}
+
+	return existing, nil
+}
+
+// bulkWrite runs writes unordered, so one failing write does not stop the
+// others, and records their outcomes in results. indexes maps each write to
+// its result; writes that succeed are recorded as success.
+func (s *TicketStore) bulkWrite(ctx context.Context, writes []mongo.WriteModel, indexes []int, results []BulkResult, success string) {
+	var sugar = s.log.Sugar()
+
+	for _, i := range indexes {
+		results[i].Result = success
+	}
+
+	_, err := s.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err == nil {
+		return
+	}
+
+	sugar.Error(err)
+
+	var bulkErr mongo.BulkWriteException
+
+	if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+		// The outcome of each write is unknown, so every one is reported as
+		// failed
+		for _, i := range indexes {
+			results[i].Result = BulkFailed
+			results[i].Error = err.Error()
+		}
+
+		return
+	}
+
+	for _, writeErr := range bulkErr.WriteErrors {
+		i := indexes[writeErr.Index]
+		results[i].Result = BulkFailed
+		results[i].Error = writeErr.Message
+	}
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+const _commentsCollection = "comments"
+
+// CommentStore provides operations for the comments left on tickets.
+// Comments are only added to existing tickets and are removed along with
+// them.
+type CommentStore struct {
+	collection *mongo.Collection
+	tickets    *mongo.Collection
+	log        *zap.Logger
+}
+
+// NewCommentStore creates a new CommentStore with a Mongo DB client config
+// and logger
+func NewCommentStore(client *mongo.Client, logger *zap.Logger) *CommentStore {
+	db := client.Database(_database)
+
+	return &CommentStore{
+		collection: db.Collection(_commentsCollection),
+		tickets:    db.Collection(_ticketsCollection),
+		log:        logger.Named("storage.comments"),
+	}
+}
+
+// AddComment adds comment to the ticket identified by comment.Ticket and
+// returns it as stored
+func (s *CommentStore) AddComment(ctx context.Context, comment models.Comment) (*models.Comment, error) {
+	var sugar = s.log.Sugar()
+
+	ticketId, err := s.resolve(ctx, comment.Ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := bson.D{
+		{Key: "ticket", Value: ticketId},
+		{Key: "author", Value: comment.Author},
+		{Key: "body", Value: comment.Body},
+		{Key: "createdOn", Value: time.Now()},
+	}
+
+	res, err := s.collection.InsertOne(ctx, doc)
+	if err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	insertedId, ok := res.InsertedID.(bson.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to decode inserted ID into ObjectID")
+	}
+
+	sugar.Debugw("added comment", "comment.id", insertedId.Hex(), "ticket.id", comment.Ticket)
+
+	var added *models.Comment
+
+	if err := s.collection.FindOne(ctx, bson.D{{Key: "_id", Value: insertedId}}).Decode(&added); err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	return added, nil
+}
+
+// FindComments returns the comments on the ticket identified by id, oldest
+// first
+func (s *CommentStore) FindComments(ctx context.Context, id string) ([]models.Comment, error) {
+	var (
+		sugar   = s.log.Sugar()
+		results = []models.Comment{}
+	)
+
+	ticketId, err := s.resolve(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdOn", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := s.collection.Find(ctx, bson.D{{Key: "ticket", Value: ticketId}}, opts)
+	if err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	if err := cursor.All(ctx, &results); err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// resolve parses the ID of a ticket and checks that it exists
+func (s *CommentStore) resolve(ctx context.Context, id string) (bson.ObjectID, error) {
+	objectId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		s.log.Sugar().Debugw("id provided is not a valid ObjectID", "error", err)
+		return objectId, ErrTicketNotFound
+	}
+
+	n, err := s.tickets.CountDocuments(ctx, bson.D{{Key: "_id", Value: objectId}})
+	if err != nil {
+		s.log.Sugar().Error(err)
+		return objectId, err
+	}
+
+	if n == 0 {
+		return objectId, ErrTicketNotFound
+	}
+
+	return objectId, nil
+}
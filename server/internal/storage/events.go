@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+// Types of ticket events
+const (
+	EventTicketCreated  = "ticket.created"
+	EventTicketUpdated  = "ticket.updated"
+	EventTicketDeleted  = "ticket.deleted"
+	EventCommentCreated = "comment.created"
+)
+
+// TicketEvent is a change to a ticket, or a comment left on one
+type TicketEvent struct {
+	// ID identifies the event, so a watch can resume after it
+	ID string `json:"-"`
+
+	Type     string `json:"type"`
+	TicketID string `json:"ticketId"`
+
+	// Ticket is the ticket as it was just after the change. It is missing
+	// when the ticket was deleted.
+	Ticket  *models.Ticket  `json:"ticket,omitempty"`
+	Comment *models.Comment `json:"comment,omitempty"`
+}
+
+// TicketWatch reads the events of the tickets as they happen. It is not safe
+// for concurrent use.
+type TicketWatch struct {
+	stream *mongo.ChangeStream
+	log    *zap.Logger
+}
+
+// changeEvent is the part of a change stream event read by a TicketWatch
+type changeEvent struct {
+	ID struct {
+		Data string `bson:"_data"`
+	} `bson:"_id"`
+	OperationType string `bson:"operationType"`
+	Namespace     struct {
+		Collection string `bson:"coll"`
+	} `bson:"ns"`
+	DocumentKey struct {
+		ID bson.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument bson.RawValue `bson:"fullDocument"`
+}
+
+// WatchTickets watches the tickets and their comments for changes made by any
+// server. Events happening after the event identified by after are read, or
+// those happening from now on when after is empty. Next waits up to wait for
+// an event. The watch must be closed.
+func (s *TicketStore) WatchTickets(ctx context.Context, after string, wait time.Duration) (watch *TicketWatch, err error) {
+	defer observe("WatchTickets", time.Now(), &err)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "$or", Value: bson.A{
+			bson.D{
+				{Key: "ns.coll", Value: _ticketsCollection},
+				{Key: "operationType", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "update", "replace", "delete"}}}},
+			},
+			bson.D{
+				{Key: "ns.coll", Value: _commentsCollection},
+				{Key: "operationType", Value: "insert"},
+			},
+		}}}}},
+	}
+
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup).SetMaxAwaitTime(wait)
+	if after != "" {
+		opts.SetResumeAfter(bson.D{{Key: "_data", Value: after}})
+	}
+
+	stream, err := s.collection.Database().Watch(ctx, pipeline, opts)
+	if err != nil {
+		s.log.Sugar().Errorw("failed to watch tickets", "after", after, "error", err)
+		return nil, err
+	}
+
+	return &TicketWatch{stream: stream, log: s.log}, nil
+}
+
+// Next returns the next event, or nil when none happened in the watch's wait
+func (w *TicketWatch) Next(ctx context.Context) (*TicketEvent, error) {
+	if !w.stream.TryNext(ctx) {
+		return nil, w.stream.Err()
+	}
+
+	var change changeEvent
+
+	if err := w.stream.Decode(&change); err != nil {
+		w.log.Sugar().Error(err)
+		return nil, err
+	}
+
+	event := &TicketEvent{ID: change.ID.Data, TicketID: change.DocumentKey.ID.Hex()}
+
+	switch change.OperationType {
+	case "insert":
+		event.Type = EventTicketCreated
+	case "update", "replace":
+		event.Type = EventTicketUpdated
+	case "delete":
+		event.Type = EventTicketDeleted
+	}
+
+	// An updated ticket is read after the update, so it may be gone already
+	doc, ok := change.FullDocument.DocumentOK()
+	if !ok {
+		return event, nil
+	}
+
+	if change.Namespace.Collection == _commentsCollection {
+		var comment models.Comment
+
+		if err := bson.Unmarshal(doc, &comment); err != nil {
+			w.log.Sugar().Error(err)
+			return nil, err
+		}
+
+		event.Type, event.TicketID, event.Comment = EventCommentCreated, comment.Ticket, &comment
+
+		return event, nil
+	}
+
+	var ticket models.Ticket
+
+	if err := bson.Unmarshal(doc, &ticket); err != nil {
+		w.log.Sugar().Error(err)
+		return nil, err
+	}
+
+	event.Ticket = &ticket
+
+	return event, nil
+}
+
+// Close stops watching
+func (w *TicketWatch) Close(ctx context.Context) error {
+	return w.stream.Close(ctx)
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+const _fieldsCollection = "fields"
+
+var (
+	ErrSchemaNotFound = errors.New("field schema not found")
+)
+
+// FieldStore provides CRUD operations for the custom field schemas of ticket
+// categories
+type FieldStore struct {
+	collection *mongo.Collection
+	log        *zap.Logger
+}
+
+// NewFieldStore creates a new FieldStore with a Mongo DB client config and
+// logger
+func NewFieldStore(client *mongo.Client, logger *zap.Logger) *FieldStore {
+	return &FieldStore{
+		collection: client.Database(_database).Collection(_fieldsCollection),
+		log:        logger.Named("storage.fields"),
+	}
+}
+
+// FindSchema finds the field schema of category
+func (s *FieldStore) FindSchema(ctx context.Context, category string) (*models.FieldSchema, error) {
+	var (
+		schema *models.FieldSchema
+		sugar  = s.log.Sugar()
+	)
+
+	res := s.collection.FindOne(ctx, bson.D{{Key: "_id", Value: category}})
+
+	if err := res.Err(); err != nil {
+		switch {
+		case errors.Is(err, mongo.ErrNoDocuments):
+			sugar.Debugw("field schema not found", "category", category)
+			return nil, ErrSchemaNotFound
+
+		default:
+			sugar.Error(err)
+			return nil, err
+		}
+	}
+
+	if err := res.Decode(&schema); err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// FindSchemas returns the field schemas of every category, sorted by category
+func (s *FieldStore) FindSchemas(ctx context.Context) ([]models.FieldSchema, error) {
+	var (
+		sugar   = s.log.Sugar()
+		results = []models.FieldSchema{}
+		opts    = options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	)
+
+	cursor, err := s.collection.Find(ctx, bson.D{}, opts)
+	if err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	if err := cursor.All(ctx, &results); err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	sugar.Debugw("retrieved field schemas", "count", len(results))
+
+	return results, nil
+}
+
+// SaveSchema creates or replaces the field schema of schema.Category. Values
+// already stored on tickets are left untouched.
+func (s *FieldStore) SaveSchema(ctx context.Context, schema models.FieldSchema) (*models.FieldSchema, error) {
+	var (
+		sugar  = s.log.Sugar()
+		filter = bson.D{{Key: "_id", Value: schema.Category}}
+		update = bson.D{{Key: "$set", Value: bson.D{
+			{Key: "fields", Value: nonNil(schema.Fields)},
+			{Key: "updatedAt", Value: time.Now()},
+		}}}
+		opts = options.UpdateOne().SetUpsert(true)
+	)
+
+	if _, err := s.collection.UpdateOne(ctx, filter, update, opts); err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	sugar.Debugw("saved field schema", "category", schema.Category, "fields", len(schema.Fields))
+
+	return s.FindSchema(ctx, schema.Category)
+}
+
+// DeleteSchema removes the field schema of category
+func (s *FieldStore) DeleteSchema(ctx context.Context, category string) error {
+	var sugar = s.log.Sugar()
+
+	res, err := s.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: category}})
+	if err != nil {
+		sugar.Error(err)
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		sugar.Debugw("field schema not found", "category", category)
+		return ErrSchemaNotFound
+	}
+
+	sugar.Debugw("deleted field schema", "category", category)
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// _customFieldPrefix marks a custom field in filter and sort keys, e.g.
+// "cf.assetTag"
+const _customFieldPrefix = "cf."
+
+var (
+	ErrInvalidFilter = errors.New("invalid filter")
+
+	// _sortableFields lists the built-in ticket fields tickets can be sorted by
+	_sortableFields = []string{
+		"title",
+		"site",
+		"category",
+		"assignedTo",
+		"createdBy",
+		"priority",
+		"status",
+		"createdOn",
+		"updatedAt",
+	}
+)
+
+// TicketFilter narrows the tickets returned by FindTickets. Zero-valued
+// fields do not filter anything.
+type TicketFilter struct {
+	// Query matches against a ticket's title and description
+	Query string
+
+	// Site matches tickets raised at the site
+	Site string
+
+	// Statuses matches tickets with any of the statuses
+	Statuses []string
+
+	// CreatedAfter matches tickets created after the time
+	CreatedAfter time.Time
+
+	// AnyTags matches tickets carrying at least one of the tags
+	AnyTags []string
+
+	// AllTags matches tickets carrying every one of the tags
+	AllTags []string
+
+	// NoTags matches tickets carrying none of the tags
+	NoTags []string
+
+	// CustomFields matches tickets whose custom fields equal the given
+	// values. Numeric values also match numbers, and dates match dates: a
+	// date like 2024-05-01 matches any time that day, in UTC.
+	CustomFields map[string]string
+
+	// Sort orders the results by a comma-separated list of fields, each
+	// optionally prefixed with "-" for descending order. Custom fields are
+	// prefixed with "cf.", e.g. "-priority,cf.serial".
+	Sort string
+
+	// Limit caps the number of results when positive
+	Limit int
+
+	// Offset skips that many results when positive, for reading results a
+	// page at a time
+	Offset int
+}
+
+// IsZero reports whether filter matches every ticket
+func (f TicketFilter) IsZero() bool {
+	return f.Query == "" && f.Site == "" && len(f.Statuses) == 0 && f.CreatedAfter.IsZero() &&
+		len(f.AnyTags) == 0 && len(f.AllTags) == 0 && len(f.NoTags) == 0 && len(f.CustomFields) == 0
+}
+
+// Validate checks that the custom fields and sort keys of filter can be
+// turned into a query. Errors wrap ErrInvalidFilter.
+func (f TicketFilter) Validate() error {
+	for key := range f.CustomFields {
+		if !models.IsValidFieldKey(key) {
+			return fmt.Errorf("%w: invalid custom field %q", ErrInvalidFilter, key)
+		}
+	}
+
+	for _, key := range sortKeys(f.Sort) {
+		field := strings.TrimPrefix(key, "-")
+
+		if custom, ok := strings.CutPrefix(field, _customFieldPrefix); ok {
+			if !models.IsValidFieldKey(custom) {
+				return fmt.Errorf("%w: invalid custom field %q", ErrInvalidFilter, custom)
+			}
+
+			continue
+		}
+
+		if !slices.Contains(_sortableFields, field) {
+			return fmt.Errorf("%w: cannot sort by %q", ErrInvalidFilter, field)
+		}
+	}
+
+	return nil
+}
+
+// document builds the Mongo query for filter
+func (f TicketFilter) document() bson.D {
+	// Tickets merged into another are only reachable through the ticket
+	// they were merged into
+	var filter = bson.D{{Key: "mergedInto", Value: nil}}
+
+	if f.Query != "" {
+		filter = append(filter, bson.E{Key: "$or", Value: []bson.D{
+			{{Key: "title", Value: bson.D{
+				{Key: "$regex", Value: f.Query},
+				{Key: "$options", Value: "i"},
+			}}},
+			{{Key: "description", Value: bson.D{
+				{Key: "$regex", Value: f.Query},
+				{Key: "$options", Value: "i"},
+			}}},
+		}})
+	}
+
+	if f.Site != "" {
+		filter = append(filter, bson.E{Key: "site", Value: f.Site})
+	}
+
+	if len(f.Statuses) > 0 {
+		filter = append(filter, bson.E{Key: "status", Value: bson.D{{Key: "$in", Value: f.Statuses}}})
+	}
+
+	if !f.CreatedAfter.IsZero() {
+		filter = append(filter, bson.E{Key: "createdOn", Value: bson.D{{Key: "$gt", Value: f.CreatedAfter}}})
+	}
+
+	// Each tag and custom field condition is a separate clause so that they
+	// can be combined
+	var clauses []bson.D
+
+	if len(f.AnyTags) > 0 {
+		clauses = append(clauses, bson.D{{Key: "tags", Value: bson.D{
+			{Key: "$in", Value: models.NormalizeTags(f.AnyTags)},
+		}}})
+	}
+
+	if len(f.AllTags) > 0 {
+		clauses = append(clauses, bson.D{{Key: "tags", Value: bson.D{
+			{Key: "$all", Value: models.NormalizeTags(f.AllTags)},
+		}}})
+	}
+
+	if len(f.NoTags) > 0 {
+		clauses = append(clauses, bson.D{{Key: "tags", Value: bson.D{
+			{Key: "$nin", Value: models.NormalizeTags(f.NoTags)},
+		}}})
+	}
+
+	for key, value := range f.CustomFields {
+		clauses = append(clauses, customFieldClause("customFields."+key, value))
+	}
+
+	if len(clauses) > 0 {
+		filter = append(filter, bson.E{Key: "$and", Value: clauses})
+	}
+
+	return filter
+}
+
+// customFieldClause builds the condition matching the custom field at path
+// against value, which is a string from a query parameter. Custom fields are
+// stored with their type, so value also matches the number or date it reads
+// as.
+func customFieldClause(path, value string) bson.D {
+	values := []any{value}
+
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		values = append(values, number)
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		values = append(values, t.UTC())
+	}
+
+	clause := bson.D{{Key: path, Value: bson.D{{Key: "$in", Value: values}}}}
+
+	// A date without a time matches any time that day
+	if day, err := time.Parse(time.DateOnly, value); err == nil {
+		clause = bson.D{{Key: "$or", Value: []bson.D{
+			clause,
+			{{Key: path, Value: bson.D{
+				{Key: "$gte", Value: day},
+				{Key: "$lt", Value: day.AddDate(0, 0, 1)},
+			}}},
+		}}}
+	}
+
+	return clause
+}
+
+// sortDocument builds the Mongo sort specification for filter. Validate
+// should be called first; unknown keys are skipped.
+func (f TicketFilter) sortDocument() bson.D {
+	var sort bson.D
+
+	for _, key := range sortKeys(f.Sort) {
+		var (
+			field     = strings.TrimPrefix(key, "-")
+			direction = 1
+		)
+
+		if strings.HasPrefix(key, "-") {
+			direction = -1
+		}
+
+		if custom, ok := strings.CutPrefix(field, _customFieldPrefix); ok {
+			if models.IsValidFieldKey(custom) {
+				sort = append(sort, bson.E{Key: "customFields." + custom, Value: direction})
+			}
+
+			continue
+		}
+
+		if slices.Contains(_sortableFields, field) {
+			sort = append(sort, bson.E{Key: field, Value: direction})
+		}
+	}
+
+	return sort
+}
+
+// sortKeys splits a comma-separated sort parameter into its keys
+func sortKeys(sort string) []string {
+	var keys []string
+
+	for _, key := range strings.Split(sort, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
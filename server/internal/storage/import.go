@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ImportResult is the outcome of importing a single ticket: the ID it was
+// stored under, or the error that kept it from being stored
+type ImportResult struct {
+	ID  string
+	Err error
+}
+
+// ImportTickets inserts tickets brought over from another system in a single
+// unordered batch and returns the outcome for each ticket, in order. A ticket
+// that cannot be stored does not keep the others from being stored; an error
+// is only returned when it is unknown which tickets were stored. Unlike
+// CreateTicket, tickets keep their original timestamps and assignees and are
+// not routed through queues. Tickets without a creation time are stamped with
+// the current time, and resolved tickets without a resolution time are taken
+// to be resolved at their last update.
+func (s *TicketStore) ImportTickets(ctx context.Context, tickets []models.Ticket) (_ []ImportResult, err error) {
+	defer observe("ImportTickets", time.Now(), &err)
+
+	var (
+		sugar = s.log.Sugar()
+		now   = time.Now()
+		docs  = make([]bson.D, 0, len(tickets))
+	)
+
+	for _, ticket := range tickets {
+		if ticket.CreatedOn.IsZero() {
+			ticket.CreatedOn = now
+		}
+
+		if ticket.UpdatedAt.IsZero() {
+			ticket.UpdatedAt = ticket.CreatedOn
+		}
+
+		switch {
+		case !models.IsResolved(ticket.Status):
+			ticket.ClosedOn = time.Time{}
+		case ticket.ClosedOn.IsZero():
+			ticket.ClosedOn = ticket.UpdatedAt
+		}
+
+		docs = append(docs, ticketDocument(ticket))
+	}
+
+	res, err := s.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+
+	var bulkErr mongo.BulkWriteException
+
+	if err != nil && (!errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 || res == nil) {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	// The IDs of every ticket are generated before inserting, whether or not
+	// the ticket was stored
+	results := make([]ImportResult, len(tickets))
+
+	for i, insertedId := range res.InsertedIDs {
+		objectId, ok := insertedId.(bson.ObjectID)
+		if !ok {
+			return nil, errors.New("failed to decode inserted ID into ObjectID")
+		}
+
+		results[i].ID = objectId.Hex()
+	}
+
+	for _, writeErr := range bulkErr.WriteErrors {
+		results[writeErr.Index] = ImportResult{Err: errors.New(writeErr.Message)}
+	}
+
+	if err != nil {
+		sugar.Errorw("failed to import some tickets", "count", len(bulkErr.WriteErrors), "error", err)
+	}
+
+	sugar.Debugw("imported tickets", "count", len(tickets)-len(bulkErr.WriteErrors))
+
+	return results, nil
+}
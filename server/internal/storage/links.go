@@ -0,0 +1,325 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+const _linksCollection = "links"
+
+var (
+	ErrLinkNotFound = errors.New("link not found")
+	ErrLinkExists   = errors.New("link already exists")
+	ErrLinkCycle    = errors.New("link would create a cycle")
+	ErrInvalidLink  = errors.New("invalid link")
+)
+
+// LinkStore provides operations for typed links between tickets. Links are
+// only created between existing tickets and are removed along with the
+// tickets they connect.
+type LinkStore struct {
+	collection *mongo.Collection
+	tickets    *mongo.Collection
+	log        *zap.Logger
+}
+
+// NewLinkStore creates a new LinkStore with a Mongo DB client config and
+// logger
+func NewLinkStore(client *mongo.Client, logger *zap.Logger) *LinkStore {
+	db := client.Database(_database)
+
+	return &LinkStore{
+		collection: db.Collection(_linksCollection),
+		tickets:    db.Collection(_ticketsCollection),
+		log:        logger.Named("storage.links"),
+	}
+}
+
+// CreateLink links link.Source to link.Target. Both tickets must exist, a
+// ticket has at most one parent and is a duplicate of at most one ticket,
+// and parent, duplicate-of and blocks links may not form cycles.
+func (s *LinkStore) CreateLink(ctx context.Context, link models.Link) (*models.Link, error) {
+	var sugar = s.log.Sugar()
+
+	if !models.IsValidLinkType(link.Type) {
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidLink, link.Type)
+	}
+
+	source, target, err := s.resolve(ctx, link.Source, link.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	if source == target {
+		return nil, fmt.Errorf("%w: a ticket cannot be linked to itself", ErrInvalidLink)
+	}
+
+	if err := s.checkExisting(ctx, link.Type, source, target); err != nil {
+		return nil, err
+	}
+
+	if models.IsAcyclicLinkType(link.Type) {
+		cyclic, err := s.reaches(ctx, link.Type, target, source)
+		if err != nil {
+			sugar.Error(err)
+			return nil, err
+		}
+
+		if cyclic {
+			return nil, ErrLinkCycle
+		}
+	}
+
+	doc := bson.D{
+		{Key: "type", Value: link.Type},
+		{Key: "source", Value: source},
+		{Key: "target", Value: target},
+		{Key: "createdOn", Value: time.Now()},
+	}
+
+	res, err := s.collection.InsertOne(ctx, doc)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrLinkExists
+		}
+
+		sugar.Error(err)
+
+		return nil, err
+	}
+
+	insertedId, ok := res.InsertedID.(bson.ObjectID)
+	if !ok {
+		return nil, errors.New("failed to decode inserted ID into ObjectID")
+	}
+
+	sugar.Debugw("created link", "link.id", insertedId.Hex(), "type", link.Type, "source", link.Source, "target", link.Target)
+
+	return s.findLink(ctx, insertedId)
+}
+
+// FindLinks returns every link to or from the ticket identified by id
+func (s *LinkStore) FindLinks(ctx context.Context, id string) ([]models.Link, error) {
+	var (
+		sugar   = s.log.Sugar()
+		results = []models.Link{}
+	)
+
+	objectId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		sugar.Debugw("id provided is not a valid ObjectID", "error", err)
+		return nil, ErrTicketNotFound
+	}
+
+	n, err := s.tickets.CountDocuments(ctx, bson.D{{Key: "_id", Value: objectId}})
+	if err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	if n == 0 {
+		return nil, ErrTicketNotFound
+	}
+
+	filter := bson.D{{Key: "$or", Value: []bson.D{
+		{{Key: "source", Value: objectId}},
+		{{Key: "target", Value: objectId}},
+	}}}
+	opts := options.Find().SetSort(bson.D{{Key: "createdOn", Value: 1}})
+
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	if err := cursor.All(ctx, &results); err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// DeleteLink removes the link identified by linkId, which must involve the
+// ticket identified by id
+func (s *LinkStore) DeleteLink(ctx context.Context, id, linkId string) error {
+	var sugar = s.log.Sugar()
+
+	ticketObjectId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrLinkNotFound
+	}
+
+	linkObjectId, err := bson.ObjectIDFromHex(linkId)
+	if err != nil {
+		return ErrLinkNotFound
+	}
+
+	filter := bson.D{
+		{Key: "_id", Value: linkObjectId},
+		{Key: "$or", Value: []bson.D{
+			{{Key: "source", Value: ticketObjectId}},
+			{{Key: "target", Value: ticketObjectId}},
+		}},
+	}
+
+	res, err := s.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		sugar.Error(err)
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return ErrLinkNotFound
+	}
+
+	sugar.Debugw("deleted link", "link.id", linkId, "ticket.id", id)
+
+	return nil
+}
+
+// findLink finds a link by its ID
+func (s *LinkStore) findLink(ctx context.Context, id bson.ObjectID) (*models.Link, error) {
+	var link *models.Link
+
+	res := s.collection.FindOne(ctx, bson.D{{Key: "_id", Value: id}})
+	if err := res.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrLinkNotFound
+		}
+
+		return nil, err
+	}
+
+	if err := res.Decode(&link); err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// resolve parses the source and target IDs and checks that both tickets
+// exist
+func (s *LinkStore) resolve(ctx context.Context, sourceId, targetId string) (source, target bson.ObjectID, err error) {
+	source, err = bson.ObjectIDFromHex(sourceId)
+	if err != nil {
+		return source, target, ErrTicketNotFound
+	}
+
+	target, err = bson.ObjectIDFromHex(targetId)
+	if err != nil {
+		return source, target, ErrTicketNotFound
+	}
+
+	ids := []bson.ObjectID{source, target}
+
+	n, err := s.tickets.CountDocuments(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}})
+	if err != nil {
+		return source, target, err
+	}
+
+	// A self-link counts a single document; it is rejected by the caller
+	if (source == target && n != 1) || (source != target && n != 2) {
+		return source, target, ErrTicketNotFound
+	}
+
+	return source, target, nil
+}
+
+// checkExisting rejects links that duplicate an existing link or give a
+// ticket a second parent or a second original
+func (s *LinkStore) checkExisting(ctx context.Context, linkType string, source, target bson.ObjectID) error {
+	var clauses = []bson.D{
+		{{Key: "type", Value: linkType}, {Key: "source", Value: source}, {Key: "target", Value: target}},
+	}
+
+	switch linkType {
+	case models.LinkRelated:
+		// Related links are symmetric, so the reverse link is a duplicate
+		clauses = append(clauses, bson.D{
+			{Key: "type", Value: linkType}, {Key: "source", Value: target}, {Key: "target", Value: source},
+		})
+	case models.LinkParent:
+		n, err := s.collection.CountDocuments(ctx, bson.D{{Key: "type", Value: linkType}, {Key: "target", Value: target}})
+		if err != nil {
+			return err
+		}
+
+		if n > 0 {
+			return fmt.Errorf("%w: ticket already has a parent", ErrInvalidLink)
+		}
+	case models.LinkDuplicate:
+		n, err := s.collection.CountDocuments(ctx, bson.D{{Key: "type", Value: linkType}, {Key: "source", Value: source}})
+		if err != nil {
+			return err
+		}
+
+		if n > 0 {
+			return fmt.Errorf("%w: ticket is already a duplicate", ErrInvalidLink)
+		}
+	}
+
+	n, err := s.collection.CountDocuments(ctx, bson.D{{Key: "$or", Value: clauses}})
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		return ErrLinkExists
+	}
+
+	return nil
+}
+
+// reaches reports whether to can be reached from from by following links of
+// linkType from source to target
+func (s *LinkStore) reaches(ctx context.Context, linkType string, from, to bson.ObjectID) (bool, error) {
+	var (
+		frontier = []bson.ObjectID{from}
+		visited  = map[bson.ObjectID]bool{from: true}
+	)
+
+	for len(frontier) > 0 {
+		if visited[to] {
+			return true, nil
+		}
+
+		filter := bson.D{
+			{Key: "type", Value: linkType},
+			{Key: "source", Value: bson.D{{Key: "$in", Value: frontier}}},
+		}
+
+		cursor, err := s.collection.Find(ctx, filter)
+		if err != nil {
+			return false, err
+		}
+
+		var links []struct {
+			Target bson.ObjectID `bson:"target"`
+		}
+
+		if err := cursor.All(ctx, &links); err != nil {
+			return false, err
+		}
+
+		frontier = nil
+
+		for _, link := range links {
+			if !visited[link.Target] {
+				visited[link.Target] = true
+				frontier = append(frontier, link.Target)
+			}
+		}
+	}
+
+	return visited[to], nil
+}
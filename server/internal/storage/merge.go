@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var (
+	ErrInvalidMerge = errors.New("invalid merge")
+)
+
+// MergeTickets folds the ticket identified by sourceId into the ticket
+// identified by targetId and returns the updated target. The target gains
+// the source's tags, any custom fields it does not already have, and the
+// source's links. The source is closed and records the target it was merged
+// into; the target records the source it absorbed. All changes are made in
+// a single transaction.
+func (s *TicketStore) MergeTickets(ctx context.Context, sourceId, targetId string) (_ *models.Ticket, err error) {
+	defer observe("MergeTickets", time.Now(), &err)
+
+	var sugar = s.log.Sugar()
+
+	source, err := bson.ObjectIDFromHex(sourceId)
+	if err != nil {
+		return nil, ErrTicketNotFound
+	}
+
+	target, err := bson.ObjectIDFromHex(targetId)
+	if err != nil {
+		return nil, ErrTicketNotFound
+	}
+
+	if source == target {
+		return nil, fmt.Errorf("%w: a ticket cannot be merged into itself", ErrInvalidMerge)
+	}
+
+	session, err := s.collection.Database().Client().StartSession()
+	if err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(txCtx context.Context) (any, error) {
+		return nil, s.merge(txCtx, source, target)
+	})
+	if err != nil {
+		if !errors.Is(err, ErrTicketNotFound) && !errors.Is(err, ErrInvalidMerge) {
+			sugar.Error(err)
+		}
+
+		return nil, err
+	}
+
+	sugar.Debugw("merged tickets", "source", sourceId, "target", targetId)
+
+	return s.FindTicket(ctx, targetId)
+}
+
+// merge performs the writes of MergeTickets. It must run inside a
+// transaction and may be retried, so it only depends on the stored state.
+func (s *TicketStore) merge(ctx context.Context, source, target bson.ObjectID) error {
+	var now = time.Now()
+
+	sourceTicket, err := s.findUnmerged(ctx, source)
+	if err != nil {
+		return err
+	}
+
+	targetTicket, err := s.findUnmerged(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	if err := s.moveLinks(ctx, source, target); err != nil {
+		return err
+	}
+
+	// The target keeps its own custom field values and gains the ones only
+	// the source has
+	targetSet := bson.D{{Key: "updatedAt", Value: now}}
+
+	for key, value := range sourceTicket.CustomFields {
+		if _, ok := targetTicket.CustomFields[key]; !ok {
+			targetSet = append(targetSet, bson.E{Key: "customFields." + key, Value: value})
+		}
+	}
+
+	targetUpdate := bson.D{
+		{Key: "$set", Value: targetSet},
+		{Key: "$addToSet", Value: bson.D{
+			{Key: "tags", Value: bson.D{{Key: "$each", Value: models.NormalizeTags(sourceTicket.Tags)}}},
+			{Key: "mergedFrom", Value: source},
+		}},
+	}
+
+	if _, err := s.collection.UpdateByID(ctx, target, targetUpdate); err != nil {
+		return err
+	}
+
+	sourceSet := bson.D{
+		{Key: "mergedInto", Value: target},
+		{Key: "status", Value: models.StatusClosed},
+		{Key: "updatedAt", Value: now},
+	}
+
+	if sourceTicket.ClosedOn.IsZero() {
+		sourceSet = append(sourceSet, bson.E{Key: "closedOn", Value: now})
+	}
+
+	sourceUpdate := bson.D{{Key: "$set", Value: sourceSet}}
+
+	_, err = s.collection.UpdateByID(ctx, source, sourceUpdate)
+
+	return err
+}
+
+// findUnmerged loads a ticket taking part in a merge, which must not have
+// been merged already
+func (s *TicketStore) findUnmerged(ctx context.Context, id bson.ObjectID) (*models.Ticket, error) {
+	var ticket *models.Ticket
+
+	res := s.collection.FindOne(ctx, bson.D{{Key: "_id", Value: id}})
+	if err := res.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrTicketNotFound
+		}
+
+		return nil, err
+	}
+
+	if err := res.Decode(&ticket); err != nil {
+		return nil, err
+	}
+
+	if ticket.MergedInto != "" {
+		return nil, fmt.Errorf("%w: ticket %s has already been merged", ErrInvalidMerge, id.Hex())
+	}
+
+	return ticket, nil
+}
+
+// moveLinks re-points the links of source to target. Links between the two
+// tickets, links the target already has, and parent links that would give
+// the target a second parent are dropped.
+func (s *TicketStore) moveLinks(ctx context.Context, source, target bson.ObjectID) error {
+	type link struct {
+		Type      string        `bson:"type"`
+		Source    bson.ObjectID `bson:"source"`
+		Target    bson.ObjectID `bson:"target"`
+		CreatedOn time.Time     `bson:"createdOn"`
+	}
+
+	involving := func(id bson.ObjectID) bson.D {
+		return bson.D{{Key: "$or", Value: []bson.D{
+			{{Key: "source", Value: id}},
+			{{Key: "target", Value: id}},
+		}}}
+	}
+
+	var sourceLinks, targetLinks []link
+
+	for id, links := range map[bson.ObjectID]*[]link{source: &sourceLinks, target: &targetLinks} {
+		cursor, err := s.links.Find(ctx, involving(id))
+		if err != nil {
+			return err
+		}
+
+		if err := cursor.All(ctx, links); err != nil {
+			return err
+		}
+	}
+
+	var (
+		key = func(l link) string {
+			return l.Type + ":" + l.Source.Hex() + ":" + l.Target.Hex()
+		}
+		existing  = make(map[string]bool, len(targetLinks))
+		hasParent bool
+		moved     []any
+	)
+
+	for _, l := range targetLinks {
+		existing[key(l)] = true
+
+		if l.Type == models.LinkParent && l.Target == target {
+			hasParent = true
+		}
+	}
+
+	for _, l := range sourceLinks {
+		if l.Source == source {
+			l.Source = target
+		}
+
+		if l.Target == source {
+			l.Target = target
+		}
+
+		if l.Source == l.Target || existing[key(l)] {
+			continue
+		}
+
+		// Related links are symmetric, so the reverse link is a duplicate
+		reverse := link{Type: l.Type, Source: l.Target, Target: l.Source}
+		if l.Type == models.LinkRelated && existing[key(reverse)] {
+			continue
+		}
+
+		if l.Type == models.LinkParent && l.Target == target {
+			if hasParent {
+				continue
+			}
+
+			hasParent = true
+		}
+
+		existing[key(l)] = true
+		moved = append(moved, l)
+	}
+
+	if _, err := s.links.DeleteMany(ctx, involving(source)); err != nil {
+		return err
+	}
+
+	if len(moved) == 0 {
+		return nil
+	}
+
+	_, err := s.links.InsertMany(ctx, moved)
+
+	return err
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/metrics"
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	_operationDuration = metrics.Factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "nestqueue_store_operation_duration_seconds",
+		Help:    "How long ticket store operations take.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation"})
+	_operationErrors = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "nestqueue_store_operation_errors_total",
+		Help: "Ticket store operations failing for reasons other than invalid or missing tickets.",
+	}, []string{"operation"})
+)
+
+// _expectedErrors are errors caused by the caller rather than the store,
+// which are not counted as failures
+var _expectedErrors = []error{ErrTicketNotFound, ErrInvalidFilter, ErrInvalidMerge, ErrInvalidLink}
+
+// observe records how long the operation started at start took and whether it
+// failed. It is deferred by each TicketStore operation with its named error.
+func observe(operation string, start time.Time, err *error) {
+	_operationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	if *err == nil {
+		return
+	}
+
+	for _, expected := range _expectedErrors {
+		if errors.Is(*err, expected) {
+			return
+		}
+	}
+
+	_operationErrors.WithLabelValues(operation).Inc()
+}
+
+// OpenTickets counts the open tickets at a site with a status and priority
+type OpenTickets struct {
+	Site     string `bson:"site"`
+	Status   string `bson:"status"`
+	Priority int    `bson:"priority"`
+	Count    int    `bson:"count"`
+}
+
+// CountOpenTickets counts the open tickets by site, status and priority
+func (s *TicketStore) CountOpenTickets(ctx context.Context) (counts []OpenTickets, err error) {
+	defer observe("CountOpenTickets", time.Now(), &err)
+
+	pipeline := bson.A{
+		bson.D{{Key: "$match", Value: bson.D{{Key: "status", Value: bson.D{{Key: "$in", Value: models.OpenStatuses}}}}}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "site", Value: "$site"}, {Key: "status", Value: "$status"}, {Key: "priority", Value: "$priority"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "site", Value: "$_id.site"},
+			{Key: "status", Value: "$_id.status"},
+			{Key: "priority", Value: "$_id.priority"},
+			{Key: "count", Value: 1},
+		}}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,513 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+const _queuesCollection = "queues"
+
+var (
+	ErrQueueNotFound  = errors.New("queue not found")
+	ErrMemberNotFound = errors.New("queue member not found")
+	ErrMemberExists   = errors.New("queue member already exists")
+)
+
+// QueueStore provides CRUD operations for assignment queues and routes new
+// tickets to their members
+type QueueStore struct {
+	collection *mongo.Collection
+	tickets    *mongo.Collection
+	log        *zap.Logger
+}
+
+// NewQueueStore creates a new QueueStore with a Mongo DB client config and
+// logger
+func NewQueueStore(client *mongo.Client, logger *zap.Logger) *QueueStore {
+	db := client.Database(_database)
+
+	return &QueueStore{
+		collection: db.Collection(_queuesCollection),
+		tickets:    db.Collection(_ticketsCollection),
+		log:        logger.Named("storage.queues"),
+	}
+}
+
+// CreateQueue adds a new queue to the store
+func (s *QueueStore) CreateQueue(ctx context.Context, queue models.Queue) (id string, err error) {
+	var (
+		sugar = s.log.Sugar()
+		now   = time.Now()
+	)
+
+	doc := bson.D{
+		{Key: "name", Value: queue.Name},
+		{Key: "sites", Value: nonNil(queue.Sites)},
+		{Key: "categories", Value: nonNil(queue.Categories)},
+		{Key: "strategy", Value: queue.Strategy},
+		{Key: "members", Value: nonNil(queue.Members)},
+		{Key: "cursor", Value: 0},
+		{Key: "createdOn", Value: now},
+		{Key: "updatedAt", Value: now},
+	}
+
+	res, err := s.collection.InsertOne(ctx, doc)
+	if err != nil {
+		return "", err
+	}
+
+	insertedId, ok := res.InsertedID.(bson.ObjectID)
+	if !ok {
+		return "", errors.New("failed to decode inserted ID into ObjectID")
+	}
+
+	sugar.Debugw("created new queue", "id", insertedId.Hex())
+
+	return insertedId.Hex(), nil
+}
+
+// FindQueue finds a queue by its ID
+func (s *QueueStore) FindQueue(ctx context.Context, id string) (*models.Queue, error) {
+	var (
+		queue *models.Queue
+		sugar = s.log.Sugar()
+	)
+
+	objectId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		sugar.Debugw("id provided is not a valid ObjectID", "error", err)
+		return nil, ErrQueueNotFound
+	}
+
+	res := s.collection.FindOne(ctx, bson.D{{Key: "_id", Value: objectId}})
+
+	if err := res.Err(); err != nil {
+		switch {
+		case errors.Is(err, mongo.ErrNoDocuments):
+			sugar.Debugw("queue not found", "queue.id", id)
+			return nil, ErrQueueNotFound
+
+		default:
+			sugar.Error(err)
+			return nil, err
+		}
+	}
+
+	if err := res.Decode(&queue); err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	return queue, nil
+}
+
+// FindQueues returns all queues sorted by name
+func (s *QueueStore) FindQueues(ctx context.Context) ([]models.Queue, error) {
+	var (
+		sugar   = s.log.Sugar()
+		results = []models.Queue{}
+		opts    = options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	)
+
+	cursor, err := s.collection.Find(ctx, bson.D{}, opts)
+	if err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	if err := cursor.All(ctx, &results); err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	sugar.Debugw("retrieved queues", "count", len(results))
+
+	return results, nil
+}
+
+// QueueUpdate holds the fields of a queue to change. Fields left nil are not
+// changed.
+type QueueUpdate struct {
+	Name       *string   `json:"name"`
+	Sites      *[]string `json:"sites"`
+	Categories *[]string `json:"categories"`
+	Strategy   *string   `json:"strategy"`
+}
+
+// IsZero reports whether update changes nothing
+func (u QueueUpdate) IsZero() bool {
+	return u == QueueUpdate{}
+}
+
+// UpdateQueue updates the name, sites, categories or strategy of an existing
+// queue. Members are managed with AddMember, UpdateMember and RemoveMember.
+func (s *QueueStore) UpdateQueue(ctx context.Context, id string, update QueueUpdate) (*models.Queue, error) {
+	var (
+		updatesDoc = bson.D{}
+		sugar      = s.log.Sugar()
+	)
+
+	objectId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		sugar.Debugw("bad id provided", "error", err)
+		return nil, ErrQueueNotFound
+	}
+
+	// Populate updatesDoc based on the fields set in update
+
+	if update.Name != nil {
+		updatesDoc = append(updatesDoc, bson.E{Key: "name", Value: *update.Name})
+	}
+
+	if update.Sites != nil {
+		updatesDoc = append(updatesDoc, bson.E{Key: "sites", Value: nonNil(*update.Sites)})
+	}
+
+	if update.Categories != nil {
+		updatesDoc = append(updatesDoc, bson.E{Key: "categories", Value: nonNil(*update.Categories)})
+	}
+
+	if update.Strategy != nil {
+		updatesDoc = append(updatesDoc, bson.E{Key: "strategy", Value: *update.Strategy})
+	}
+
+	updatesDoc = append(updatesDoc, bson.E{Key: "updatedAt", Value: time.Now()})
+
+	res, err := s.collection.UpdateByID(ctx, objectId, bson.D{{Key: "$set", Value: updatesDoc}})
+	if err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	if res.MatchedCount == 0 {
+		return nil, ErrQueueNotFound
+	}
+
+	sugar.Debugw("queue updated", "queue.id", id, "updates", len(updatesDoc)-1)
+
+	return s.FindQueue(ctx, id)
+}
+
+// DeleteQueue removes a queue from the store. Tickets already assigned by the
+// queue keep their assignee.
+func (s *QueueStore) DeleteQueue(ctx context.Context, id string) error {
+	var sugar = s.log.Sugar()
+
+	objectId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		sugar.Debugw("bad id provided", "error", err)
+		return ErrQueueNotFound
+	}
+
+	res, err := s.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: objectId}})
+	if err != nil {
+		sugar.Error(err)
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		sugar.Debugw("queue not found", "queue.id", id)
+		return ErrQueueNotFound
+	}
+
+	sugar.Debugw("deleted queue", "queue.id", id)
+
+	return nil
+}
+
+// AddMember appends member to the queue identified by id. Members are
+// identified by email, which must be unique within a queue.
+func (s *QueueStore) AddMember(ctx context.Context, id string, member models.QueueMember) (*models.Queue, error) {
+	var sugar = s.log.Sugar()
+
+	objectId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		sugar.Debugw("bad id provided", "error", err)
+		return nil, ErrQueueNotFound
+	}
+
+	filter := bson.D{
+		{Key: "_id", Value: objectId},
+		{Key: "members.email", Value: bson.D{{Key: "$ne", Value: member.Email}}},
+	}
+	update := bson.D{
+		{Key: "$push", Value: bson.D{{Key: "members", Value: member}}},
+		{Key: "$set", Value: bson.D{{Key: "updatedAt", Value: time.Now()}}},
+	}
+
+	res, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	// Nothing matched: either the queue does not exist or the member is
+	// already part of it
+	if res.MatchedCount == 0 {
+		if _, err := s.FindQueue(ctx, id); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrMemberExists
+	}
+
+	sugar.Debugw("added queue member", "queue.id", id, "member", member.Email)
+
+	return s.FindQueue(ctx, id)
+}
+
+// UpdateMember sets the availability of a member of the queue identified by id
+func (s *QueueStore) UpdateMember(ctx context.Context, id, email string, available bool) (*models.Queue, error) {
+	var sugar = s.log.Sugar()
+
+	objectId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		sugar.Debugw("bad id provided", "error", err)
+		return nil, ErrQueueNotFound
+	}
+
+	filter := bson.D{
+		{Key: "_id", Value: objectId},
+		{Key: "members.email", Value: email},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "members.$.available", Value: available},
+		{Key: "updatedAt", Value: time.Now()},
+	}}}
+
+	res, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	if res.MatchedCount == 0 {
+		if _, err := s.FindQueue(ctx, id); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrMemberNotFound
+	}
+
+	sugar.Debugw("updated queue member", "queue.id", id, "member", email, "available", available)
+
+	return s.FindQueue(ctx, id)
+}
+
+// RemoveMember removes a member from the queue identified by id
+func (s *QueueStore) RemoveMember(ctx context.Context, id, email string) (*models.Queue, error) {
+	var sugar = s.log.Sugar()
+
+	objectId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		sugar.Debugw("bad id provided", "error", err)
+		return nil, ErrQueueNotFound
+	}
+
+	filter := bson.D{
+		{Key: "_id", Value: objectId},
+		{Key: "members.email", Value: email},
+	}
+	update := bson.D{
+		{Key: "$pull", Value: bson.D{{Key: "members", Value: bson.D{{Key: "email", Value: email}}}}},
+		{Key: "$set", Value: bson.D{{Key: "updatedAt", Value: time.Now()}}},
+	}
+
+	res, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	if res.MatchedCount == 0 {
+		if _, err := s.FindQueue(ctx, id); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrMemberNotFound
+	}
+
+	sugar.Debugw("removed queue member", "queue.id", id, "member", email)
+
+	return s.FindQueue(ctx, id)
+}
+
+// Route picks an assignee for ticket. The most specific queue matching the
+// ticket's site and category with at least one available member is used, and
+// the assignee is chosen according to that queue's strategy. An empty string
+// is returned when no queue can take the ticket.
+func (s *QueueStore) Route(ctx context.Context, ticket models.Ticket) (string, error) {
+	var sugar = s.log.Sugar()
+
+	queues, err := s.FindQueues(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// Prefer queues constraining both site and category over catch-all queues
+	slices.SortStableFunc(queues, func(a, b models.Queue) int {
+		return cmp.Compare(b.Specificity(), a.Specificity())
+	})
+
+	for _, queue := range queues {
+		if !queue.Matches(ticket) || len(queue.AvailableMembers()) == 0 {
+			continue
+		}
+
+		var assignee string
+
+		switch queue.Strategy {
+		case models.StrategyLeastOpen:
+			assignee, err = s.leastOpen(ctx, queue)
+		default:
+			assignee, err = s.roundRobin(ctx, queue)
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		if assignee == "" {
+			continue
+		}
+
+		sugar.Debugw("routed ticket", "queue.id", queue.ID, "queue.strategy", queue.Strategy, "assignee", assignee)
+
+		return assignee, nil
+	}
+
+	sugar.Debugw("no queue matched ticket", "site", ticket.Site, "category", ticket.Category)
+
+	return "", nil
+}
+
+// roundRobin advances the queue's cursor and returns the available member it
+// pointed to. The cursor is incremented atomically so concurrent tickets are
+// spread across members.
+func (s *QueueStore) roundRobin(ctx context.Context, queue models.Queue) (string, error) {
+	objectId, err := bson.ObjectIDFromHex(queue.ID)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		current *models.Queue
+		update  = bson.D{{Key: "$inc", Value: bson.D{{Key: "cursor", Value: 1}}}}
+		opts    = options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	)
+
+	res := s.collection.FindOneAndUpdate(ctx, bson.D{{Key: "_id", Value: objectId}}, update, opts)
+	if err := res.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// The queue was deleted since it was listed
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	if err := res.Decode(&current); err != nil {
+		return "", err
+	}
+
+	available := current.AvailableMembers()
+	if len(available) == 0 {
+		return "", nil
+	}
+
+	return available[current.Cursor%len(available)].Email, nil
+}
+
+// leastOpen returns the available member of queue with the fewest open
+// tickets assigned. Ties go to the member added to the queue first.
+func (s *QueueStore) leastOpen(ctx context.Context, queue models.Queue) (string, error) {
+	var (
+		available = queue.AvailableMembers()
+		emails    = make([]string, 0, len(available))
+	)
+
+	for _, member := range available {
+		emails = append(emails, member.Email)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "assignedTo", Value: bson.D{{Key: "$in", Value: emails}}},
+			{Key: "status", Value: bson.D{{Key: "$in", Value: models.OpenStatuses}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$assignedTo"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
+
+	cursor, err := s.tickets.Aggregate(ctx, pipeline)
+	if err != nil {
+		return "", err
+	}
+
+	var counts []struct {
+		Email string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+
+	if err := cursor.All(ctx, &counts); err != nil {
+		return "", fmt.Errorf("failed to decode open ticket counts: %w", err)
+	}
+
+	open := make(map[string]int, len(counts))
+	for _, c := range counts {
+		open[c.Email] = c.Count
+	}
+
+	best := emails[0]
+	for _, email := range emails[1:] {
+		if open[email] < open[best] {
+			best = email
+		}
+	}
+
+	return best, nil
+}
+
+// toStrings converts a decoded JSON array into a slice of strings. ok is false
+// if v is not an array or any of its elements is not a string.
+func toStrings(v any) (result []string, ok bool) {
+	values, ok := v.([]any)
+	if !ok {
+		return nil, false
+	}
+
+	result = make([]string, 0, len(values))
+
+	for _, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+
+		result = append(result, s)
+	}
+
+	return result, true
+}
+
+// nonNil returns values, or an empty slice if values is nil, so that Mongo
+// stores an empty array instead of null
+func nonNil[T any](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+
+	return values
+}
@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+const _rulesCollection = "rules"
+
+var (
+	ErrRuleNotFound = errors.New("rule not found")
+)
+
+// RuleStore provides CRUD operations for triage rules
+type RuleStore struct {
+	collection *mongo.Collection
+	log        *zap.Logger
+}
+
+// NewRuleStore creates a new RuleStore with a Mongo DB client config and logger
+func NewRuleStore(client *mongo.Client, logger *zap.Logger) *RuleStore {
+	return &RuleStore{
+		collection: client.Database(_database).Collection(_rulesCollection),
+		log:        logger.Named("storage.rules"),
+	}
+}
+
+// CreateRule adds a new rule to the store
+func (s *RuleStore) CreateRule(ctx context.Context, rule models.Rule) (id string, err error) {
+	var (
+		sugar = s.log.Sugar()
+		now   = time.Now()
+	)
+
+	doc := ruleDocument(rule)
+	doc = append(doc,
+		bson.E{Key: "createdOn", Value: now},
+		bson.E{Key: "updatedAt", Value: now},
+	)
+
+	res, err := s.collection.InsertOne(ctx, doc)
+	if err != nil {
+		return "", err
+	}
+
+	insertedId, ok := res.InsertedID.(bson.ObjectID)
+	if !ok {
+		return "", errors.New("failed to decode inserted ID into ObjectID")
+	}
+
+	sugar.Debugw("created new rule", "id", insertedId.Hex())
+
+	return insertedId.Hex(), nil
+}
+
+// FindRule finds a rule by its ID
+func (s *RuleStore) FindRule(ctx context.Context, id string) (*models.Rule, error) {
+	var (
+		rule  *models.Rule
+		sugar = s.log.Sugar()
+	)
+
+	objectId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		sugar.Debugw("id provided is not a valid ObjectID", "error", err)
+		return nil, ErrRuleNotFound
+	}
+
+	res := s.collection.FindOne(ctx, bson.D{{Key: "_id", Value: objectId}})
+
+	if err := res.Err(); err != nil {
+		switch {
+		case errors.Is(err, mongo.ErrNoDocuments):
+			sugar.Debugw("rule not found", "rule.id", id)
+			return nil, ErrRuleNotFound
+
+		default:
+			sugar.Error(err)
+			return nil, err
+		}
+	}
+
+	if err := res.Decode(&rule); err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// FindRules returns all rules in evaluation order. If enabledOnly is set,
+// disabled rules are left out.
+func (s *RuleStore) FindRules(ctx context.Context, enabledOnly bool) ([]models.Rule, error) {
+	var (
+		sugar   = s.log.Sugar()
+		results = []models.Rule{}
+		filter  = bson.D{}
+		opts    = options.Find().SetSort(bson.D{
+			{Key: "order", Value: 1},
+			{Key: "name", Value: 1},
+		})
+	)
+
+	if enabledOnly {
+		filter = bson.D{{Key: "enabled", Value: true}}
+	}
+
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	if err := cursor.All(ctx, &results); err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	sugar.Debugw("retrieved rules", "count", len(results), "enabledOnly", enabledOnly)
+
+	return results, nil
+}
+
+// ReplaceRule overwrites an existing rule with rule, keeping its creation date
+func (s *RuleStore) ReplaceRule(ctx context.Context, id string, rule models.Rule) (*models.Rule, error) {
+	var sugar = s.log.Sugar()
+
+	objectId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		sugar.Debugw("bad id provided", "error", err)
+		return nil, ErrRuleNotFound
+	}
+
+	doc := append(ruleDocument(rule), bson.E{Key: "updatedAt", Value: time.Now()})
+
+	res, err := s.collection.UpdateByID(ctx, objectId, bson.D{{Key: "$set", Value: doc}})
+	if err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	if res.MatchedCount == 0 {
+		return nil, ErrRuleNotFound
+	}
+
+	sugar.Debugw("rule replaced", "rule.id", id)
+
+	return s.FindRule(ctx, id)
+}
+
+// DeleteRule removes a rule from the store
+func (s *RuleStore) DeleteRule(ctx context.Context, id string) error {
+	var sugar = s.log.Sugar()
+
+	objectId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		sugar.Debugw("bad id provided", "error", err)
+		return ErrRuleNotFound
+	}
+
+	res, err := s.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: objectId}})
+	if err != nil {
+		sugar.Error(err)
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		sugar.Debugw("rule not found", "rule.id", id)
+		return ErrRuleNotFound
+	}
+
+	sugar.Debugw("deleted rule", "rule.id", id)
+
+	return nil
+}
+
+// ruleDocument converts the user-editable fields of rule into a document
+func ruleDocument(rule models.Rule) bson.D {
+	return bson.D{
+		{Key: "name", Value: rule.Name},
+		{Key: "enabled", Value: rule.Enabled},
+		{Key: "order", Value: rule.Order},
+		{Key: "events", Value: nonNil(rule.Events)},
+		{Key: "match", Value: rule.Match},
+		{Key: "conditions", Value: nonNil(rule.Conditions)},
+		{Key: "actions", Value: nonNil(rule.Actions)},
+	}
+}
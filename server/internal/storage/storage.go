@@ -4,15 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/digitalnest-wit/nestqueue/internal/models"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
 	"go.uber.org/zap"
 )
 
+const (
+	_database          = "nq_tickets"
+	_ticketsCollection = "tickets"
+)
+
 var (
 	ErrTicketNotFound = errors.New("ticket not found")
 )
@@ -20,17 +27,16 @@ var (
 // TicketStore provides CRUD operations for tickets stored in a Mongo DB collection
 type TicketStore struct {
 	collection *mongo.Collection
+	links      *mongo.Collection
+	comments   *mongo.Collection
+	queues     *QueueStore
 	log        *zap.Logger
 }
 
 // NewTicketStore creates a new TicketStore with a Mongo DB client config and
-// logger. If client cannot be pinged, an error is returned.
-func NewTicketStore(ctx context.Context, client *mongo.Client, logger *zap.Logger) (*TicketStore, error) {
-	const (
-		database   = "nq_tickets"
-		collection = "tickets"
-	)
-
+// logger. New unassigned tickets are routed through queues. If client cannot
+// be pinged, an error is returned.
+func NewTicketStore(ctx context.Context, client *mongo.Client, queues *QueueStore, logger *zap.Logger) (*TicketStore, error) {
 	var sugar = logger.Sugar()
 
 	if err := client.Ping(ctx, readpref.Primary()); err != nil {
@@ -38,33 +44,66 @@ func NewTicketStore(ctx context.Context, client *mongo.Client, logger *zap.Logge
 		return nil, err
 	}
 
-	sugar.Debugw("connected to Mongo DB cluster", "database", database, "collection", collection)
+	sugar.Debugw("connected to Mongo DB cluster", "database", _database, "collection", _ticketsCollection)
 
-	return &TicketStore{
-		collection: client.Database(database).Collection(collection),
+	store := &TicketStore{
+		collection: client.Database(_database).Collection(_ticketsCollection),
+		links:      client.Database(_database).Collection(_linksCollection),
+		comments:   client.Database(_database).Collection(_commentsCollection),
+		queues:     queues,
 		log:        logger.Named("storage"),
-	}, nil
+	}
+
+	if err := store.ensureIndexes(ctx); err != nil {
+		sugar.Errorw("failed to create indexes", "error", err)
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// ensureIndexes creates the indexes used to query tickets, their links and
+// their comments. Creating an index that already exists is a no-op.
+func (s *TicketStore) ensureIndexes(ctx context.Context) error {
+	for _, collection := range []*mongo.Collection{s.collection, s.links, s.comments} {
+		if err := createIndexes(ctx, collection); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-// CreateTicket adds a new ticket to the store
+// CreateTicket adds a new ticket to the store. Tickets created without an
+// assignee are assigned by the queue matching their site and category, if any.
 func (s *TicketStore) CreateTicket(ctx context.Context, ticket models.Ticket) (id string, err error) {
+	defer observe("CreateTicket", time.Now(), &err)
+
 	var (
 		sugar = s.log.Sugar()
 		now   = time.Now()
-		doc   = bson.D{
-			{Key: "title", Value: ticket.Title},
-			{Key: "description", Value: ticket.Description},
-			{Key: "site", Value: ticket.Site},
-			{Key: "category", Value: ticket.Category},
-			{Key: "assignedTo", Value: ticket.AssignedTo},
-			{Key: "createdBy", Value: ticket.CreatedBy},
-			{Key: "priority", Value: ticket.Priority},
-			{Key: "status", Value: ticket.Status},
-			{Key: "createdOn", Value: now},
-			{Key: "updatedAt", Value: now},
-		}
 	)
 
+	if ticket.AssignedTo == "" && s.queues != nil {
+		// Failing to route a ticket should not prevent it from being created;
+		// it is left unassigned instead
+		assignee, err := s.queues.Route(ctx, ticket)
+		if err != nil {
+			sugar.Errorw("failed to route ticket", "error", err)
+		}
+
+		ticket.AssignedTo = assignee
+	}
+
+	ticket.CreatedOn = now
+	ticket.UpdatedAt = now
+
+	if models.IsResolved(ticket.Status) {
+		ticket.ClosedOn = now
+	}
+
+	doc := ticketDocument(ticket)
+
 	res, err := s.collection.InsertOne(ctx, doc)
 	if err != nil {
 		return "", err
@@ -80,8 +119,40 @@ func (s *TicketStore) CreateTicket(ctx context.Context, ticket models.Ticket) (i
 	return insertedId.Hex(), err
 }
 
+// ticketDocument returns the document a new ticket is stored as
+func ticketDocument(ticket models.Ticket) bson.D {
+	customFields := ticket.CustomFields
+	if customFields == nil {
+		customFields = map[string]any{}
+	}
+
+	doc := bson.D{
+		{Key: "title", Value: ticket.Title},
+		{Key: "description", Value: ticket.Description},
+		{Key: "site", Value: ticket.Site},
+		{Key: "category", Value: ticket.Category},
+		{Key: "assignedTo", Value: ticket.AssignedTo},
+		{Key: "createdBy", Value: ticket.CreatedBy},
+		{Key: "priority", Value: ticket.Priority},
+		{Key: "status", Value: ticket.Status},
+		{Key: "tags", Value: models.NormalizeTags(ticket.Tags)},
+		{Key: "customFields", Value: customFields},
+		{Key: "createdOn", Value: ticket.CreatedOn},
+		{Key: "updatedAt", Value: ticket.UpdatedAt},
+		{Key: "schemaVersion", Value: models.TicketSchemaVersion},
+	}
+
+	if !ticket.ClosedOn.IsZero() {
+		doc = append(doc, bson.E{Key: "closedOn", Value: ticket.ClosedOn})
+	}
+
+	return doc
+}
+
 // FindTicket finds a ticket by its ID
-func (s *TicketStore) FindTicket(ctx context.Context, id string) (*models.Ticket, error) {
+func (s *TicketStore) FindTicket(ctx context.Context, id string) (_ *models.Ticket, err error) {
+	defer observe("FindTicket", time.Now(), &err)
+
 	var (
 		ticket *models.Ticket
 		filter bson.D
@@ -90,7 +161,7 @@ func (s *TicketStore) FindTicket(ctx context.Context, id string) (*models.Ticket
 
 	objectId, err := bson.ObjectIDFromHex(id)
 	if err != nil {
-		sugar.Debugw("id provided is not a valid ObjectID", err)
+		sugar.Debugw("id provided is not a valid ObjectID", "error", err)
 		return nil, ErrTicketNotFound
 	}
 
@@ -122,74 +193,172 @@ func (s *TicketStore) FindTicket(ctx context.Context, id string) (*models.Ticket
 }
 
 // UpdateTicket updates an existing ticket
-func (s *TicketStore) UpdateTicket(ctx context.Context, id string, updates map[string]any) (*models.Ticket, error) {
-	var (
-		updatesDoc = bson.D{}
-		sugar      = s.log.Sugar()
-	)
+func (s *TicketStore) UpdateTicket(ctx context.Context, id string, updates map[string]any) (_ *models.Ticket, err error) {
+	defer observe("UpdateTicket", time.Now(), &err)
+
+	var sugar = s.log.Sugar()
 
 	objectId, err := bson.ObjectIDFromHex(id)
 	if err != nil {
-		sugar.Debugw("bad id provided", err)
-		return nil, err
+		sugar.Debugw("id provided is not a valid ObjectID", "error", err)
+		return nil, ErrTicketNotFound
 	}
 
 	for k, v := range updates {
 		sugar.Debugw("update field type", "key", k, "type", fmt.Sprintf("%T", v), "value", v)
 	}
 
-	// Populate updatesDoc based on the fields provided in updates
+	if update := updateDocuments(updates); len(update) > 0 {
+		if _, err := s.collection.UpdateByID(ctx, objectId, update); err != nil {
+			sugar.Error(err)
+			return nil, err
+		}
+	}
+
+	if status, ok := updates["status"].(string); ok {
+		if err := s.trackResolution(ctx, status, objectId); err != nil {
+			sugar.Errorw("failed to record resolution time", "ticket.id", id, "error", err)
+		}
+
+		// Closing a parent closes its open descendants
+		if status == models.StatusClosed {
+			if err := s.closeChildren(ctx, objectId); err != nil {
+				sugar.Errorw("failed to close child tickets", "ticket.id", id, "error", err)
+			}
+		}
+	}
+
+	updatedTicket, err := s.FindTicket(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	sugar.Debugw("ticket updated", "ticket.id", id, "updates", len(updates))
+
+	return updatedTicket, nil
+}
+
+// updateDocuments builds the update pipeline applying updates to a ticket in
+// a single write. tags replaces the ticket's tags, then addTags and
+// removeTags are applied to the result, so all three may be combined.
+func updateDocuments(updates map[string]any) mongo.Pipeline {
+	var updatesDoc = bson.D{}
 
 	if title, ok := updates["title"].(string); ok {
-		updatesDoc = append(updatesDoc, bson.E{Key: "title", Value: title})
+		updatesDoc = append(updatesDoc, bson.E{Key: "title", Value: literal(title)})
 	}
 
 	if description, ok := updates["description"].(string); ok {
-		updatesDoc = append(updatesDoc, bson.E{Key: "description", Value: description})
+		updatesDoc = append(updatesDoc, bson.E{Key: "description", Value: literal(description)})
 	}
 
 	if site, ok := updates["site"].(string); ok {
-		updatesDoc = append(updatesDoc, bson.E{Key: "site", Value: site})
+		updatesDoc = append(updatesDoc, bson.E{Key: "site", Value: literal(site)})
 	}
 
 	if category, ok := updates["category"].(string); ok {
-		updatesDoc = append(updatesDoc, bson.E{Key: "category", Value: category})
+		updatesDoc = append(updatesDoc, bson.E{Key: "category", Value: literal(category)})
 	}
 
 	if assignedTo, ok := updates["assignedTo"].(string); ok {
-		updatesDoc = append(updatesDoc, bson.E{Key: "assignedTo", Value: assignedTo})
+		updatesDoc = append(updatesDoc, bson.E{Key: "assignedTo", Value: literal(assignedTo)})
 	}
 
 	if priority, ok := updates["priority"].(float64); ok {
-		updatesDoc = append(updatesDoc, bson.E{Key: "priority", Value: int(priority)})
+		updatesDoc = append(updatesDoc, bson.E{Key: "priority", Value: literal(int(priority))})
 	}
 
 	if status, ok := updates["status"].(string); ok {
-		updatesDoc = append(updatesDoc, bson.E{Key: "status", Value: status})
+		updatesDoc = append(updatesDoc, bson.E{Key: "status", Value: literal(status)})
 	}
 
-	if len(updates) > 0 {
-		updatesDoc = append(updatesDoc, bson.E{Key: "updatedAt", Value: time.Now()})
+	if tags, ok := tagsExpression(updates); ok {
+		updatesDoc = append(updatesDoc, bson.E{Key: "tags", Value: tags})
 	}
 
-	_, err = s.collection.UpdateByID(ctx, objectId, bson.D{{Key: "$set", Value: updatesDoc}})
-	if err != nil {
-		sugar.Error(err)
-		return nil, err
+	// Custom fields are updated individually so that fields left out are
+	// kept. A nil value removes the field.
+	var unsetDoc bson.A
+
+	if customFields, ok := updates["customFields"].(map[string]any); ok {
+		for key, value := range customFields {
+			path := "customFields." + key
+
+			if value == nil {
+				unsetDoc = append(unsetDoc, path)
+			} else {
+				updatesDoc = append(updatesDoc, bson.E{Key: path, Value: literal(value)})
+			}
+		}
 	}
 
-	updatedTicket, err := s.FindTicket(ctx, id)
-	if err != nil {
-		return nil, err
+	if len(updates) == 0 {
+		return nil
 	}
 
-	sugar.Debugw("ticket updated", "ticket.id", id, "updates", len(updates))
+	updatesDoc = append(updatesDoc, bson.E{Key: "updatedAt", Value: literal(time.Now())})
 
-	return updatedTicket, nil
+	update := mongo.Pipeline{{{Key: "$set", Value: updatesDoc}}}
+
+	if len(unsetDoc) > 0 {
+		update = append(update, bson.D{{Key: "$unset", Value: unsetDoc}})
+	}
+
+	return update
 }
 
-// DeleteTicket removes a ticket from the store
-func (s *TicketStore) DeleteTicket(ctx context.Context, id string) error {
+// tagsExpression returns the expression computing a ticket's tags from the
+// tags, addTags and removeTags of updates. Added tags are appended after the
+// ticket's tags, keeping their order. ok is false when updates changes no
+// tags.
+func tagsExpression(updates map[string]any) (_ any, ok bool) {
+	var (
+		tags, replaced = toStrings(updates["tags"])
+		addTags, _     = toStrings(updates["addTags"])
+		removeTags, _  = toStrings(updates["removeTags"])
+	)
+
+	if !replaced && len(addTags) == 0 && len(removeTags) == 0 {
+		return nil, false
+	}
+
+	var current any = bson.D{{Key: "$ifNull", Value: bson.A{"$tags", bson.A{}}}}
+	if replaced {
+		current = literal(models.NormalizeTags(tags))
+	}
+
+	return bson.D{{Key: "$let", Value: bson.D{
+		{Key: "vars", Value: bson.D{{Key: "current", Value: current}}},
+		{Key: "in", Value: bson.D{{Key: "$filter", Value: bson.D{
+			{Key: "input", Value: bson.D{{Key: "$concatArrays", Value: bson.A{
+				"$$current",
+				bson.D{{Key: "$filter", Value: bson.D{
+					{Key: "input", Value: literal(models.NormalizeTags(addTags))},
+					{Key: "as", Value: "added"},
+					{Key: "cond", Value: bson.D{{Key: "$not", Value: bson.A{
+						bson.D{{Key: "$in", Value: bson.A{"$$added", "$$current"}}},
+					}}}},
+				}}},
+			}}}},
+			{Key: "as", Value: "tag"},
+			{Key: "cond", Value: bson.D{{Key: "$not", Value: bson.A{
+				bson.D{{Key: "$in", Value: bson.A{"$$tag", literal(models.NormalizeTags(removeTags))}}},
+			}}}},
+		}}}},
+	}}}, true
+}
+
+// literal wraps value so an update pipeline stores it as is, rather than
+// reading strings starting with $ as field paths
+func literal(value any) bson.D {
+	return bson.D{{Key: "$literal", Value: value}}
+}
+
+// DeleteTicket removes a ticket from the store, along with its links and
+// comments
+func (s *TicketStore) DeleteTicket(ctx context.Context, id string) (err error) {
+	defer observe("DeleteTicket", time.Now(), &err)
+
 	var (
 		filter bson.D
 		sugar  = s.log.Sugar()
@@ -197,8 +366,8 @@ func (s *TicketStore) DeleteTicket(ctx context.Context, id string) error {
 
 	objectId, err := bson.ObjectIDFromHex(id)
 	if err != nil {
-		sugar.Debugw("bad id provided", err)
-		return err
+		sugar.Debugw("id provided is not a valid ObjectID", "error", err)
+		return ErrTicketNotFound
 	}
 
 	filter = append(filter, bson.E{Key: "_id", Value: objectId})
@@ -217,41 +386,153 @@ func (s *TicketStore) DeleteTicket(ctx context.Context, id string) error {
 		}
 	}
 
+	// Links and comments of a deleted ticket would dangle, so they go with it
+	linkFilter := bson.D{{Key: "$or", Value: []bson.D{
+		{{Key: "source", Value: objectId}},
+		{{Key: "target", Value: objectId}},
+	}}}
+
+	if _, err := s.links.DeleteMany(ctx, linkFilter); err != nil {
+		sugar.Errorw("failed to delete ticket links", "ticket.id", id, "error", err)
+	}
+
+	if _, err := s.comments.DeleteMany(ctx, bson.D{{Key: "ticket", Value: objectId}}); err != nil {
+		sugar.Errorw("failed to delete ticket comments", "ticket.id", id, "error", err)
+	}
+
 	sugar.Debugw("deleted ticket", "ticket.id", id)
 
 	return nil
 }
 
-// FindTickets returns all tickets, optionally filtered by a query. The query
-// matches against a ticket's title and description.
-func (s *TicketStore) FindTickets(ctx context.Context, query string) ([]models.Ticket, error) {
+// trackResolution records when the tickets identified by ids, which were
+// given status, were resolved. The first resolution is kept when a ticket
+// moves between resolved statuses, and the time is cleared when it is
+// reopened.
+func (s *TicketStore) trackResolution(ctx context.Context, status string, ids ...bson.ObjectID) error {
+	filter := bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}}
+
+	if !models.IsResolved(status) {
+		update := bson.D{{Key: "$unset", Value: bson.D{{Key: "closedOn", Value: ""}}}}
+		_, err := s.collection.UpdateMany(ctx, filter, update)
+
+		return err
+	}
+
+	filter = append(filter, bson.E{Key: "closedOn", Value: nil})
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "closedOn", Value: time.Now()}}}}
+
+	_, err := s.collection.UpdateMany(ctx, filter, update)
+
+	return err
+}
+
+// closeChildren closes every open descendant of the tickets identified by
+// parents, following parent links
+func (s *TicketStore) closeChildren(ctx context.Context, parents ...bson.ObjectID) error {
+	var (
+		sugar       = s.log.Sugar()
+		frontier    = slices.Clone(parents)
+		visited     = make(map[bson.ObjectID]bool, len(parents))
+		descendants []bson.ObjectID
+	)
+
+	for _, parent := range parents {
+		visited[parent] = true
+	}
+
+	for len(frontier) > 0 {
+		filter := bson.D{
+			{Key: "type", Value: models.LinkParent},
+			{Key: "source", Value: bson.D{{Key: "$in", Value: frontier}}},
+		}
+
+		cursor, err := s.links.Find(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		var links []struct {
+			Target bson.ObjectID `bson:"target"`
+		}
+
+		if err := cursor.All(ctx, &links); err != nil {
+			return err
+		}
+
+		frontier = nil
+
+		for _, link := range links {
+			if !visited[link.Target] {
+				visited[link.Target] = true
+				frontier = append(frontier, link.Target)
+				descendants = append(descendants, link.Target)
+			}
+		}
+	}
+
+	if len(descendants) == 0 {
+		return nil
+	}
+
+	filter := bson.D{
+		{Key: "_id", Value: bson.D{{Key: "$in", Value: descendants}}},
+		{Key: "status", Value: bson.D{{Key: "$in", Value: models.OpenStatuses}}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "status", Value: models.StatusClosed},
+		{Key: "updatedAt", Value: time.Now()},
+		{Key: "closedOn", Value: time.Now()},
+	}}}
+
+	res, err := s.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	sugar.Debugw("closed child tickets", "parents", len(parents), "count", res.ModifiedCount)
+
+	return nil
+}
+
+// FindTickets returns all tickets matching filter. An empty filter matches
+// every ticket that has not been merged into another.
+func (s *TicketStore) FindTickets(ctx context.Context, filter TicketFilter) (_ []models.Ticket, err error) {
+	defer observe("FindTickets", time.Now(), &err)
+
 	var (
 		sugar   = s.log.Sugar()
 		results []models.Ticket
-		filter  = bson.D{}
 	)
 
-	// Build the query filter, if provided
-	if query != "" {
-		filter = bson.D{
-			{Key: "$or", Value: []bson.D{
-				{{Key: "title", Value: bson.D{
-					{Key: "$regex", Value: query},
-					{Key: "$options", Value: "i"},
-				}}},
-				{{Key: "description", Value: bson.D{
-					{Key: "$regex", Value: query},
-					{Key: "$options", Value: "i"},
-				}}},
-			}},
-		}
+	var (
+		opts = options.Find()
+		sort = filter.sortDocument()
+	)
+
+	// Pages are only stable when every ticket has a place in the order
+	if filter.Limit > 0 || filter.Offset > 0 {
+		sort = append(sort, bson.E{Key: "_id", Value: 1})
+	}
+
+	if len(sort) > 0 {
+		opts.SetSort(sort)
+	}
+
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+
+	if filter.Offset > 0 {
+		opts.SetSkip(int64(filter.Offset))
 	}
 
-	cursor, err := s.collection.Find(ctx, filter)
+	cursor, err := s.collection.Find(ctx, filter.document(), opts)
 	if err != nil {
 		sugar.Error(err)
 		return nil, err
 	}
+	defer cursor.Close(ctx)
 
 	results = []models.Ticket{}
 
@@ -266,7 +547,75 @@ func (s *TicketStore) FindTickets(ctx context.Context, query string) ([]models.T
 		results = append(results, ticket)
 	}
 
-	sugar.Debugw("retreived tickets", "count", len(results), "query", query)
+	// A cursor stopping early, e.g. when ctx is canceled, must not pass for
+	// the end of the results
+	if err := cursor.Err(); err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	sugar.Debugw("retreived tickets", "count", len(results), "filter", filter)
 
 	return results, nil
 }
+
+// StreamTickets calls fn with every ticket matching filter as it is read from
+// the cursor, so the results are never held in memory together. Iteration
+// stops at the first error returned by fn, which is returned.
+func (s *TicketStore) StreamTickets(ctx context.Context, filter TicketFilter, fn func(models.Ticket) error) (err error) {
+	defer observe("StreamTickets", time.Now(), &err)
+
+	var sugar = s.log.Sugar()
+
+	opts := options.Find()
+	if sort := filter.sortDocument(); len(sort) > 0 {
+		opts.SetSort(sort)
+	}
+
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+
+	cursor, err := s.collection.Find(ctx, filter.document(), opts)
+	if err != nil {
+		sugar.Error(err)
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var ticket models.Ticket
+
+		if err := cursor.Decode(&ticket); err != nil {
+			sugar.Error(err)
+			return err
+		}
+
+		if err := fn(ticket); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// AggregateTickets runs a read-only aggregation pipeline over the stored
+// tickets, such as a report, and decodes every result into results
+func (s *TicketStore) AggregateTickets(ctx context.Context, pipeline mongo.Pipeline, results any) (err error) {
+	defer observe("AggregateTickets", time.Now(), &err)
+
+	for _, stage := range pipeline {
+		for _, e := range stage {
+			if e.Key == "$out" || e.Key == "$merge" {
+				return fmt.Errorf("aggregation stage %s writes to the store", e.Key)
+			}
+		}
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+
+	return cursor.All(ctx, results)
+}
@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+const _tagsCollection = "tags"
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+)
+
+// TagStore manages the tag registry, which records a display color for each
+// tag, and reports how many tickets use each tag
+type TagStore struct {
+	collection *mongo.Collection
+	tickets    *mongo.Collection
+	log        *zap.Logger
+}
+
+// NewTagStore creates a new TagStore with a Mongo DB client config and logger
+func NewTagStore(client *mongo.Client, logger *zap.Logger) *TagStore {
+	db := client.Database(_database)
+
+	return &TagStore{
+		collection: db.Collection(_tagsCollection),
+		tickets:    db.Collection(_ticketsCollection),
+		log:        logger.Named("storage.tags"),
+	}
+}
+
+// FindTags returns every registered tag along with every tag in use on a
+// ticket, sorted by name. Tags in use but never registered have no color.
+func (s *TagStore) FindTags(ctx context.Context) ([]models.Tag, error) {
+	var (
+		sugar      = s.log.Sugar()
+		registered []models.Tag
+	)
+
+	cursor, err := s.collection.Find(ctx, bson.D{})
+	if err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	if err := cursor.All(ctx, &registered); err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	counts, err := s.usage(ctx)
+	if err != nil {
+		sugar.Error(err)
+		return nil, err
+	}
+
+	results := make([]models.Tag, 0, len(registered)+len(counts))
+
+	for _, tag := range registered {
+		tag.Registered = true
+		tag.Count = counts[tag.Name]
+		delete(counts, tag.Name)
+
+		results = append(results, tag)
+	}
+
+	for name, count := range counts {
+		results = append(results, models.Tag{Name: name, Count: count})
+	}
+
+	slices.SortFunc(results, func(a, b models.Tag) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	sugar.Debugw("retrieved tags", "count", len(results))
+
+	return results, nil
+}
+
+// usage counts the tickets carrying each tag
+func (s *TagStore) usage(ctx context.Context) (map[string]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$tags"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
+
+	cursor, err := s.tickets.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Name  string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Name] = row.Count
+	}
+
+	return counts, nil
+}
+
+// SaveTag registers tag, or updates its color if it is already registered
+func (s *TagStore) SaveTag(ctx context.Context, tag models.Tag) error {
+	var (
+		sugar  = s.log.Sugar()
+		filter = bson.D{{Key: "_id", Value: tag.Name}}
+		update = bson.D{{Key: "$set", Value: bson.D{{Key: "color", Value: tag.Color}}}}
+		opts   = options.UpdateOne().SetUpsert(true)
+	)
+
+	if _, err := s.collection.UpdateOne(ctx, filter, update, opts); err != nil {
+		sugar.Error(err)
+		return err
+	}
+
+	sugar.Debugw("saved tag", "tag", tag.Name, "color", tag.Color)
+
+	return nil
+}
+
+// DeleteTag removes a tag from the registry. If purge is set, the tag is also
+// removed from every ticket carrying it, and deleting an unregistered tag is
+// not an error.
+func (s *TagStore) DeleteTag(ctx context.Context, name string, purge bool) error {
+	var sugar = s.log.Sugar()
+
+	res, err := s.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: name}})
+	if err != nil {
+		sugar.Error(err)
+		return err
+	}
+
+	if !purge {
+		if res.DeletedCount == 0 {
+			return ErrTagNotFound
+		}
+
+		sugar.Debugw("deleted tag", "tag", name)
+
+		return nil
+	}
+
+	update := bson.D{{Key: "$pull", Value: bson.D{{Key: "tags", Value: name}}}}
+
+	purged, err := s.tickets.UpdateMany(ctx, bson.D{{Key: "tags", Value: name}}, update)
+	if err != nil {
+		sugar.Error(err)
+		return err
+	}
+
+	sugar.Debugw("deleted tag", "tag", name, "tickets", purged.ModifiedCount)
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/digitalnest-wit/nestqueue/internal/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Issue is a problem with a stored document
+type Issue struct {
+	Collection string `json:"collection"`
+	ID         string `json:"id"`
+	Problem    string `json:"problem"`
+}
+
+// Verify checks the integrity of the stored tickets, links and comments:
+// tickets missing required fields or holding invalid values, and references
+// to tickets that do not exist. Documents are read as stored, so problems
+// hidden by decoding them into tickets are found too.
+func Verify(ctx context.Context, client *mongo.Client) ([]Issue, error) {
+	var (
+		db      = Database(client)
+		tickets = db.Collection(_ticketsCollection)
+		issues  = []Issue{}
+	)
+
+	ids, err := ticketIDs(ctx, tickets)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := tickets.Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		id := idOf(cursor.Current)
+
+		for _, problem := range ticketProblems(cursor.Current, ids) {
+			issues = append(issues, Issue{Collection: _ticketsCollection, ID: id, Problem: problem})
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	cursor, err = db.Collection(_linksCollection).Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		for _, key := range []string{"source", "target"} {
+			value, ok := cursor.Current.Lookup(key).ObjectIDOK()
+
+			switch {
+			case !ok:
+				issues = append(issues, Issue{Collection: _linksCollection, ID: idOf(cursor.Current), Problem: key + " is missing"})
+			case !ids[value]:
+				issues = append(issues, Issue{Collection: _linksCollection, ID: idOf(cursor.Current), Problem: fmt.Sprintf("%s %s does not exist", key, value.Hex())})
+			}
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	cursor, err = db.Collection(_commentsCollection).Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		value, ok := cursor.Current.Lookup("ticket").ObjectIDOK()
+
+		switch {
+		case !ok:
+			issues = append(issues, Issue{Collection: _commentsCollection, ID: idOf(cursor.Current), Problem: "ticket is missing"})
+		case !ids[value]:
+			issues = append(issues, Issue{Collection: _commentsCollection, ID: idOf(cursor.Current), Problem: fmt.Sprintf("ticket %s does not exist", value.Hex())})
+		}
+	}
+
+	return issues, cursor.Err()
+}
+
+// ticketIDs returns the IDs of every ticket
+func ticketIDs(ctx context.Context, tickets *mongo.Collection) (map[bson.ObjectID]bool, error) {
+	var ids = make(map[bson.ObjectID]bool)
+
+	cursor, err := tickets.Find(ctx, bson.D{}, options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		if id, ok := cursor.Current.Lookup("_id").ObjectIDOK(); ok {
+			ids[id] = true
+		}
+	}
+
+	return ids, cursor.Err()
+}
+
+// ticketProblems returns the problems of a stored ticket. ids holds the IDs
+// of every ticket.
+func ticketProblems(doc bson.Raw, ids map[bson.ObjectID]bool) []string {
+	var problems []string
+
+	if title, ok := doc.Lookup("title").StringValueOK(); !ok || title == "" {
+		problems = append(problems, "title is missing")
+	}
+
+	status, ok := doc.Lookup("status").StringValueOK()
+	if !ok {
+		problems = append(problems, "status is missing")
+	} else if !slices.Contains(models.OpenStatuses, status) && !models.IsResolved(status) {
+		problems = append(problems, fmt.Sprintf("status %q is unknown", status))
+	}
+
+	if priority, ok := doc.Lookup("priority").AsInt64OK(); !ok || priority < 1 || priority > 5 {
+		problems = append(problems, "priority is missing or not from 1 to 5")
+	}
+
+	for _, key := range []string{"createdOn", "updatedAt"} {
+		if _, ok := doc.Lookup(key).DateTimeOK(); !ok {
+			problems = append(problems, key+" is missing")
+		}
+	}
+
+	if _, closed := doc.Lookup("closedOn").DateTimeOK(); models.IsResolved(status) && !closed {
+		problems = append(problems, "closedOn is missing from a resolved ticket")
+	}
+
+	if version, ok := doc.Lookup("schemaVersion").AsInt64OK(); !ok || version < models.TicketSchemaVersion {
+		problems = append(problems, fmt.Sprintf("schemaVersion is missing or older than %d", models.TicketSchemaVersion))
+	}
+
+	if _, ok := doc.Lookup("tags").ArrayOK(); !ok {
+		problems = append(problems, "tags is missing or not a list")
+	}
+
+	if value, err := doc.LookupErr("customFields"); err == nil {
+		if _, ok := value.DocumentOK(); !ok {
+			problems = append(problems, "customFields is not a document")
+		}
+	}
+
+	if value, err := doc.LookupErr("mergedInto"); err == nil {
+		if into, ok := referencedID(value); !ok || !ids[into] {
+			problems = append(problems, fmt.Sprintf("merged into %s, which does not exist", value))
+		}
+	}
+
+	return problems
+}
+
+// referencedID returns the ticket ID referenced by value, which is stored as
+// an ObjectID or its hex string
+func referencedID(value bson.RawValue) (bson.ObjectID, bool) {
+	if id, ok := value.ObjectIDOK(); ok {
+		return id, true
+	}
+
+	hex, ok := value.StringValueOK()
+	if !ok {
+		return bson.ObjectID{}, false
+	}
+
+	id, err := bson.ObjectIDFromHex(hex)
+
+	return id, err == nil
+}
+
+// idOf returns the ID of a stored document as text
+func idOf(doc bson.Raw) string {
+	value := doc.Lookup("_id")
+
+	if id, ok := value.ObjectIDOK(); ok {
+		return id.Hex()
+	}
+
+	return value.String()
+}
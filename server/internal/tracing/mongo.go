@@ -0,0 +1,117 @@
+package tracing
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// _instrumentationName names the tracer recording the spans of Mongo commands
+const _instrumentationName = "github.com/digitalnest-wit/nestqueue/internal/tracing"
+
+// NewCommandMonitor returns a Mongo command monitor recording a client span
+// for every command sent to the cluster with the global tracer provider, as a
+// child of the span carried by the context of the operation sending it.
+// Commands and replies are not recorded since they hold ticket data.
+//
+// The contrib otelmongo monitor only supports version 1 of the driver, so
+// spans are recorded here with the database semantic conventions instead.
+func NewCommandMonitor() *event.CommandMonitor {
+	var (
+		tracer = otel.Tracer(_instrumentationName)
+
+		// spans holds the span of each running command by request ID
+		spans sync.Map
+	)
+
+	finish := func(requestID int64, err error) {
+		value, ok := spans.LoadAndDelete(requestID)
+		if !ok {
+			return
+		}
+
+		span := value.(trace.Span)
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		span.End()
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			var (
+				collection = commandCollection(e)
+				name       = e.CommandName
+			)
+
+			if collection != "" {
+				name += " " + collection
+			}
+
+			attributes := []attribute.KeyValue{
+				attribute.String("db.system.name", "mongodb"),
+				attribute.String("db.namespace", e.DatabaseName),
+				attribute.String("db.operation.name", e.CommandName),
+			}
+
+			if collection != "" {
+				attributes = append(attributes, attribute.String("db.collection.name", collection))
+			}
+
+			if host, port, ok := serverAddress(e.ConnectionID); ok {
+				attributes = append(attributes, attribute.String("server.address", host), attribute.Int("server.port", port))
+			}
+
+			_, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+
+			spans.Store(e.RequestID, span)
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			finish(e.RequestID, nil)
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			finish(e.RequestID, e.Failure)
+		},
+	}
+}
+
+// commandCollection returns the collection a command acts on, which is the
+// value of its first element for commands such as find, insert and aggregate
+func commandCollection(e *event.CommandStartedEvent) string {
+	elements, err := e.Command.Elements()
+	if err != nil || len(elements) == 0 {
+		return ""
+	}
+
+	collection, _ := elements[0].Value().StringValueOK()
+
+	return collection
+}
+
+// serverAddress returns the host and port of the server of a connection ID,
+// which the driver formats as host:port[-number]
+func serverAddress(connectionID string) (string, int, bool) {
+	if i := strings.LastIndex(connectionID, "["); i >= 0 {
+		connectionID = connectionID[:i]
+	}
+
+	host, port, err := net.SplitHostPort(connectionID)
+	if err != nil {
+		return "", 0, false
+	}
+
+	n, err := strconv.Atoi(port)
+
+	return host, n, err == nil
+}
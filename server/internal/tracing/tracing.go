@@ -0,0 +1,99 @@
+// Package tracing records spans of the work done serving requests with the
+// OpenTelemetry SDK and exports them to a collector.
+//
+// Spans are carried in contexts so the spans of Mongo commands become
+// children of the request that ran them. Trace context crosses process
+// boundaries in W3C traceparent headers.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Propagator carries trace context in W3C traceparent and tracestate headers
+var Propagator propagation.TextMapPropagator = propagation.TraceContext{}
+
+var errShuttingDown = errors.New("span exporter is shutting down")
+
+// Config configures a tracer provider
+type Config struct {
+	// ServiceName names the service in exported spans, unless
+	// OTEL_SERVICE_NAME names it otherwise
+	ServiceName string
+
+	// Exporter exports sampled spans. Spans are not exported when it is nil,
+	// though they are still propagated and logged.
+	Exporter sdktrace.SpanExporter
+}
+
+// Provider is a tracer provider that reports whether it still exports spans
+type Provider struct {
+	*sdktrace.TracerProvider
+
+	// stopping is set once the provider starts shutting down
+	stopping atomic.Bool
+}
+
+// NewProvider creates a tracer provider exporting spans in batches with the
+// exporter of config. Spans are sampled as set by OTEL_TRACES_SAMPLER and
+// OTEL_TRACES_SAMPLER_ARG; by default every trace started here is sampled,
+// and traces continued from other processes are sampled when they were
+// sampled there.
+func NewProvider(ctx context.Context, config Config) (*Provider, error) {
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(config.ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+
+	if config.Exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(config.Exporter))
+	}
+
+	return &Provider{TracerProvider: sdktrace.NewTracerProvider(opts...)}, nil
+}
+
+// Shutdown exports the spans still buffered and stops exporting spans
+func (p *Provider) Shutdown(ctx context.Context) error {
+	p.stopping.Store(true)
+
+	return p.TracerProvider.Shutdown(ctx)
+}
+
+// Check reports an error once the provider is shutting down and no longer
+// exports spans. It is meant as a readiness check.
+func (p *Provider) Check(ctx context.Context) error {
+	if p.stopping.Load() {
+		return errShuttingDown
+	}
+
+	return nil
+}
+
+// LogFields returns the IDs of the trace and span carried by ctx as log
+// fields, so logs can be found from a trace and the other way around
+func LogFields(ctx context.Context) []zap.Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+
+	return []zap.Field{
+		zap.String("trace_id", sc.TraceID().String()),
+		zap.String("span_id", sc.SpanID().String()),
+	}
+}
@@ -0,0 +1,29 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestProviderCheck(t *testing.T) {
+	ctx := context.Background()
+
+	provider, err := NewProvider(ctx, Config{ServiceName: "test", Exporter: tracetest.NewInMemoryExporter()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := provider.Check(ctx); err != nil {
+		t.Fatalf("Check = %v before shutting down, want nil", err)
+	}
+
+	if err := provider.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := provider.Check(ctx); err == nil {
+		t.Error("Check = nil after shutting down, want an error")
+	}
+}